@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// requireAdmin wraps h so it is only reachable with the configured admin token
+// passed as "Authorization: Bearer <token>"
+func (s *Server) requireAdmin(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if s.adminToken == "" {
+			http.Error(w, "Admin API is disabled", http.StatusForbidden)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		h(w, r, ps)
+	}
+}
+
+// handleRenamePlayer handles PATCH /api/admin/scores/:name
+func (s *Server) handleRenamePlayer(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	newName, err := s.sanitizeName(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), nameErrorStatus(err))
+		return
+	}
+
+	oldName := ps.ByName("name")
+	renamed, ok := s.lb.RenamePlayer(oldName, newName)
+	if !ok {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+
+	logger := loggerFrom(r.Context())
+	logger.Info("admin renamed player", "from", oldName, "to", newName, "entries", renamed)
+	s.auditLog(r, "rename", oldName, map[string]any{"to": newName, "entries": renamed})
+
+	// Logged so warm-up replays the rename after the submissions before it
+	ev := SubmissionEvent{Type: eventRename, Timestamp: s.now(), RequestID: requestIDFrom(r.Context()), Name: oldName, To: newName}
+	if err := s.events.Record(ev); err != nil {
+		logger.Error("failed to record rename event", "from", oldName, "to", newName, "err", err)
+	}
+	if s.backupInterval > 0 {
+		if _, err := s.backupNow(); err != nil {
+			logger.Error("failed to persist board after rename", "dir", s.backupDir, "err", err)
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]any{"status": "success", "renamed": renamed})
+}
+
+// handleExport handles GET /api/admin/export, serving every retained
+// submission as newline-delimited JSON.
+//
+// The export is written to a temp file first so Range requests can seek
+// within it. Its ETag is a hash of the content, so a client resuming with
+// If-Range gets the rest of the same export, or the whole new one if
+// submissions arrived in between.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// History copies under the lock, so the export is a consistent view
+	records := s.lb.History()
+	s.auditLog(r, "export", "", map[string]any{"records": len(records)})
+
+	f, err := os.CreateTemp("", "flappy-gopher-export-*.ndjson")
+	if err != nil {
+		loggerFrom(r.Context()).Error("failed to create export file", "err", err)
+		http.Error(w, "Failed to prepare export", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	sum := sha256.New()
+	bw := bufio.NewWriter(io.MultiWriter(f, sum))
+	enc := json.NewEncoder(bw)
+	for _, rec := range records {
+		if err = enc.Encode(rec); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		loggerFrom(r.Context()).Error("failed to write export file", "err", err)
+		http.Error(w, "Failed to prepare export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum.Sum(nil)[:16])+`"`)
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// redactedValue stands in for a secret that is set
+const redactedValue = "[redacted]"
+
+// redacted returns c with every field tagged redact:"true" replaced, so it
+// can leave the process. Those are secrets and URLs that may embed
+// credentials; unset ones stay empty so it's still clear they are unset.
+func (c Config) redacted() Config {
+	v := reflect.ValueOf(&c).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		if t.Field(i).Tag.Get("redact") != "true" {
+			continue
+		}
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			if f.Len() > 0 {
+				f.SetString(redactedValue)
+			}
+		case reflect.Slice:
+			for j := range f.Len() {
+				if e := f.Index(j); e.Kind() == reflect.String && e.Len() > 0 {
+					e.SetString(redactedValue)
+				}
+			}
+		default:
+			// A secret of a kind redacted can't blank would otherwise leak
+			panic("redact tag on unsupported config field " + t.Field(i).Name)
+		}
+	}
+	return c
+}
+
+// handleGetConfig handles GET /api/admin/config, reporting the effective
+// configuration after defaults, the config file, the environment and flags
+// are merged, with secrets redacted
+func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, r, http.StatusOK, s.config.redacted())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// dailyDateLayout names daily archives and is the :date route format
+const dailyDateLayout = "2006-01-02"
+
+// dailyArchivePath returns where the board for day is archived
+func (s *Server) dailyArchivePath(day time.Time) string {
+	return filepath.Join(s.dailyDir, day.Format(dailyDateLayout)+".json")
+}
+
+// archiveDay stores the final board of day as compact JSON and prunes
+// archives beyond dailyKeep
+func (s *Server) archiveDay(day time.Time, entries []Score) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.dailyArchivePath(day), data); err != nil {
+		return err
+	}
+	return pruneDailyArchives(s.dailyDir, s.dailyKeep)
+}
+
+// pruneDailyArchives deletes all but the newest keep archives in dir
+func pruneDailyArchives(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			if _, err := time.Parse(dailyDateLayout, name); err == nil {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+
+	// ISO dates sort lexically in chronological order
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("remove old daily archives: %w", err)
+	}
+	return nil
+}
+
+// handleGetDailyBoard handles GET /api/leaderboard/daily/:date
+func (s *Server) handleGetDailyBoard(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	day, err := time.Parse(dailyDateLayout, ps.ByName("date"))
+	if err != nil {
+		http.Error(w, "Date must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	data, err := os.ReadFile(s.dailyArchivePath(day))
+	if errors.Is(err, fs.ErrNotExist) || s.dailyDir == "" {
+		http.Error(w, "No board archived for that day", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		loggerFrom(r.Context()).Error("failed to read daily archive", "day", ps.ByName("date"), "err", err)
+		http.Error(w, "Failed to read archive", http.StatusInternalServerError)
+		return
+	}
+
+	var entries []Score
+	if err := json.Unmarshal(data, &entries); err != nil {
+		loggerFrom(r.Context()).Error("corrupt daily archive", "day", ps.ByName("date"), "err", err)
+		http.Error(w, "Failed to read archive", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, capEntries(s, w, entries))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupPrefix     = "leaderboard-"
+	backupSuffix     = ".json"
+	backupTimeLayout = "20060102-150405"
+)
+
+// writeBackup writes a timestamped snapshot of lb into dir and returns its
+// path
+func writeBackup(lb *Leaderboard, dir string, t time.Time) (string, error) {
+	path := filepath.Join(dir, backupPrefix+t.UTC().Format(backupTimeLayout)+backupSuffix)
+	if err := writeJSONFile(path, lb.GetTopScores()); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// pruneBackups deletes all but the newest keep snapshots in dir
+func pruneBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), backupPrefix) && strings.HasSuffix(e.Name(), backupSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+
+	// The timestamp layout sorts lexically in chronological order
+	sort.Strings(names)
+	var errs []string
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("remove old backups: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// backupNow writes a snapshot outside the regular schedule, e.g. after an
+// admin edit, and prunes old ones
+func (s *Server) backupNow() (string, error) {
+	path, err := writeBackup(s.lb, s.backupDir, s.now())
+	if err != nil {
+		return "", err
+	}
+	return path, pruneBackups(s.backupDir, s.backupKeep)
+}
+
+// finalBackup writes one last snapshot on shutdown, giving up when ctx is
+// done so a slow disk can't hold up exit
+func (s *Server) finalBackup(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		path, err := writeBackup(s.lb, s.backupDir, s.now())
+		if err == nil {
+			slog.Info("wrote final leaderboard backup", "path", path)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runBackups snapshots the board on the configured schedule until ctx is
+// cancelled
+func (s *Server) runBackups(ctx context.Context) {
+	dir := s.backupDir
+	ticker := time.NewTicker(s.backupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		path, err := writeBackup(s.lb, dir, s.now())
+		if err != nil {
+			slog.Error("failed to write leaderboard backup", "dir", dir, "err", err)
+			continue
+		}
+		slog.Info("wrote leaderboard backup", "path", path)
+
+		if err := pruneBackups(dir, s.backupKeep); err != nil {
+			slog.Error("failed to prune leaderboard backups", "dir", dir, "err", err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// boardListenerBuffer is how many events a listener may fall behind by
+// before further events for it are dropped
+const boardListenerBuffer = 256
+
+// BoardEventKind says what kind of change a BoardEvent describes
+type BoardEventKind string
+
+// Kinds of board change
+const (
+	// BoardAdd is a submission placing on the board
+	BoardAdd BoardEventKind = "add"
+	// BoardReset is the board being cleared, e.g. at the daily reset
+	BoardReset BoardEventKind = "reset"
+	// BoardRemove is an admin purge or delete taking entries off
+	BoardRemove BoardEventKind = "remove"
+	// BoardRename is an admin renaming a player
+	BoardRename BoardEventKind = "rename"
+	// BoardReplace is the whole board being swapped, by a follower sync or
+	// a restored archive
+	BoardReplace BoardEventKind = "replace"
+)
+
+// BoardEvent describes one change to the board. Added and Evicted hold the
+// entries that appeared on and dropped off the board with it: an add that
+// pushes the last entry off reports both, and a rename reports the old
+// entries evicted and the renamed ones added.
+type BoardEvent struct {
+	Kind    BoardEventKind `json:"kind"`
+	Version uint64         `json:"version"`
+	At      time.Time      `json:"at"`
+	Added   []Score        `json:"added,omitempty"`
+	Evicted []Score        `json:"evicted,omitempty"`
+}
+
+// boardListener runs one registered callback on its own goroutine, so a
+// slow callback only delays its own events
+type boardListener struct {
+	fn func(BoardEvent)
+	ch chan BoardEvent
+}
+
+func (bl *boardListener) run() {
+	for ev := range bl.ch {
+		bl.call(ev)
+	}
+}
+
+// call runs the callback, recovering from a panic so a faulty listener
+// can't take the server down
+func (bl *boardListener) call(ev BoardEvent) {
+	defer func() {
+		if err := recover(); err != nil {
+			slog.Error("board listener panicked", "kind", ev.Kind, "version", ev.Version, "err", err)
+		}
+	}()
+	bl.fn(ev)
+}
+
+// OnChange registers fn to be called after every board change. Calls for
+// one listener arrive in order but asynchronously; a listener that falls
+// boardListenerBuffer events behind misses events until it catches up.
+func (lb *Leaderboard) OnChange(fn func(BoardEvent)) {
+	bl := &boardListener{fn: fn, ch: make(chan BoardEvent, boardListenerBuffer)}
+	go bl.run()
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.listeners = append(lb.listeners, bl)
+}
+
+// notify tells listeners about the change from prev to the current board.
+// lb.mu must be held for writing.
+func (lb *Leaderboard) notify(kind BoardEventKind, prev []Score) {
+	if len(lb.listeners) == 0 {
+		return
+	}
+
+	ev := BoardEvent{Kind: kind, Version: lb.version, At: lb.now().UTC()}
+	before := make(map[Score]bool, len(prev))
+	for _, e := range prev {
+		before[e] = true
+	}
+	after := make(map[Score]bool, len(lb.entries))
+	for _, e := range lb.entries {
+		after[e] = true
+		if !before[e] {
+			ev.Added = append(ev.Added, e)
+		}
+	}
+	for _, e := range prev {
+		if !after[e] {
+			ev.Evicted = append(ev.Evicted, e)
+		}
+	}
+
+	for _, bl := range lb.listeners {
+		select {
+		case bl.ch <- ev:
+		default:
+			slog.Warn("board listener is falling behind, dropping event", "kind", kind, "version", ev.Version)
+		}
+	}
+}
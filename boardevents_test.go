@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// eventSummary is a BoardEvent in comparable form: its kind and the names
+// and scores added and evicted
+func eventSummary(ev BoardEvent) string {
+	names := func(scores []Score) []string {
+		out := []string{}
+		for _, e := range scores {
+			out = append(out, fmt.Sprintf("%s:%v", e.Name, e.Score))
+		}
+		return out
+	}
+	return fmt.Sprintf("%s +%v -%v", ev.Kind, names(ev.Added), names(ev.Evicted))
+}
+
+func TestBoardListenerEvents(t *testing.T) {
+	add := func(name string, score float64) func(*Leaderboard) {
+		return func(lb *Leaderboard) { lb.AddScore(t.Context(), name, score) }
+	}
+	tests := []struct {
+		name string
+		ops  []func(*Leaderboard)
+		want []string
+	}{
+		{name: "adds", ops: []func(*Leaderboard){add("ann", 10), add("bob", 20)}, want: []string{"add +[ann:10] -[]", "add +[bob:20] -[]"}},
+		{
+			name: "add pushing the last entry off",
+			ops:  []func(*Leaderboard){add("ann", 10), add("bob", 20), add("cat", 30)},
+			want: []string{"add +[ann:10] -[]", "add +[bob:20] -[]", "add +[cat:30] -[ann:10]"},
+		},
+		{name: "score off the board", ops: []func(*Leaderboard){add("ann", 10), add("bob", 20), add("cat", 5)}, want: []string{"add +[ann:10] -[]", "add +[bob:20] -[]"}},
+		{
+			name: "reset",
+			ops:  []func(*Leaderboard){add("ann", 10), func(lb *Leaderboard) { lb.Reset() }},
+			want: []string{"add +[ann:10] -[]", "reset +[] -[ann:10]"},
+		},
+		{
+			name: "rename",
+			ops:  []func(*Leaderboard){add("ann", 10), func(lb *Leaderboard) { lb.RenamePlayer("ann", "anna") }},
+			want: []string{"add +[ann:10] -[]", "rename +[anna:10] -[ann:10]"},
+		},
+		{
+			name: "purge",
+			ops:  []func(*Leaderboard){add("ann", 10), add("bob", 99), func(lb *Leaderboard) { lb.PurgeScores(50, 100) }},
+			want: []string{"add +[ann:10] -[]", "add +[bob:99] -[]", "remove +[] -[bob:99]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size = 2
+			events := make(chan BoardEvent, 10)
+			lb.OnChange(func(ev BoardEvent) { events <- ev })
+			for _, op := range tt.ops {
+				op(lb)
+			}
+
+			var prev uint64
+			for i, want := range tt.want {
+				select {
+				case ev := <-events:
+					if got := eventSummary(ev); got != want {
+						t.Errorf("event %d = %s, want %s", i, got, want)
+					}
+					if ev.Version <= prev {
+						t.Errorf("event %d has version %d, not past %d", i, ev.Version, prev)
+					}
+					prev = ev.Version
+				case <-time.After(time.Second):
+					t.Fatalf("event %d never arrived, want %s", i, want)
+				}
+			}
+			select {
+			case ev := <-events:
+				t.Errorf("unexpected event %s", eventSummary(ev))
+			case <-time.After(20 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestBoardListenerIsolation(t *testing.T) {
+	lb := NewLeaderboard()
+	// A listener that panics on every event
+	lb.OnChange(func(BoardEvent) { panic("bad listener") })
+	// A listener stuck until the end of the test
+	stuck := make(chan struct{})
+	defer close(stuck)
+	lb.OnChange(func(BoardEvent) { <-stuck })
+	events := make(chan BoardEvent, 10)
+	lb.OnChange(func(ev BoardEvent) { events <- ev })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 3 {
+			lb.AddScore(t.Context(), fmt.Sprint("p", i), float64(i))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a stuck listener blocked submissions")
+	}
+
+	for i := range 3 {
+		select {
+		case <-events:
+		case <-time.After(time.Second):
+			t.Fatalf("healthy listener got %d of 3 events", i)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxSubmitBodyBytes caps a submission body after decompression
+const maxSubmitBodyBytes = 8 << 10
+
+// errBodyTooLarge is returned once a body exceeds its decompressed cap
+var errBodyTooLarge = errors.New("request body too large")
+
+// cappedReader fails with errBodyTooLarge instead of silently truncating
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.remaining < 0 {
+		return 0, errBodyTooLarge
+	}
+	// Read one byte past the cap so an oversized body is detected rather
+	// than cut off at exactly the limit
+	if int64(len(p)) > c.remaining+1 {
+		p = p[:c.remaining+1]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// requestBody returns r's body decoded per its Content-Encoding (gzip,
+// deflate or none) and capped at limit bytes after decoding, so a small
+// compressed payload can't expand without bound
+func requestBody(w http.ResponseWriter, r *http.Request, limit int64) (io.Reader, *submitError) {
+	raw := http.MaxBytesReader(w, r.Body, limit)
+
+	var body io.Reader
+	var err error
+	switch enc := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); enc {
+	case "", "identity":
+		body = raw
+	case "gzip", "x-gzip":
+		body, err = gzip.NewReader(raw)
+	case "deflate":
+		body, err = zlib.NewReader(raw)
+	default:
+		return nil, &submitError{Status: http.StatusUnsupportedMediaType, Message: "Unsupported Content-Encoding " + enc}
+	}
+	if err != nil {
+		return nil, &submitError{Status: http.StatusBadRequest, Message: "Invalid compressed body"}
+	}
+	return &cappedReader{r: body, remaining: limit}, nil
+}
+
+// bodyTooLarge reports whether err came from exceeding a body cap
+func bodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.Is(err, errBodyTooLarge) || errors.As(err, &maxErr)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	default:
+		return data
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestSubmitCompressedBody(t *testing.T) {
+	valid := []byte(`{"name":"ann","score":10}`)
+	// Padding compresses to almost nothing but decodes past the cap
+	bomb := []byte(`{"name":"ann","score":10,"meta":{"pad":"` + strings.Repeat("a", maxSubmitBodyBytes) + `"}}`)
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		// raw sends body as is under encoding instead of compressing it
+		raw    bool
+		status int
+	}{
+		{name: "plain", body: valid, status: http.StatusCreated},
+		{name: "identity", encoding: "identity", body: valid, status: http.StatusCreated},
+		{name: "gzip", encoding: "gzip", body: valid, status: http.StatusCreated},
+		{name: "deflate", encoding: "deflate", body: valid, status: http.StatusCreated},
+		{name: "gzip over the decompressed cap", encoding: "gzip", body: bomb, status: http.StatusRequestEntityTooLarge},
+		{name: "deflate over the decompressed cap", encoding: "deflate", body: bomb, status: http.StatusRequestEntityTooLarge},
+		{name: "corrupt gzip", encoding: "gzip", body: valid, raw: true, status: http.StatusBadRequest},
+		{name: "unsupported encoding", encoding: "br", body: valid, status: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			body := tt.body
+			if !tt.raw {
+				body = compress(t, tt.encoding, body)
+			}
+			if len(body) > maxSubmitBodyBytes {
+				t.Fatalf("compressed body is %d bytes, over the cap before decoding", len(body))
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/scores", bytes.NewReader(body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			testHandler(s).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if placed := len(s.lb.GetTopScores()) == 1; placed != (tt.status == http.StatusCreated) {
+				t.Errorf("score recorded = %v with status %d", placed, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCappedReader(t *testing.T) {
+	tests := []struct {
+		size, limit int
+		tooLarge    bool
+	}{
+		{size: 0, limit: 4},
+		{size: 4, limit: 4},
+		{size: 5, limit: 4, tooLarge: true},
+		{size: 1000, limit: 4, tooLarge: true},
+	}
+	for _, tt := range tests {
+		r := &cappedReader{r: strings.NewReader(strings.Repeat("x", tt.size)), remaining: int64(tt.limit)}
+		data, err := io.ReadAll(r)
+		if bodyTooLarge(err) != tt.tooLarge {
+			t.Errorf("%d bytes under a cap of %d: err = %v, want too large %v", tt.size, tt.limit, err, tt.tooLarge)
+		}
+		if !tt.tooLarge && len(data) != tt.size {
+			t.Errorf("%d bytes under a cap of %d: read %d", tt.size, tt.limit, len(data))
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// breakerWindow is the span over which the global submission rate is measured
+const breakerWindow = 10 * time.Second
+
+// slidingCounter counts events over a sliding window made of one-second
+// buckets, using only atomic operations
+type slidingCounter struct {
+	buckets []slidingBucket
+}
+
+type slidingBucket struct {
+	second atomic.Int64
+	count  atomic.Int64
+}
+
+func newSlidingCounter(window time.Duration) *slidingCounter {
+	n := int(window / time.Second)
+	if n < 1 {
+		n = 1
+	}
+	return &slidingCounter{buckets: make([]slidingBucket, n)}
+}
+
+// Add records one event at t
+func (sc *slidingCounter) Add(t time.Time) {
+	sec := t.Unix()
+	b := &sc.buckets[sec%int64(len(sc.buckets))]
+	if old := b.second.Load(); old != sec && b.second.CompareAndSwap(old, sec) {
+		b.count.Store(0)
+	}
+	b.count.Add(1)
+}
+
+// Count returns the number of events in the window ending at t
+func (sc *slidingCounter) Count(t time.Time) int64 {
+	now := t.Unix()
+	oldest := now - int64(len(sc.buckets)) + 1
+
+	var total int64
+	for i := range sc.buckets {
+		b := &sc.buckets[i]
+		if sec := b.second.Load(); sec >= oldest && sec <= now {
+			total += b.count.Load()
+		}
+	}
+	return total
+}
+
+// RateAt returns the average number of events per second over the window
+// ending at t
+func (sc *slidingCounter) RateAt(t time.Time) float64 {
+	return float64(sc.Count(t)) / float64(len(sc.buckets))
+}
+
+// submissionsOverloaded records a submission attempt and reports whether the
+// global rate now exceeds the configured limit
+func (s *Server) submissionsOverloaded() bool {
+	t := s.now()
+	s.submitRate.Add(t)
+	return s.maxSubmitRate > 0 && s.submitRate.RateAt(t) > s.maxSubmitRate
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// changeLogSize is how many past board versions are kept for diffing
+const changeLogSize = 64
+
+// boardSnapshot is the board as it stood at a given version
+type boardSnapshot struct {
+	version uint64
+	entries []Score
+}
+
+// recordChange bumps the board version, remembers the new state and
+// tells listeners what kind of change it was. Nothing is recorded while
+// replaying. lb.mu must be held for writing.
+func (lb *Leaderboard) recordChange(kind BoardEventKind) {
+	if lb.replaying {
+		return
+	}
+	var prev []Score
+	if n := len(lb.changeLog); n > 0 {
+		prev = lb.changeLog[n-1].entries
+	}
+	lb.logVersion()
+	lb.notify(kind, prev)
+}
+
+// logVersion bumps the board version and remembers the new state. lb.mu
+// must be held for writing.
+func (lb *Leaderboard) logVersion() {
+	lb.version++
+	lb.changeLog = append(lb.changeLog, boardSnapshot{
+		version: lb.version,
+		entries: slices.Clone(lb.entries),
+	})
+	if len(lb.changeLog) > changeLogSize {
+		lb.changeLog = lb.changeLog[len(lb.changeLog)-changeLogSize:]
+	}
+}
+
+// Version returns the current board version
+func (lb *Leaderboard) Version() uint64 {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.version
+}
+
+var (
+	errVersionFuture = errors.New("version is from the future")
+	errVersionGone   = errors.New("version is no longer retained")
+)
+
+// BoardAt returns the board as it stood at version. It fails with
+// errVersionGone, along with the oldest version still retained, once
+// version has aged out of the change log.
+func (lb *Leaderboard) BoardAt(version uint64) ([]Score, uint64, error) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	if version > lb.version {
+		return nil, 0, errVersionFuture
+	}
+	if version == lb.version {
+		return slices.Clone(lb.entries), 0, nil
+	}
+	for _, snap := range lb.changeLog {
+		if snap.version == version {
+			return slices.Clone(snap.entries), 0, nil
+		}
+	}
+	// Version 0 is the empty board, known for as long as version 1 is
+	if version == 0 && len(lb.changeLog) > 0 && lb.changeLog[0].version == 1 {
+		return []Score{}, 0, nil
+	}
+
+	oldest := lb.version
+	if len(lb.changeLog) > 0 {
+		oldest = lb.changeLog[0].version
+	}
+	return nil, oldest, errVersionGone
+}
+
+// RankedScore is a board entry along with its 1-based rank
+type RankedScore struct {
+	Rank int `json:"rank"`
+	Score
+}
+
+// MovedScore is an entry whose rank changed between two versions
+type MovedScore struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	Score
+}
+
+// BoardChanges describes how the board changed since a client's version.
+// When the requested version is no longer retained, Full is set and
+// Entries holds the whole current board instead of a diff.
+type BoardChanges struct {
+	Version uint64        `json:"version"`
+	Full    bool          `json:"full"`
+	Entries []Score       `json:"entries,omitempty"`
+	Added   []RankedScore `json:"added,omitempty"`
+	Removed []Score       `json:"removed,omitempty"`
+	Moved   []MovedScore  `json:"moved,omitempty"`
+}
+
+// ChangesSince diffs the current board against version since. It returns
+// false if since is newer than the current version.
+func (lb *Leaderboard) ChangesSince(since uint64) (BoardChanges, bool) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	changes := BoardChanges{Version: lb.version}
+	if since > lb.version {
+		return changes, false
+	}
+
+	var old []Score
+	found := since == 0
+	for _, snap := range lb.changeLog {
+		if snap.version == since {
+			old, found = snap.entries, true
+			break
+		}
+	}
+	if !found {
+		changes.Full = true
+		changes.Entries = slices.Clone(lb.entries)
+		return changes, true
+	}
+	return diffBoards(old, lb.entries, lb.version), true
+}
+
+// diffBoards describes how cur, the board at version, differs from old
+func diffBoards(old, cur []Score, version uint64) BoardChanges {
+	changes := BoardChanges{Version: version}
+	oldRank := make(map[Score]int, len(old))
+	for i, s := range old {
+		oldRank[s] = i + 1
+	}
+	for i, s := range cur {
+		from, ok := oldRank[s]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, RankedScore{Rank: i + 1, Score: s})
+		case from != i+1:
+			changes.Moved = append(changes.Moved, MovedScore{From: from, To: i + 1, Score: s})
+		}
+		delete(oldRank, s)
+	}
+	for _, s := range old {
+		if _, ok := oldRank[s]; ok {
+			changes.Removed = append(changes.Removed, s)
+		}
+	}
+	return changes
+}
+
+// frozenChangesSince diffs the frozen board, at version, against version
+// since, so a frozen board's diffs stop at the freeze
+func (lb *Leaderboard) frozenChangesSince(since uint64, frozen []Score, version uint64) (BoardChanges, bool) {
+	if since > version {
+		return BoardChanges{Version: version}, false
+	}
+	if since == 0 {
+		return diffBoards(nil, frozen, version), true
+	}
+	old, _, err := lb.BoardAt(since)
+	if err != nil {
+		return BoardChanges{Version: version, Full: true, Entries: frozen}, true
+	}
+	return diffBoards(old, frozen, version), true
+}
+
+// handleGetChanges handles GET /api/leaderboard/changes?since=<version>
+func (s *Server) handleGetChanges(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	since, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid since version", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		changes BoardChanges
+		ok      bool
+	)
+	if frozen, version, _, isFrozen := s.freeze.Frozen(); isFrozen {
+		changes, ok = s.lb.frozenChangesSince(since, frozen, version)
+	} else {
+		changes, ok = s.lb.ChangesSince(since)
+	}
+	if !ok {
+		http.Error(w, "Version is from the future", http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, changes)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckEnvironment reports problems Validate can't see because they depend
+// on the machine the server would run on: unreadable files, unusable
+// directories and unknown timezones. Warnings are settings that work but
+// are likely unintended.
+func (c Config) CheckEnvironment() (warnings []string, err error) {
+	var errs []error
+	for _, relay := range c.Relays {
+		if u, err := url.Parse(relay); err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid relay %q: must be an absolute ws or wss URL", relay))
+		}
+	}
+	if c.ResetTimezone != "" {
+		if _, err := time.LoadLocation(c.ResetTimezone); err != nil {
+			errs = append(errs, fmt.Errorf("invalid resetTimezone %q: %w", c.ResetTimezone, err))
+		}
+	}
+	if c.TLSCert != "" && c.TLSKey != "" {
+		if _, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey); err != nil {
+			errs = append(errs, fmt.Errorf("invalid TLS certificate: %w", err))
+		}
+	}
+
+	if err := checkWebDir(c.WebDir, c.Index); err != nil {
+		if c.StrictWebDir {
+			errs = append(errs, err)
+		} else {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	dirs := []struct{ setting, path string }{
+		{"dataDir", c.DataDir},
+		{"backupDir", c.BackupDir},
+	}
+	if c.EventLog != "" {
+		dirs = append(dirs, struct{ setting, path string }{"eventLog", filepath.Dir(c.EventLog)})
+	}
+	if c.AuditLog != "" {
+		dirs = append(dirs, struct{ setting, path string }{"auditLog", filepath.Dir(c.AuditLog)})
+	}
+	for _, d := range dirs {
+		if err := checkDirUsable(d.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.setting, err))
+		}
+	}
+	if c.WarmUp {
+		if _, err := os.Stat(c.EventLog); errors.Is(err, fs.ErrNotExist) {
+			warnings = append(warnings, fmt.Sprintf("warmUp is set but event log %s doesn't exist yet", c.EventLog))
+		}
+	}
+
+	if c.AdminToken == "" {
+		warnings = append(warnings, "adminToken is empty, so the admin API is disabled")
+	} else if len(c.AdminToken) < 16 {
+		warnings = append(warnings, "adminToken is shorter than 16 characters")
+	}
+	return warnings, errors.Join(errs...)
+}
+
+// checkDirUsable reports whether dir is, or could be created as, a
+// directory; an empty dir is the working directory
+func checkDirUsable(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	for p := dir; ; p = filepath.Dir(p) {
+		fi, err := os.Stat(p)
+		if errors.Is(err, fs.ErrNotExist) && filepath.Dir(p) != p {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", p)
+		}
+		return nil
+	}
+}
+
+// checkConfig prints the result of validating c for -check-config and
+// returns the exit code
+func checkConfig(w io.Writer, c Config) int {
+	errs := c.Validate()
+	warnings, envErrs := c.CheckEnvironment()
+	for _, warning := range warnings {
+		fmt.Fprintln(w, "warning:", warning)
+	}
+
+	var list []error
+	for _, err := range []error{errs, envErrs} {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			list = append(list, joined.Unwrap()...)
+		} else if err != nil {
+			list = append(list, err)
+		}
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(w, "configuration OK")
+		return 0
+	}
+	for _, err := range list {
+		fmt.Fprintln(w, "error:", err)
+	}
+	fmt.Fprintf(w, "%d problem(s) found\n", len(list))
+	return 1
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var errNameClaimed = errors.New("name already claimed")
+
+// nameClaims ties player names to a secret token. Claimed names may only
+// be submitted under with the matching token; unclaimed names stay open.
+type nameClaims struct {
+	mu   sync.Mutex
+	path string
+	// hashes maps a claimed name to the hex SHA-256 of its token
+	hashes map[string]string
+}
+
+func hashClaimToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadNameClaims reads the claims stored at path, if any
+func loadNameClaims(path string) (*nameClaims, error) {
+	nc := &nameClaims{path: path, hashes: make(map[string]string)}
+	if err := readJSONFile(path, &nc.hashes); err != nil {
+		return nil, err
+	}
+	return nc, nil
+}
+
+// Claim reserves name and returns the token required to submit under it
+func (nc *nameClaims) Claim(name string) (string, error) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if _, ok := nc.hashes[name]; ok {
+		return "", errNameClaimed
+	}
+
+	token := rand.Text()
+	nc.hashes[name] = hashClaimToken(token)
+	if nc.path != "" {
+		if err := writeJSONFile(nc.path, nc.hashes); err != nil {
+			delete(nc.hashes, name)
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// Claimed reports whether name has been claimed
+func (nc *nameClaims) Claimed(name string) bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	_, ok := nc.hashes[name]
+	return ok
+}
+
+// Authorized reports whether token may submit under name
+func (nc *nameClaims) Authorized(name, token string) bool {
+	nc.mu.Lock()
+	hash, ok := nc.hashes[name]
+	nc.mu.Unlock()
+
+	if !ok {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashClaimToken(token))) == 1
+}
+
+// handleClaimName handles POST /api/names/claim
+func (s *Server) handleClaimName(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name, err := s.sanitizeName(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), nameErrorStatus(err))
+		return
+	}
+	// A lookalike of a known player claims, and so collides with, that player
+	name = s.lb.CanonicalName(name)
+
+	token, err := s.claims.Claim(name)
+	if errors.Is(err, errNameClaimed) {
+		http.Error(w, "Name already claimed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		loggerFrom(r.Context()).Error("failed to store name claim", "name", name, "err", err)
+		http.Error(w, "Failed to claim name", http.StatusInternalServerError)
+		return
+	}
+
+	loggerFrom(r.Context()).Info("name claimed", "name", name)
+
+	writeJSON(w, r, http.StatusCreated, map[string]string{"name": name, "token": token})
+}
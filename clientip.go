@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parseTrustedProxies parses CIDRs or bare IP addresses of reverse proxies
+// whose X-Forwarded-For header may be believed
+func parseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, item := range list {
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// trusted reports whether addr belongs to one of the trusted proxies
+func trusted(proxies []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the host part of r.RemoteAddr
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// forwardedClientIP walks X-Forwarded-For from the nearest hop outwards and
+// returns the first address that isn't itself a trusted proxy
+func forwardedClientIP(proxies []netip.Prefix, peer netip.Addr, header []string) netip.Addr {
+	var hops []string
+	for _, h := range header {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A malformed hop can't be attributed; stop at the last good one
+			break
+		}
+		client = addr.Unmap()
+		if !trusted(proxies, client) {
+			break
+		}
+	}
+	return client
+}
+
+// withClientIP resolves the caller's address once per request. The
+// X-Forwarded-For header is only honoured when the direct peer is one of
+// the trusted proxies; otherwise RemoteAddr is used.
+func withClientIP(proxies []netip.Prefix, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := remoteIP(r)
+		if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 && len(proxies) > 0 {
+			if peer, err := netip.ParseAddr(ip); err == nil && trusted(proxies, peer) {
+				ip = forwardedClientIP(proxies, peer.Unmap(), xff).String()
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), clientIPKey, ip)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIP returns the caller's address as resolved by withClientIP,
+// falling back to RemoteAddr outside that middleware
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
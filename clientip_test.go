@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		proxies bool
+		remote  string
+		xff     []string
+		want    string
+	}{
+		{name: "no proxy configured", remote: "203.0.113.5:1234", xff: []string{"198.51.100.7"}, want: "203.0.113.5"},
+		{name: "untrusted peer", proxies: true, remote: "203.0.113.5:1234", xff: []string{"198.51.100.7"}, want: "203.0.113.5"},
+		{name: "trusted peer", proxies: true, remote: "10.1.2.3:1234", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "trusted single address", proxies: true, remote: "192.0.2.1:1234", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "trusted peer without header", proxies: true, remote: "10.1.2.3:1234", want: "10.1.2.3"},
+		{name: "spoofed leftmost hop", proxies: true, remote: "10.1.2.3:1234", xff: []string{"1.1.1.1, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "chain of trusted proxies", proxies: true, remote: "10.1.2.3:1234", xff: []string{"198.51.100.7, 10.9.9.9"}, want: "198.51.100.7"},
+		{name: "repeated headers", proxies: true, remote: "10.1.2.3:1234", xff: []string{"198.51.100.7", "10.9.9.9"}, want: "198.51.100.7"},
+		{name: "malformed hop", proxies: true, remote: "10.1.2.3:1234", xff: []string{"bogus, 10.9.9.9"}, want: "10.9.9.9"},
+		{name: "IPv6 trusted peer", proxies: true, remote: "[2001:db8::1]:1234", xff: []string{"2001:db9::7"}, want: "2001:db9::7"},
+		{name: "IPv4-mapped peer", proxies: true, remote: "[::ffff:10.1.2.3]:1234", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trustedList := proxies
+			if !tt.proxies {
+				trustedList = nil
+			}
+			var got string
+			h := withClientIP(trustedList, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = clientIP(r) }))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	for _, item := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0"} {
+		if _, err := parseTrustedProxies([]string{item}); err == nil {
+			t.Errorf("parseTrustedProxies accepted %q", item)
+		}
+	}
+}
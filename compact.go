@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// defaultCompactMinBytes is the event log size below which compaction
+// isn't worth a rewrite
+const defaultCompactMinBytes = 64 << 20
+
+// readEvents decodes every readable line of the event log at path,
+// returning how many torn or malformed lines were skipped
+func readEvents(path string) ([]SubmissionEvent, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var events []SubmissionEvent
+	skipped := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, maxEventLineBytes)
+	for sc.Scan() {
+		var ev SubmissionEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil || !ev.valid() {
+			skipped++
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, skipped, sc.Err()
+}
+
+// Compact rewrites the log to the events keep returns. The new log
+// replaces the old one by rename, so a crash leaves one or the other
+// whole. Appends wait until it is done.
+func (el *EventLog) Compact(keep func([]SubmissionEvent) []SubmissionEvent) (before, after int, err error) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+
+	events, skipped, err := readEvents(el.path)
+	if err != nil {
+		return 0, 0, err
+	}
+	kept := keep(events)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ev := range kept {
+		if err := enc.Encode(ev); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := writeFileAtomic(el.path, buf.Bytes()); err != nil {
+		return 0, 0, err
+	}
+
+	// The old handle still points at the replaced file
+	f, err := os.OpenFile(el.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, 0, err
+	}
+	el.f.Close()
+	el.f, el.enc = f, json.NewEncoder(f)
+	return len(events) + skipped, len(kept), nil
+}
+
+// compactEvents keeps the events warm-up needs to rebuild the same board:
+// those that end up on it, the retained history and the renames. History
+// events older than maxAge before now are dropped too, unless they are on
+// the board. It replays events into a scratch board configured like lb,
+// and heads the kept events with a snapshot of what the dropped ones
+// contributed to stats, the records feed, milestones and last submissions.
+func compactEvents(lb *Leaderboard, events []SubmissionEvent, maxAge time.Duration, now time.Time) []SubmissionEvent {
+	lb.mu.RLock()
+	scratch := NewLeaderboard()
+	scratch.size = lb.size
+	scratch.dedup = lb.dedup
+	scratch.minDisplayScore = lb.minDisplayScore
+	scratch.maxHistory = lb.maxHistory
+	scratch.decay, scratch.now = lb.decay, lb.now
+	scratch.records = &recordLog{size: lb.records.size}
+	if lb.milestones != nil {
+		// Winners come from the events alone; the tracker isn't saved
+		scratch.milestones = &milestoneTracker{}
+		for _, m := range lb.milestones.milestones {
+			scratch.milestones.milestones = append(scratch.milestones.milestones, Milestone{Threshold: m.Threshold})
+		}
+	}
+	lb.mu.RUnlock()
+
+	// IDs number the events so their board and history entries can be
+	// traced back to them
+	stats := newStatsAccumulator()
+	replayEvents(scratch, stats, events, func(i int) uint64 { return uint64(i + 1) })
+
+	needed := make(map[uint64]bool)
+	for _, e := range scratch.entries {
+		needed[e.ID] = true
+	}
+	for _, e := range scratch.history {
+		if maxAge == 0 || now.Sub(e.Timestamp) < maxAge {
+			needed[e.ID] = true
+		}
+	}
+
+	var kept []SubmissionEvent
+	for i, ev := range events {
+		// Renames are few, and kept submissions may need any of them.
+		// Purged submissions leave the scratch board and history, so they
+		// are dropped and the purge isn't needed again.
+		if ev.Type == eventRename || (ev.Type == "" && needed[uint64(i+1)]) {
+			kept = append(kept, ev)
+		}
+	}
+
+	state := scratch.replayState()
+	state.Stats = stats.snapshot()
+	state.Covers = len(kept)
+	snapshot := SubmissionEvent{Type: eventSnapshot, Timestamp: now, State: &state}
+	return append([]SubmissionEvent{snapshot}, kept...)
+}
+
+// runEventLogCompaction compacts the event log every interval once it has
+// grown past minBytes, until ctx is cancelled
+func (s *Server) runEventLogCompaction(ctx context.Context, interval time.Duration, minBytes int64, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fi, err := os.Stat(s.events.path)
+		if err != nil {
+			slog.Error("failed to stat event log", "path", s.events.path, "err", err)
+			continue
+		}
+		if fi.Size() < minBytes {
+			continue
+		}
+
+		start := time.Now()
+		before, after, err := s.events.Compact(func(events []SubmissionEvent) []SubmissionEvent {
+			return compactEvents(s.lb, events, maxAge, s.now())
+		})
+		if err != nil {
+			slog.Error("failed to compact event log", "path", s.events.path, "err", err)
+			continue
+		}
+		slog.Info("compacted event log", "path", s.events.path, "before", before, "after", after, "duration", time.Since(start))
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var compactBase = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func submitted(minute int, name string, score float64) SubmissionEvent {
+	return SubmissionEvent{Timestamp: compactBase.Add(time.Duration(minute) * time.Minute), Name: name, Score: score, Mode: defaultMode}
+}
+
+func renamed(minute int, from, to string) SubmissionEvent {
+	return SubmissionEvent{Type: eventRename, Timestamp: compactBase.Add(time.Duration(minute) * time.Minute), Name: from, To: to}
+}
+
+func purged(minute int, min, max float64) SubmissionEvent {
+	return SubmissionEvent{Type: eventPurge, Timestamp: compactBase.Add(time.Duration(minute) * time.Minute), MinScore: &min, MaxScore: &max}
+}
+
+// replayedState is what warm-up rebuilds, with IDs left out since they
+// number events differently in the full and the compacted log
+type replayedState struct {
+	Board      []Score
+	History    []Score
+	Stats      StatsResponse
+	Records    []RecordEvent
+	Milestones []Milestone
+	LastSubmit map[string]time.Time
+}
+
+func withoutIDs(scores []Score) []Score {
+	out := make([]Score, len(scores))
+	for i, e := range scores {
+		e.ID = 0
+		out[i] = e
+	}
+	return out
+}
+
+func replayInto(lb *Leaderboard, events []SubmissionEvent) replayedState {
+	stats := newStatsAccumulator()
+	next := uint64(0)
+	replayEvents(lb, stats, events, func(int) uint64 { next++; return next })
+
+	records := lb.Records()
+	for i := range records {
+		records[i].ID = 0
+		if records[i].Previous != nil {
+			prev := *records[i].Previous
+			prev.ID = 0
+			records[i].Previous = &prev
+		}
+	}
+	return replayedState{
+		Board:      withoutIDs(lb.GetTopScores()),
+		History:    withoutIDs(lb.History()),
+		Stats:      stats.Stats(),
+		Records:    records,
+		Milestones: lb.Milestones(),
+		LastSubmit: lb.lastSubmit,
+	}
+}
+
+func compactTestBoard(size int, dedup bool) *Leaderboard {
+	lb := NewLeaderboard()
+	lb.size, lb.dedup = size, dedup
+	lb.now = func() time.Time { return compactBase.Add(24 * time.Hour) }
+	lb.milestones = &milestoneTracker{milestones: []Milestone{{Threshold: 10}, {Threshold: 40}}}
+	return lb
+}
+
+func TestCompactEventsPreservesReplayedState(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		dedup  bool
+		events []SubmissionEvent
+		maxAge time.Duration
+		// kept is how many events the compacted log holds, snapshot included
+		kept int
+	}{
+		{
+			name: "submissions pushed off the board",
+			size: 2,
+			events: []SubmissionEvent{
+				submitted(0, "ann", 5), submitted(1, "bob", 12), submitted(2, "cat", 30),
+				submitted(3, "dan", 45), submitted(4, "eve", 8),
+			},
+			maxAge: time.Hour,
+			kept:   3,
+		},
+		{
+			name:  "renames kept with dedup",
+			size:  3,
+			dedup: true,
+			events: []SubmissionEvent{
+				submitted(0, "ann", 15), submitted(1, "ann", 20), submitted(2, "bob", 3),
+				renamed(3, "ann", "anna"), submitted(4, "ann", 50), submitted(5, "cat", 1),
+				submitted(6, "dan", 2), submitted(7, "eve", 4),
+			},
+			maxAge: time.Hour,
+			kept:   5,
+		},
+		{
+			name: "purges applied and dropped",
+			size: 2,
+			events: []SubmissionEvent{
+				submitted(0, "ann", 50), submitted(1, "bob", 20), submitted(2, "cat", 10),
+				purged(3, 40, 60), submitted(4, "dan", 1),
+			},
+			maxAge: time.Hour,
+			kept:   3,
+		},
+		{
+			name: "history kept without an age limit",
+			size: 1,
+			events: []SubmissionEvent{
+				submitted(0, "ann", 5), submitted(1, "bob", 12), submitted(2, "cat", 3),
+			},
+			kept: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := replayInto(compactTestBoard(tt.size, tt.dedup), tt.events)
+			if tt.maxAge > 0 {
+				// Dropped history no longer comes back
+				want.History = want.Board
+			}
+
+			lb := compactTestBoard(tt.size, tt.dedup)
+			compacted := compactEvents(lb, tt.events, tt.maxAge, lb.now())
+			if len(compacted) != tt.kept {
+				t.Fatalf("compacted log has %d events, want %d", len(compacted), tt.kept)
+			}
+			if compacted[0].Type != eventSnapshot {
+				t.Fatalf("compacted log starts with %q, want a snapshot", compacted[0].Type)
+			}
+
+			got := replayInto(compactTestBoard(tt.size, tt.dedup), compacted)
+			if tt.maxAge > 0 {
+				got.History = got.Board
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("replayed compacted log:\n got %+v\nwant %+v", got, want)
+			}
+
+			// Compacting again, with new events appended, changes nothing
+			more := append(compacted, submitted(10, "zed", 60))
+			want = replayInto(compactTestBoard(tt.size, tt.dedup), append(tt.events, submitted(10, "zed", 60)))
+			again := compactEvents(lb, more, tt.maxAge, lb.now())
+			got = replayInto(compactTestBoard(tt.size, tt.dedup), again)
+			if tt.maxAge > 0 {
+				got.History, want.History = got.Board, want.Board
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("replayed twice-compacted log:\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEventLogCompactRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	el, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer el.Close()
+
+	events := []SubmissionEvent{submitted(0, "ann", 5), submitted(1, "bob", 12), renamed(2, "bob", "rob")}
+	for _, ev := range events {
+		if err := el.Record(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lb := compactTestBoard(1, false)
+	before, after, err := el.Compact(func(events []SubmissionEvent) []SubmissionEvent {
+		return compactEvents(lb, events, 0, lb.now())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != 3 || after != 4 {
+		t.Errorf("Compact = %d, %d; want 3, 4", before, after)
+	}
+
+	// Appends after compaction go to the new file
+	if err := el.Record(submitted(3, "cat", 20)); err != nil {
+		t.Fatal(err)
+	}
+	read, skipped, err := readEvents(path)
+	if err != nil || skipped != 0 {
+		t.Fatalf("readEvents: %v, %d skipped", err, skipped)
+	}
+	got := replayInto(compactTestBoard(1, false), read)
+	want := replayInto(compactTestBoard(1, false), append(events, submitted(3, "cat", 20)))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed log:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestWarmUpIsQuiet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	el, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []SubmissionEvent{submitted(0, "ann", 15), submitted(1, "bob", 45)} {
+		if err := el.Record(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	el.Close()
+
+	lb := compactTestBoard(10, false)
+	events := make(chan BoardEvent, 10)
+	lb.OnChange(func(ev BoardEvent) { events <- ev })
+	s := NewServer(WithLeaderboard(lb))
+	if n, err := s.warmUp(path); err != nil || n != 2 {
+		t.Fatalf("warmUp = %d, %v; want 2, nil", n, err)
+	}
+	if got := len(lb.GetTopScores()); got != 2 {
+		t.Fatalf("board has %d entries after warm-up, want 2", got)
+	}
+	if lb.milestones.unsaved {
+		t.Error("milestones awarded during warm-up were left unsaved")
+	}
+
+	// Listeners hear about the first live change, not the replayed ones
+	lb.mu.Lock()
+	lb.addEntry(Score{Name: "cat", Score: 1, Timestamp: compactBase.Add(time.Hour)})
+	lb.recordChange(BoardAdd)
+	lb.mu.Unlock()
+	select {
+	case ev := <-events:
+		if len(ev.Added) != 1 || ev.Added[0].Name != "cat" {
+			t.Errorf("first event after warm-up added %+v, want only cat", ev.Added)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event for the live change")
+	}
+}
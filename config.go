@@ -0,0 +1,587 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every server setting. Values come from, in increasing order
+// of precedence, the defaults, the -config file, environment variables and
+// command-line flags.
+type Config struct {
+	Addr          string   `json:"addr"`
+	Relays        []string `json:"relays"`
+	ListenName    string   `json:"listenName"`
+	ListenRetries int      `json:"listenRetries"`
+	ListenBackoff Duration `json:"listenBackoff"`
+
+	AdminToken string `json:"adminToken" redact:"true"`
+
+	// SigningSecret and SigningPublicKey, when set, require submissions to
+	// carry an X-Signature made with one of SignatureAlgs
+	SigningSecret    string   `json:"signingSecret" redact:"true"`
+	SigningPublicKey string   `json:"signingPublicKey"`
+	SignatureAlgs    []string `json:"signatureAlgs"`
+	EventStart       string   `json:"eventStart"`
+	EventEnd         string   `json:"eventEnd"`
+
+	PollInterval Duration `json:"pollInterval"`
+
+	MaxSubmitRate        float64   `json:"maxSubmitRate"`
+	HistorySize          int       `json:"historySize"`
+	QueueSize            int       `json:"submitQueue"`
+	LeaderboardSize      int       `json:"leaderboardSize"`
+	BoardLabel           string    `json:"boardLabel"`
+	LiveTTL              Duration  `json:"liveTTL"`
+	ScoreDecay           Duration  `json:"scoreDecay"`
+	MaxResponseEntries   int       `json:"maxResponseEntries"`
+	BoardCacheTTL        Duration  `json:"boardCacheTTL"`
+	BoardCacheSize       int       `json:"boardCacheSize"`
+	MinDisplayScore      int       `json:"minDisplayScore"`
+	NamePattern          string    `json:"namePattern"`
+	MaxScoreRate         float64   `json:"maxScoreRate"`
+	RequireSession       bool      `json:"requireSession"`
+	Dedup                bool      `json:"dedup"`
+	CollapseHomoglyphs   bool      `json:"collapseHomoglyphs"`
+	RecordAllSubmissions bool      `json:"recordAllSubmissions"`
+	Milestones           []float64 `json:"milestones"`
+	RecordFeedSize       int       `json:"recordFeedSize"`
+	ScoreDecimals        int       `json:"scoreDecimals"`
+	ScoreFormula         string    `json:"scoreFormula"`
+	ReplayMaxFrames      int       `json:"replayMaxFrames"`
+	ReplayMaxTaps        int       `json:"replayMaxTaps"`
+	JumpFactor           float64   `json:"jumpFactor"`
+	JumpMinHistory       int       `json:"jumpMinHistory"`
+
+	DataDir           string   `json:"dataDir"`
+	BackupDir         string   `json:"backupDir"`
+	BackupInterval    Duration `json:"backupInterval"`
+	BackupKeep        int      `json:"backupKeep"`
+	SelfCheckInterval Duration `json:"selfCheckInterval"`
+	EventLog          string   `json:"eventLog"`
+	WarmUp            bool     `json:"warmUp"`
+	CompactInterval   Duration `json:"compactInterval"`
+	CompactMinBytes   int      `json:"compactMinBytes"`
+	EventMaxAge       Duration `json:"eventMaxAge"`
+	AuditLog          string   `json:"auditLog"`
+	PublishWebhook    string   `json:"publishWebhook" redact:"true"`
+	PushGateway       string   `json:"pushGateway" redact:"true"`
+	PushJob           string   `json:"pushJob"`
+	PushInterval      Duration `json:"pushInterval"`
+
+	// ShutdownTimeout bounds graceful shutdown, including the final flush;
+	// connections still open afterwards are cut and the exit is non-zero
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+
+	DailyReset    bool   `json:"dailyReset"`
+	ResetTimezone string `json:"resetTimezone"`
+	DailyKeep     int    `json:"dailyKeep"`
+
+	Follow         string   `json:"follow"`
+	FollowToken    string   `json:"followToken" redact:"true"`
+	FollowInterval Duration `json:"followInterval"`
+	FollowerWrites string   `json:"followerWrites"`
+
+	// TLSCert and TLSKey, when set, serve HTTPS on the local listener
+	TLSCert string `json:"tlsCert"`
+	TLSKey  string `json:"tlsKey"`
+
+	// HTTP2 serves HTTP/2 alongside HTTP/1.1: over TLS, and as h2c on
+	// plain and portal connections
+	HTTP2 bool `json:"http2"`
+
+	// TrustedProxies lists CIDRs of reverse proxies whose X-Forwarded-For
+	// header is believed
+	TrustedProxies []string `json:"trustedProxies"`
+
+	// AllowedOrigins lists browser origins, or "*", that may open
+	// WebSocket connections; empty only admits same-host origins
+	AllowedOrigins []string `json:"allowedOrigins"`
+
+	// ExemptNames lists players whose requests skip the rate limits, in
+	// addition to those added through the admin API
+	ExemptNames []string `json:"exemptNames"`
+
+	// RateLimits throttles each client per route; the first rule matching a
+	// request applies. Only settable from the config file.
+	RateLimits []RateLimitRule `json:"rateLimits"`
+
+	WebDir       string `json:"webDir"`
+	Index        string `json:"index"`
+	StrictWebDir bool   `json:"strictWebDir"`
+	DevMode      bool   `json:"dev"`
+}
+
+// defaultConfig returns the settings used when nothing overrides them
+func defaultConfig() Config {
+	return Config{
+		Relays: []string{
+			"wss://portal.gosuda.org/relay",
+			"ws://localhost:4017/relay",
+		},
+		ListenName:           "Flappy-Gopher",
+		ListenRetries:        3,
+		ListenBackoff:        Duration{time.Second},
+		HTTP2:                true,
+		HistorySize:          100000,
+		QueueSize:            1024,
+		PollInterval:         Duration{5 * time.Second},
+		NamePattern:          defaultNamePattern,
+		MaxScoreRate:         1,
+		CompactMinBytes:      defaultCompactMinBytes,
+		JumpMinHistory:       defaultJumpMinHistory,
+		Dedup:                true,
+		RecordAllSubmissions: true,
+		LeaderboardSize:      defaultBoardSize,
+		BoardLabel:           defaultBoardLabel,
+		MaxResponseEntries:   defaultMaxResponseEntries,
+		BoardCacheSize:       16,
+		SignatureAlgs:        []string{sigHMACSHA256, sigEd25519},
+		RecordFeedSize:       defaultRecordFeedSize,
+		ScoreFormula:         defaultScoreFormula,
+		ReplayMaxFrames:      defaultReplayMaxFrames,
+		ReplayMaxTaps:        defaultReplayMaxTaps,
+		DataDir:              "data",
+		BackupDir:            "backups",
+		BackupKeep:           24,
+		DailyKeep:            30,
+		FollowInterval:       Duration{5 * time.Second},
+		FollowerWrites:       followerWritesRedirect,
+		PushJob:              defaultPushJob,
+		PushInterval:         Duration{15 * time.Second},
+		ShutdownTimeout:      Duration{10 * time.Second},
+		WebDir:               "./web",
+		Index:                "index.html",
+	}
+}
+
+// LoadConfig returns the defaults overlaid with the JSON file at path, if
+// path is non-empty, and then with environment variables
+func LoadConfig(path string) (Config, error) {
+	c := defaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return c, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil {
+			return c, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	c.applyEnv()
+	return c, nil
+}
+
+// applyEnv overrides c with any of the supported environment variables
+// that are set
+func (c *Config) applyEnv() {
+	c.AdminToken = envString("ADMIN_TOKEN", c.AdminToken)
+	c.SigningSecret = envString("SIGNING_SECRET", c.SigningSecret)
+	c.SigningPublicKey = envString("SIGNING_PUBLIC_KEY", c.SigningPublicKey)
+	c.EventStart = envString("EVENT_START", c.EventStart)
+	c.EventEnd = envString("EVENT_END", c.EventEnd)
+	c.NamePattern = envString("NAME_PATTERN", c.NamePattern)
+	c.ResetTimezone = envString("RESET_TIMEZONE", c.ResetTimezone)
+	c.DailyReset = envBool("DAILY_RESET", c.DailyReset)
+	c.DevMode = envBool("DEV_MODE", c.DevMode)
+	c.WarmUp = envBool("WARM_UP", c.WarmUp)
+	c.CompactInterval.Duration = envDuration("COMPACT_INTERVAL", c.CompactInterval.Duration)
+	c.CompactMinBytes = envInt("COMPACT_MIN_BYTES", c.CompactMinBytes)
+	c.EventMaxAge.Duration = envDuration("EVENT_MAX_AGE", c.EventMaxAge.Duration)
+	c.HTTP2 = envBool("HTTP2", c.HTTP2)
+	c.RecordAllSubmissions = envBool("RECORD_ALL_SUBMISSIONS", c.RecordAllSubmissions)
+	c.CollapseHomoglyphs = envBool("COLLAPSE_HOMOGLYPHS", c.CollapseHomoglyphs)
+	c.MinDisplayScore = envInt("MIN_DISPLAY_SCORE", c.MinDisplayScore)
+	c.LeaderboardSize = envInt("LEADERBOARD_SIZE", c.LeaderboardSize)
+	c.BoardLabel = envString("BOARD_LABEL", c.BoardLabel)
+	c.LiveTTL.Duration = envDuration("LIVE_TTL", c.LiveTTL.Duration)
+	c.ScoreDecay.Duration = envDuration("SCORE_DECAY", c.ScoreDecay.Duration)
+	c.MaxResponseEntries = envInt("MAX_RESPONSE_ENTRIES", c.MaxResponseEntries)
+	c.FollowToken = envString("FOLLOW_TOKEN", c.FollowToken)
+	c.PublishWebhook = envString("PUBLISH_WEBHOOK", c.PublishWebhook)
+	c.PushGateway = envString("PUSH_GATEWAY", c.PushGateway)
+	c.ScoreDecimals = envInt("SCORE_DECIMALS", c.ScoreDecimals)
+	c.ScoreFormula = envString("SCORE_FORMULA", c.ScoreFormula)
+	c.ReplayMaxFrames = envInt("REPLAY_MAX_FRAMES", c.ReplayMaxFrames)
+	c.ReplayMaxTaps = envInt("REPLAY_MAX_TAPS", c.ReplayMaxTaps)
+	c.ShutdownTimeout.Duration = envDuration("SHUTDOWN_TIMEOUT", c.ShutdownTimeout.Duration)
+	if v := os.Getenv("MILESTONES"); v != "" {
+		if err := (*floatList)(&c.Milestones).Set(v); err != nil {
+			slog.Warn("ignoring malformed environment variable", "key", "MILESTONES", "value", v, "err", err)
+		}
+	}
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		(*stringList)(&c.TrustedProxies).Set(v)
+	}
+	if v := os.Getenv("ALLOWED_ORIGINS"); v != "" {
+		(*stringList)(&c.AllowedOrigins).Set(v)
+	}
+	if v := os.Getenv("EXEMPT_NAMES"); v != "" {
+		(*stringList)(&c.ExemptNames).Set(v)
+	}
+}
+
+// RegisterFlags binds a command-line flag to every setting in c
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "local listen address used as a fallback when the portal is unreachable (e.g. :8080)")
+	fs.Var((*stringList)(&c.Relays), "relays", "comma-separated portal relay URLs")
+	fs.StringVar(&c.ListenName, "listen-name", c.ListenName, "name to register with the portal")
+	fs.IntVar(&c.ListenRetries, "listen-retries", c.ListenRetries, "number of times to retry connecting to the portal")
+	fs.DurationVar(&c.ListenBackoff.Duration, "listen-backoff", c.ListenBackoff.Duration, "initial backoff between portal connection attempts, doubled on each retry")
+	fs.StringVar(&c.SigningSecret, "signing-secret", c.SigningSecret, "HMAC-SHA256 key submissions must be signed with (defaults to $SIGNING_SECRET; signing is off when neither key is set)")
+	fs.StringVar(&c.SigningPublicKey, "signing-public-key", c.SigningPublicKey, "base64 Ed25519 public key submissions may be signed against (defaults to $SIGNING_PUBLIC_KEY)")
+	fs.Var((*stringList)(&c.SignatureAlgs), "signature-algs", "comma-separated signature algorithms accepted in X-Sig-Alg: "+strings.Join(supportedSignatureAlgs, ", "))
+	fs.StringVar(&c.AdminToken, "admin-token", c.AdminToken, "bearer token required by the admin API (defaults to $ADMIN_TOKEN; admin API is disabled when empty)")
+	fs.StringVar(&c.EventStart, "event-start", c.EventStart, "RFC3339 time before which submissions are rejected (defaults to $EVENT_START)")
+	fs.StringVar(&c.EventEnd, "event-end", c.EventEnd, "RFC3339 time from which submissions are rejected (defaults to $EVENT_END)")
+	fs.DurationVar(&c.PollInterval.Duration, "poll-interval", c.PollInterval.Duration, "base next-poll delay hinted to leaderboard clients via X-Poll-After, with ±20% jitter (0 disables)")
+	fs.Float64Var(&c.MaxSubmitRate, "max-submit-rate", c.MaxSubmitRate, "global submissions per second above which new submissions are shed with 503 (0 disables)")
+	fs.IntVar(&c.HistorySize, "history-size", c.HistorySize, "number of past submissions retained in memory for export (0 keeps all)")
+	fs.IntVar(&c.QueueSize, "submit-queue", c.QueueSize, "capacity of the submission queue; submissions beyond it get 503 (0 writes directly under the lock)")
+	fs.IntVar(&c.LeaderboardSize, "leaderboard-size", c.LeaderboardSize, fmt.Sprintf("number of entries shown on the board, 1-%d (defaults to $LEADERBOARD_SIZE)", maxBoardSize))
+	fs.StringVar(&c.BoardLabel, "board-label", c.BoardLabel, "name of this board in leaderboard envelopes, to tell game variants apart (defaults to $BOARD_LABEL)")
+	fs.DurationVar(&c.LiveTTL.Duration, "live-ttl", c.LiveTTL.Duration, "how long a score competes on /api/leaderboard/live after it is submitted (0 disables the live board; defaults to $LIVE_TTL)")
+	fs.DurationVar(&c.ScoreDecay.Duration, "score-decay", c.ScoreDecay.Duration, "rank board entries by their score lowered linearly to nothing over this long after submission, e.g. 720h; raw scores are kept (0 disables; defaults to $SCORE_DECAY)")
+	fs.IntVar(&c.MaxResponseEntries, "max-response-entries", c.MaxResponseEntries, "most entries any list response returns; longer lists are cut and flagged with X-Truncated (defaults to $MAX_RESPONSE_ENTRIES)")
+	fs.DurationVar(&c.BoardCacheTTL.Duration, "board-cache-ttl", c.BoardCacheTTL.Duration, "how long leaderboard reads are cached; submissions invalidate the cache (0 disables)")
+	fs.IntVar(&c.BoardCacheSize, "board-cache-size", c.BoardCacheSize, "most distinct board reads kept in the cache")
+	fs.IntVar(&c.MinDisplayScore, "min-display-score", c.MinDisplayScore, "lowest score shown on the board; lower scores are recorded but not displayed (defaults to $MIN_DISPLAY_SCORE)")
+	fs.StringVar(&c.NamePattern, "name-pattern", c.NamePattern, "regular expression every player name must fully match (defaults to $NAME_PATTERN)")
+	fs.Float64Var(&c.MaxScoreRate, "max-score-rate", c.MaxScoreRate, "highest plausible points per second of play for session-bound submissions")
+	fs.Float64Var(&c.JumpFactor, "jump-factor", c.JumpFactor, "hold for admin review any score more than this many times the player's previous best (0 disables)")
+	fs.IntVar(&c.JumpMinHistory, "jump-min-history", c.JumpMinHistory, "earlier submissions a player needs before -jump-factor applies")
+	fs.BoolVar(&c.RequireSession, "require-session", c.RequireSession, "reject submissions that are not tied to a game started with /api/game/start")
+	fs.BoolVar(&c.Dedup, "dedup", c.Dedup, "keep only each player's best score on the board")
+	fs.BoolVar(&c.CollapseHomoglyphs, "collapse-homoglyphs", c.CollapseHomoglyphs, "treat names that differ only by lookalike characters, e.g. Cyrillic 'а' for 'a', as the first such player seen (defaults to $COLLAPSE_HOMOGLYPHS)")
+	fs.BoolVar(&c.RecordAllSubmissions, "record-all-submissions", c.RecordAllSubmissions, "record scores that don't beat the player's best in history and stats; when false they are answered 200 with improved=false (defaults to $RECORD_ALL_SUBMISSIONS)")
+	fs.Var((*floatList)(&c.Milestones), "milestones", "comma-separated scores; the first player to reach each is recorded at /api/milestones (defaults to $MILESTONES)")
+	fs.IntVar(&c.RecordFeedSize, "record-feed-size", c.RecordFeedSize, "number of all-time record events kept for /api/records.atom")
+	fs.IntVar(&c.ScoreDecimals, "score-decimals", c.ScoreDecimals, "decimal places allowed in scores, for modes scored by time (0 accepts whole numbers only; defaults to $SCORE_DECIMALS)")
+	fs.StringVar(&c.ScoreFormula, "score-formula", c.ScoreFormula, "formula scoring submissions that send game params instead of a score: \"raw\" or \"difficulty-weighted\" (defaults to $SCORE_FORMULA)")
+	fs.IntVar(&c.ReplayMaxFrames, "replay-max-frames", c.ReplayMaxFrames, "most frames simulated when checking a submitted replay (defaults to $REPLAY_MAX_FRAMES)")
+	fs.IntVar(&c.ReplayMaxTaps, "replay-max-taps", c.ReplayMaxTaps, "most taps accepted in a submitted replay (defaults to $REPLAY_MAX_TAPS)")
+	fs.StringVar(&c.DataDir, "data-dir", c.DataDir, "directory for durable server state such as name claims")
+	fs.StringVar(&c.BackupDir, "backup-dir", c.BackupDir, "directory for periodic leaderboard snapshots")
+	fs.DurationVar(&c.BackupInterval.Duration, "backup-interval", c.BackupInterval.Duration, "how often to snapshot the leaderboard into -backup-dir (0 disables)")
+	fs.DurationVar(&c.SelfCheckInterval.Duration, "self-check-interval", c.SelfCheckInterval.Duration, "how often to verify the board's invariants, logging and counting violations in /metrics (0 disables)")
+	fs.IntVar(&c.BackupKeep, "backup-keep", c.BackupKeep, "number of most recent snapshots to keep")
+	fs.StringVar(&c.EventLog, "event-log", c.EventLog, "append accepted submissions, including metadata, to this file as newline-delimited JSON")
+	fs.BoolVar(&c.WarmUp, "warm-up", c.WarmUp, "replay the event log into the board, history and stats before serving (defaults to $WARM_UP)")
+	fs.DurationVar(&c.CompactInterval.Duration, "compact-interval", c.CompactInterval.Duration, "how often to check whether the event log needs compacting down to what warm-up needs (0 disables; defaults to $COMPACT_INTERVAL)")
+	fs.IntVar(&c.CompactMinBytes, "compact-min-bytes", c.CompactMinBytes, "only compact the event log once it is at least this many bytes (defaults to $COMPACT_MIN_BYTES)")
+	fs.DurationVar(&c.EventMaxAge.Duration, "event-max-age", c.EventMaxAge.Duration, "when compacting, also drop events older than this unless they are still on the board (0 keeps them; defaults to $EVENT_MAX_AGE)")
+	fs.StringVar(&c.PublishWebhook, "publish-webhook", c.PublishWebhook, "POST batches of accepted submission outcomes to this URL (defaults to $PUBLISH_WEBHOOK)")
+	fs.StringVar(&c.PushGateway, "push-gateway", c.PushGateway, "Prometheus Pushgateway URL to push metrics to, for deployments that can't be scraped (defaults to $PUSH_GATEWAY)")
+	fs.StringVar(&c.PushJob, "push-job", c.PushJob, "job label for metrics pushed to -push-gateway")
+	fs.DurationVar(&c.PushInterval.Duration, "push-interval", c.PushInterval.Duration, "how often to push metrics to -push-gateway")
+	fs.StringVar(&c.AuditLog, "audit-log", c.AuditLog, "append-only audit log of admin actions (defaults to <data-dir>/audit.log)")
+	fs.DurationVar(&c.ShutdownTimeout.Duration, "shutdown-timeout", c.ShutdownTimeout.Duration, "how long shutdown waits for connections and the final flush before force-closing and exiting non-zero (defaults to $SHUTDOWN_TIMEOUT)")
+	fs.BoolVar(&c.DailyReset, "daily-reset", c.DailyReset, "clear the board every day at local midnight (defaults to $DAILY_RESET)")
+	fs.StringVar(&c.ResetTimezone, "reset-timezone", c.ResetTimezone, "IANA timezone whose midnight triggers the daily reset (defaults to $RESET_TIMEZONE, then UTC)")
+	fs.IntVar(&c.DailyKeep, "daily-keep", c.DailyKeep, "number of past daily boards archived under <data-dir>/daily")
+	fs.StringVar(&c.Follow, "follow", c.Follow, "base URL of a primary server to mirror read-only; submissions are redirected to it")
+	fs.StringVar(&c.FollowToken, "follow-token", c.FollowToken, "admin token of the -follow primary, used to pull its snapshot (defaults to $FOLLOW_TOKEN)")
+	fs.DurationVar(&c.FollowInterval.Duration, "follow-interval", c.FollowInterval.Duration, "how often a follower pulls the primary's snapshot")
+	fs.StringVar(&c.FollowerWrites, "follower-writes", c.FollowerWrites, "how a follower rejects writes: \"redirect\" answers 307 to the primary, \"error\" answers 421 with the primary's URL as primaryUrl in a JSON body")
+	fs.StringVar(&c.TLSCert, "tls-cert", c.TLSCert, "PEM certificate for serving HTTPS on the local -addr listener (requires -tls-key)")
+	fs.StringVar(&c.TLSKey, "tls-key", c.TLSKey, "PEM private key for -tls-cert")
+	fs.BoolVar(&c.HTTP2, "http2", c.HTTP2, "also serve HTTP/2, as h2c on plain and portal connections; -http2=false serves HTTP/1.1 only (defaults to $HTTP2)")
+	fs.Var((*stringList)(&c.TrustedProxies), "trusted-proxies", "comma-separated CIDRs of reverse proxies whose X-Forwarded-For is trusted (defaults to $TRUSTED_PROXIES)")
+	fs.Var((*stringList)(&c.AllowedOrigins), "allowed-origins", "comma-separated origins, or *, allowed to open WebSocket connections; empty allows same-host only (defaults to $ALLOWED_ORIGINS)")
+	fs.Var((*stringList)(&c.ExemptNames), "exempt-names", "comma-separated player names that skip the rate limits once claimed, sending X-Player-Name and X-Claim-Token (defaults to $EXEMPT_NAMES)")
+	fs.StringVar(&c.WebDir, "webdir", c.WebDir, "directory of static files to serve")
+	fs.StringVar(&c.Index, "index", c.Index, "file served for directory requests")
+	fs.BoolVar(&c.StrictWebDir, "strict-webdir", c.StrictWebDir, "exit at startup when -webdir has no -index file instead of only warning")
+	fs.BoolVar(&c.DevMode, "dev", c.DevMode, "development mode: indent all JSON responses (defaults to $DEV_MODE)")
+}
+
+// Validate reports every setting that is out of range or malformed
+func (c Config) Validate() error {
+	var errs []error
+	if len(c.Relays) == 0 && c.Addr == "" {
+		errs = append(errs, errors.New("at least one relay or a local addr is required"))
+	}
+	if c.ListenRetries < 0 {
+		errs = append(errs, errors.New("listenRetries must not be negative"))
+	}
+	if c.ListenBackoff.Duration < 0 {
+		errs = append(errs, errors.New("listenBackoff must not be negative"))
+	}
+	if c.PollInterval.Duration < 0 {
+		errs = append(errs, errors.New("pollInterval must not be negative"))
+	}
+	if c.MaxSubmitRate < 0 {
+		errs = append(errs, errors.New("maxSubmitRate must not be negative"))
+	}
+	if c.HistorySize < 0 {
+		errs = append(errs, errors.New("historySize must not be negative"))
+	}
+	if c.QueueSize < 0 {
+		errs = append(errs, errors.New("submitQueue must not be negative"))
+	}
+	if c.MaxScoreRate <= 0 {
+		errs = append(errs, errors.New("maxScoreRate must be positive"))
+	}
+	if c.JumpFactor != 0 && !(c.JumpFactor > 1) {
+		errs = append(errs, errors.New("jumpFactor must be 0 or greater than 1"))
+	}
+	if c.JumpMinHistory < 1 {
+		errs = append(errs, errors.New("jumpMinHistory must be at least 1"))
+	}
+	if c.ScoreDecimals < 0 || c.ScoreDecimals > maxScoreDecimals {
+		errs = append(errs, fmt.Errorf("scoreDecimals must be between 0 and %d", maxScoreDecimals))
+	}
+	if _, err := lookupScoreFormula(c.ScoreFormula); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ReplayMaxFrames < 1 {
+		errs = append(errs, errors.New("replayMaxFrames must be at least 1"))
+	}
+	if c.ReplayMaxTaps < 1 {
+		errs = append(errs, errors.New("replayMaxTaps must be at least 1"))
+	}
+	if c.SelfCheckInterval.Duration < 0 {
+		errs = append(errs, errors.New("selfCheckInterval must not be negative"))
+	}
+	if c.BackupInterval.Duration < 0 {
+		errs = append(errs, errors.New("backupInterval must not be negative"))
+	}
+	if c.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("shutdownTimeout must be positive"))
+	}
+	if c.BackupInterval.Duration > 0 && c.BackupKeep < 1 {
+		errs = append(errs, errors.New("backupKeep must be at least 1 when backups are enabled"))
+	}
+	if c.DailyReset && c.DailyKeep < 1 {
+		errs = append(errs, errors.New("dailyKeep must be at least 1 when the daily reset is enabled"))
+	}
+	for _, m := range c.Milestones {
+		if m <= 0 || math.IsInf(m, 0) || math.IsNaN(m) {
+			errs = append(errs, fmt.Errorf("milestone %v must be a positive number", m))
+		}
+	}
+	if c.LeaderboardSize < 1 || c.LeaderboardSize > maxBoardSize {
+		errs = append(errs, fmt.Errorf("leaderboardSize must be between 1 and %d", maxBoardSize))
+	}
+	if !modePattern.MatchString(c.BoardLabel) {
+		errs = append(errs, fmt.Errorf("boardLabel %q must be 1-32 lowercase letters, digits, '-' or '_'", c.BoardLabel))
+	}
+	if c.WarmUp && c.EventLog == "" {
+		errs = append(errs, errors.New("warmUp needs an eventLog to preload from"))
+	}
+	if c.CompactInterval.Duration < 0 {
+		errs = append(errs, errors.New("compactInterval must not be negative"))
+	}
+	if c.CompactInterval.Duration > 0 && c.EventLog == "" {
+		errs = append(errs, errors.New("compactInterval needs an eventLog to compact"))
+	}
+	if c.CompactMinBytes < 0 {
+		errs = append(errs, errors.New("compactMinBytes must not be negative"))
+	}
+	if c.EventMaxAge.Duration < 0 {
+		errs = append(errs, errors.New("eventMaxAge must not be negative"))
+	}
+	if c.MaxResponseEntries < 1 {
+		errs = append(errs, errors.New("maxResponseEntries must be at least 1"))
+	}
+	if c.LiveTTL.Duration < 0 {
+		errs = append(errs, errors.New("liveTTL must not be negative"))
+	}
+	if c.ScoreDecay.Duration < 0 {
+		errs = append(errs, errors.New("scoreDecay must not be negative"))
+	}
+	if c.BoardCacheTTL.Duration < 0 {
+		errs = append(errs, errors.New("boardCacheTTL must not be negative"))
+	}
+	if c.BoardCacheTTL.Duration > 0 && c.BoardCacheSize < 1 {
+		errs = append(errs, errors.New("boardCacheSize must be at least 1 when the board cache is enabled"))
+	}
+	if c.RecordFeedSize < 1 {
+		errs = append(errs, errors.New("recordFeedSize must be at least 1"))
+	}
+	if c.DataDir == "" {
+		errs = append(errs, errors.New("dataDir is required"))
+	}
+	if c.Follow != "" {
+		if _, err := parseFollowURL(c.Follow); err != nil {
+			errs = append(errs, err)
+		}
+		if c.FollowInterval.Duration <= 0 {
+			errs = append(errs, errors.New("followInterval must be positive"))
+		}
+		if c.FollowerWrites != followerWritesRedirect && c.FollowerWrites != followerWritesError {
+			errs = append(errs, fmt.Errorf("followerWrites must be %q or %q", followerWritesRedirect, followerWritesError))
+		}
+	}
+	if c.PushGateway != "" {
+		if u, err := url.Parse(c.PushGateway); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid pushGateway %q: must be an absolute http or https URL", c.PushGateway))
+		}
+		if c.PushJob == "" {
+			errs = append(errs, errors.New("pushJob is required when pushing metrics"))
+		}
+		if c.PushInterval.Duration <= 0 {
+			errs = append(errs, errors.New("pushInterval must be positive"))
+		}
+	}
+	if c.PublishWebhook != "" {
+		if u, err := url.Parse(c.PublishWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid publishWebhook %q: must be an absolute http or https URL", c.PublishWebhook))
+		}
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, errors.New("tlsCert and tlsKey must be set together"))
+	}
+	if _, err := newRateLimiter(c.RateLimits, time.Now); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := newSignatureVerifier(c.SignatureAlgs, c.SigningSecret, c.SigningPublicKey); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseTrustedProxies(c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseAllowedOrigins(c.AllowedOrigins); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseSubmissionWindow(c.EventStart, c.EventEnd); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := compileNamePolicy(c.NamePattern); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Duration is a time.Duration written in config files as a string such as
+// "30s" or "1h"
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// stringList is a flag.Value holding a comma-separated list
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = nil
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// floatList is a flag.Value holding a comma-separated list of numbers
+type floatList []float64
+
+func (l *floatList) String() string {
+	items := make([]string, len(*l))
+	for i, v := range *l {
+		items[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(items, ",")
+}
+
+func (l *floatList) Set(v string) error {
+	var out []float64
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", item)
+		}
+		out = append(out, f)
+	}
+	*l = out
+	return nil
+}
+
+// envString returns the environment variable key, or def when it is unset
+// or empty
+func envString(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt returns the integer value of the environment variable key, or def
+// when it is unset or malformed
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("ignoring malformed environment variable", "key", key, "value", v, "err", err)
+		return def
+	}
+	return n
+}
+
+// envBool returns the boolean value of the environment variable key, or def
+// when it is unset or malformed
+func envBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		slog.Warn("ignoring malformed environment variable", "key", key, "value", v, "err", err)
+		return def
+	}
+	return b
+}
+
+// envDuration returns the duration value of the environment variable key,
+// or def when it is unset or malformed
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		slog.Warn("ignoring malformed environment variable", "key", key, "value", v, "err", err)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkableConfig is the defaults made to pass -check-config on this
+// machine, with every path under dir
+func checkableConfig(t *testing.T, dir string) Config {
+	t.Helper()
+	web := filepath.Join(dir, "web")
+	if err := os.MkdirAll(web, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(web, "index.html"), []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := defaultConfig()
+	c.WebDir = web
+	c.DataDir = filepath.Join(dir, "data")
+	c.BackupDir = filepath.Join(dir, "backups")
+	c.AdminToken = "0123456789abcdef"
+	return c
+}
+
+func TestCheckConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config, dir string)
+		code   int
+		// want are lines expected in the output
+		want []string
+	}{
+		{name: "valid", change: func(*Config, string) {}, code: 0, want: []string{"configuration OK"}},
+		{
+			name:   "warnings alone pass",
+			change: func(c *Config, _ string) { c.AdminToken = "" },
+			code:   0,
+			want:   []string{"warning: adminToken is empty", "configuration OK"},
+		},
+		{
+			name:   "bad relay",
+			change: func(c *Config, _ string) { c.Relays = []string{"https://portal.example/relay"} },
+			code:   1,
+			want:   []string{`error: invalid relay "https://portal.example/relay"`, "1 problem(s) found"},
+		},
+		{
+			name:   "unknown timezone",
+			change: func(c *Config, _ string) { c.ResetTimezone = "Mars/Olympus" },
+			code:   1,
+			want:   []string{`error: invalid resetTimezone "Mars/Olympus"`},
+		},
+		{
+			name:   "out of range limits",
+			change: func(c *Config, _ string) { c.LeaderboardSize, c.HistorySize = 0, -1 },
+			code:   1,
+			want:   []string{"error: historySize must not be negative", "error: leaderboardSize must be between", "2 problem(s) found"},
+		},
+		{
+			name: "data dir is a file",
+			change: func(c *Config, dir string) {
+				c.DataDir = filepath.Join(dir, "data.txt")
+				os.WriteFile(c.DataDir, nil, 0o644)
+			},
+			code: 1,
+			want: []string{"error: dataDir:", "is not a directory"},
+		},
+		{
+			name: "missing web index in strict mode",
+			change: func(c *Config, dir string) {
+				c.WebDir, c.StrictWebDir = filepath.Join(dir, "empty"), true
+			},
+			code: 1,
+			want: []string{"error: web directory"},
+		},
+		{
+			name:   "missing web index otherwise warns",
+			change: func(c *Config, dir string) { c.WebDir = filepath.Join(dir, "empty") },
+			code:   0,
+			want:   []string{"warning: web directory"},
+		},
+		{
+			name: "every problem listed",
+			change: func(c *Config, _ string) {
+				c.ResetTimezone, c.ListenRetries, c.ShutdownTimeout = "Nowhere/Town", -1, Duration{-time.Second}
+			},
+			code: 1,
+			want: []string{"Nowhere/Town", "listenRetries", "3 problem(s) found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := checkableConfig(t, dir)
+			tt.change(&c, dir)
+
+			var out strings.Builder
+			if code := checkConfig(&out, c); code != tt.code {
+				t.Errorf("exit code = %d, want %d:\n%s", code, tt.code, out.String())
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output lacks %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"leaderbordSize": 20}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "leaderbordSize") {
+		t.Errorf("LoadConfig = %v, want the misspelt field reported", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// loadResetLocation resolves the IANA zone name used for the daily reset,
+// falling back to UTC with a warning when it is empty or unknown
+func loadResetLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("invalid reset timezone, using UTC", "timezone", name, "err", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// nextReset returns the first local midnight in loc strictly after t
+func nextReset(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+}
+
+// Reset clears the board and returns the entries it held, so they can be
+// archived without racing new submissions; submission history is kept
+func (lb *Leaderboard) Reset() []Score {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	final := lb.entries
+	lb.entries = make([]Score, 0)
+	lb.boardSince = lb.now()
+	lb.recordChange(BoardReset)
+	return final
+}
+
+// runDailyReset clears the board at every local midnight in loc until ctx
+// is cancelled
+func (s *Server) runDailyReset(ctx context.Context, loc *time.Location) {
+	for {
+		at := nextReset(s.now(), loc)
+		slog.Info("next leaderboard reset scheduled", "at", at)
+
+		timer := time.NewTimer(at.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		final := s.lb.Reset()
+		slog.Info("leaderboard reset", "timezone", loc.String())
+
+		if s.dailyDir != "" {
+			day := at.AddDate(0, 0, -1)
+			if err := s.archiveDay(day, final); err != nil {
+				slog.Error("failed to archive daily board", "day", day.Format(dailyDateLayout), "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestNextReset(t *testing.T) {
+	seoul := loadResetLocation("Asia/Seoul")
+	newYork := loadResetLocation("America/New_York")
+	tests := []struct {
+		name string
+		loc  *time.Location
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "before KST midnight",
+			loc:  seoul,
+			now:  time.Date(2024, 5, 1, 14, 59, 59, 0, time.UTC),
+			want: time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "at KST midnight",
+			loc:  seoul,
+			now:  time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 5, 2, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "after KST midnight, still the UTC day before",
+			loc:  seoul,
+			now:  time.Date(2024, 5, 1, 15, 0, 1, 0, time.UTC),
+			want: time.Date(2024, 5, 2, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "UTC",
+			loc:  time.UTC,
+			now:  time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "into a DST change",
+			loc:  newYork,
+			now:  time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "the day after a DST change",
+			loc:  newYork,
+			now:  time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 11, 4, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextReset(tt.now, tt.loc); !got.Equal(tt.want) {
+				t.Errorf("nextReset(%v) = %v, want %v", tt.now, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadResetLocation(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "", want: "UTC"},
+		{name: "Asia/Seoul", want: "Asia/Seoul"},
+		{name: "Mars/Olympus_Mons", want: "UTC"},
+	}
+	for _, tt := range tests {
+		if got := loadResetLocation(tt.name).String(); got != tt.want {
+			t.Errorf("loadResetLocation(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// DecayedScore is a board entry with the value it ranks by once decay has
+// worn it down; Score keeps the raw score as submitted
+type DecayedScore struct {
+	Score
+	EffectiveScore float64 `json:"effectiveScore"`
+}
+
+// decayedScore returns e's score lowered linearly to nothing over decay
+// after it was submitted
+func decayedScore(e Score, decay time.Duration, now time.Time) float64 {
+	age := now.Sub(e.Timestamp)
+	switch {
+	case age <= 0:
+		return e.Score
+	case age >= decay:
+		return 0
+	}
+	return e.Score * (1 - float64(age)/float64(decay))
+}
+
+// rankValue returns what the board is ranked by at now: raw scores, or
+// decayed ones when decay is on
+func (lb *Leaderboard) rankValue(now time.Time) func(Score) float64 {
+	if lb.decay == 0 {
+		return func(e Score) float64 { return e.Score }
+	}
+	return func(e Score) float64 { return decayedScore(e, lb.decay, now) }
+}
+
+// resort puts entries back in ranking order as of now after they were
+// replaced by ones sorted some other time. It only has work to do with
+// decay on. lb.mu must be held for writing.
+func (lb *Leaderboard) resort() {
+	if lb.decay == 0 {
+		return
+	}
+	lb.rankedAt = lb.now()
+	value := lb.rankValue(lb.rankedAt)
+	slices.SortStableFunc(lb.entries, func(a, b Score) int { return cmp.Compare(value(b), value(a)) })
+}
+
+// ranked returns the board in ranking order. Decay reorders entries as
+// they age without any write, so with it on they are re-sorted on every
+// read. lb.mu must be held.
+func (lb *Leaderboard) ranked() []Score {
+	if lb.decay == 0 {
+		return lb.entries
+	}
+	value := lb.rankValue(lb.now())
+	ranked := slices.Clone(lb.entries)
+	// Stable, so equal values keep the order they were placed in
+	slices.SortStableFunc(ranked, func(a, b Score) int { return cmp.Compare(value(b), value(a)) })
+	return ranked
+}
+
+// Decayed pairs each of scores with its effective score, or returns false
+// when decay is off
+func (lb *Leaderboard) Decayed(scores []Score) ([]DecayedScore, bool) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	if lb.decay == 0 {
+		return nil, false
+	}
+	value := lb.rankValue(lb.now())
+	decayed := make([]DecayedScore, len(scores))
+	for i, e := range scores {
+		decayed[i] = DecayedScore{Score: e, EffectiveScore: value(e)}
+	}
+	return decayed, true
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDecayedScore(t *testing.T) {
+	submitted := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	decay := 10 * 24 * time.Hour
+	tests := []struct {
+		name string
+		age  time.Duration
+		want float64
+	}{
+		{name: "fresh", age: 0, want: 80},
+		{name: "from the future", age: -time.Hour, want: 80},
+		{name: "a quarter through", age: decay / 4, want: 60},
+		{name: "half through", age: decay / 2, want: 40},
+		{name: "fully decayed", age: decay, want: 0},
+		{name: "past decay", age: 2 * decay, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Score{Name: "ann", Score: 80, Timestamp: submitted}
+			if got := decayedScore(e, decay, submitted.Add(tt.age)); got != tt.want {
+				t.Errorf("decayedScore after %v = %v, want %v", tt.age, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecayReordersBoard(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		name  string
+		decay time.Duration
+		// want is the board order, best first, read the given time after
+		// ann's old high score was submitted
+		want map[time.Duration][]string
+		// effective is each entry's effectiveScore on the last read, or
+		// nil if the board carries none
+		effective []float64
+	}{
+		{
+			name:  "old high score falls below a recent lower one",
+			decay: 10 * day,
+			want: map[time.Duration][]string{
+				5 * day:  {"ann", "bob"},
+				7 * day:  {"bob", "ann"},
+				11 * day: {"bob", "ann"},
+			},
+			effective: []float64{16, 0},
+		},
+		{
+			name: "decay off ranks by raw score",
+			want: map[time.Duration][]string{
+				5 * day:  {"ann", "bob"},
+				11 * day: {"ann", "bob"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			now := start
+			clock := func() time.Time { return now }
+			lb := NewLeaderboard()
+			lb.decay = tt.decay
+			h := testHandler(NewServer(WithLeaderboard(lb), WithClock(clock)))
+			for _, sub := range []struct {
+				at    time.Duration
+				name  string
+				score float64
+			}{{0, "ann", 100}, {5 * day, "bob", 40}} {
+				now = start.Add(sub.at)
+				if rec := do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":%q,"score":%v}`, sub.name, sub.score)); rec.Code != http.StatusCreated {
+					t.Fatalf("submit %s: status %d", sub.name, rec.Code)
+				}
+			}
+
+			var last []struct {
+				Name           string   `json:"name"`
+				Score          float64  `json:"score"`
+				EffectiveScore *float64 `json:"effectiveScore"`
+			}
+			for _, at := range []time.Duration{5 * day, 7 * day, 11 * day} {
+				want, ok := tt.want[at]
+				if !ok {
+					continue
+				}
+				now = start.Add(at)
+				if err := json.Unmarshal(do(h, http.MethodGet, "/api/leaderboard", "").Body.Bytes(), &last); err != nil {
+					t.Fatal(err)
+				}
+				got := []string{}
+				for _, e := range last {
+					got = append(got, e.Name)
+				}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("board after %v = %v, want %v", at, got, want)
+				}
+				for i, name := range want {
+					var resp struct {
+						Rank int `json:"rank"`
+					}
+					if err := json.Unmarshal(do(h, http.MethodGet, "/api/rank/"+name, "").Body.Bytes(), &resp); err != nil {
+						t.Fatal(err)
+					}
+					if resp.Rank != i+1 {
+						t.Errorf("%s ranked %d after %v, want %d", name, resp.Rank, at, i+1)
+					}
+				}
+			}
+
+			for i, e := range last {
+				// The raw score is reported as submitted, whatever it ranks by
+				if raw := map[string]float64{"ann": 100, "bob": 40}[e.Name]; e.Score != raw {
+					t.Errorf("%s has score %v, want the raw %v", e.Name, e.Score, raw)
+				}
+				switch {
+				case tt.effective == nil && e.EffectiveScore != nil:
+					t.Errorf("%s has effectiveScore %v with decay off", e.Name, *e.EffectiveScore)
+				case tt.effective != nil && e.EffectiveScore == nil:
+					t.Errorf("%s has no effectiveScore, want %v", e.Name, tt.effective[i])
+				case tt.effective != nil && math.Abs(*e.EffectiveScore-tt.effective[i]) > 1e-9:
+					t.Errorf("%s has effectiveScore %v, want %v", e.Name, *e.EffectiveScore, tt.effective[i])
+				}
+			}
+		})
+	}
+}
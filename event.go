@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SubmissionWindow restricts when scores may be submitted. A zero Start or
+// End leaves that side of the window open.
+type SubmissionWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// parseSubmissionWindow parses RFC3339 start and end times, either of which
+// may be empty
+func parseSubmissionWindow(start, end string) (SubmissionWindow, error) {
+	var sw SubmissionWindow
+	var err error
+
+	if start != "" {
+		if sw.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return sw, fmt.Errorf("invalid event start: %w", err)
+		}
+		sw.Start = sw.Start.UTC()
+	}
+	if end != "" {
+		if sw.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return sw, fmt.Errorf("invalid event end: %w", err)
+		}
+		sw.End = sw.End.UTC()
+	}
+	if !sw.Start.IsZero() && !sw.End.IsZero() && !sw.End.After(sw.Start) {
+		return sw, fmt.Errorf("event end %s must be after start %s", end, start)
+	}
+	return sw, nil
+}
+
+// Open reports whether submissions are accepted at t
+func (sw SubmissionWindow) Open(t time.Time) bool {
+	t = t.UTC()
+	if !sw.Start.IsZero() && t.Before(sw.Start) {
+		return false
+	}
+	if !sw.End.IsZero() && !t.Before(sw.End) {
+		return false
+	}
+	return true
+}
+
+// closedError is the rejection sent for submissions outside the window,
+// carrying the window times
+func (sw SubmissionWindow) closedError() *submitError {
+	body := struct {
+		Error string     `json:"error"`
+		Start *time.Time `json:"start,omitempty"`
+		End   *time.Time `json:"end,omitempty"`
+	}{Error: "Submissions are closed outside the event window"}
+	if !sw.Start.IsZero() {
+		body.Start = &sw.Start
+	}
+	if !sw.End.IsZero() {
+		body.End = &sw.End
+	}
+	return &submitError{Status: http.StatusForbidden, Message: body.Error, Body: body}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSubmissionWindowOpen(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	tests := []struct {
+		name   string
+		window SubmissionWindow
+		at     time.Time
+		want   bool
+	}{
+		{name: "before start", window: SubmissionWindow{Start: start, End: end}, at: start.Add(-time.Second), want: false},
+		{name: "exactly at start", window: SubmissionWindow{Start: start, End: end}, at: start, want: true},
+		{name: "inside", window: SubmissionWindow{Start: start, End: end}, at: start.Add(time.Hour), want: true},
+		{name: "exactly at end", window: SubmissionWindow{Start: start, End: end}, at: end, want: false},
+		{name: "after end", window: SubmissionWindow{Start: start, End: end}, at: end.Add(time.Second), want: false},
+		{name: "in another zone", window: SubmissionWindow{Start: start, End: end}, at: start.In(time.FixedZone("UTC+9", 9*60*60)), want: true},
+		{name: "open start", window: SubmissionWindow{End: end}, at: start.Add(-24 * time.Hour), want: true},
+		{name: "open start after end", window: SubmissionWindow{End: end}, at: end, want: false},
+		{name: "open end", window: SubmissionWindow{Start: start}, at: end.Add(24 * time.Hour), want: true},
+		{name: "open end before start", window: SubmissionWindow{Start: start}, at: start.Add(-time.Second), want: false},
+		{name: "unrestricted", at: start, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Open(tt.at); got != tt.want {
+				t.Errorf("Open(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSubmissionWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		want       SubmissionWindow
+		bad        bool
+	}{
+		{name: "both sides", start: "2024-05-01T12:00:00Z", end: "2024-05-01T14:00:00Z", want: SubmissionWindow{Start: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), End: time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)}},
+		{name: "converted to UTC", start: "2024-05-01T21:00:00+09:00", want: SubmissionWindow{Start: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}},
+		{name: "neither side"},
+		{name: "end equal to start", start: "2024-05-01T12:00:00Z", end: "2024-05-01T12:00:00Z", bad: true},
+		{name: "end before start", start: "2024-05-01T12:00:00Z", end: "2024-05-01T11:00:00Z", bad: true},
+		{name: "malformed start", start: "2024-05-01 12:00", bad: true},
+		{name: "malformed end", end: "tomorrow", bad: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSubmissionWindow(tt.start, tt.end)
+			if tt.bad {
+				if err == nil {
+					t.Errorf("parseSubmissionWindow(%q, %q) = %+v, want an error", tt.start, tt.end, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseSubmissionWindow(%q, %q) = %+v, %v; want %+v", tt.start, tt.end, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitOutsideWindow(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	tests := []struct {
+		name   string
+		at     time.Time
+		status int
+	}{
+		{name: "before", at: start.Add(-time.Minute), status: http.StatusForbidden},
+		{name: "during", at: start.Add(time.Hour), status: http.StatusCreated},
+		{name: "after", at: end, status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := func() time.Time { return tt.at }
+			h := testHandler(NewServer(WithClock(clock), WithSubmissionWindow(SubmissionWindow{Start: start, End: end})))
+
+			rec := do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":10}`)
+			if rec.Code != tt.status {
+				t.Fatalf("submit: status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status == http.StatusForbidden {
+				var body struct {
+					Start *time.Time `json:"start"`
+					End   *time.Time `json:"end"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Start == nil || !body.Start.Equal(start) || body.End == nil || !body.End.Equal(end) {
+					t.Errorf("rejection window = %v to %v, want %v to %v", body.Start, body.End, start, end)
+				}
+			}
+
+			// Reads stay open whatever the window
+			for _, target := range []string{"/api/leaderboard", "/api/stats"} {
+				if rec := do(h, http.MethodGet, target, ""); rec.Code != http.StatusOK {
+					t.Errorf("GET %s: status %d, want %d", target, rec.Code, http.StatusOK)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Types of admin change in the event log
+const (
+	eventRename = "rename"
+	eventPurge  = "purge"
+	// eventSnapshot heads a compacted log; see replayState
+	eventSnapshot = "snapshot"
+)
+
+// SubmissionEvent is a single accepted submission as recorded in the event
+// log. Admin changes that warm-up has to replay are logged alongside with
+// a Type; submissions have none.
+type SubmissionEvent struct {
+	Type      string          `json:"type,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	RequestID string          `json:"requestId,omitempty"`
+	Name      string          `json:"name"`
+	Score     float64         `json:"score"`
+	Mode      string          `json:"mode"`
+	Meta      json.RawMessage `json:"meta,omitempty"`
+	// To is the new name of a rename, whose Name is the old one
+	To string `json:"to,omitempty"`
+	// MinScore and MaxScore bound the scores a purge removed
+	MinScore *float64 `json:"minScore,omitempty"`
+	MaxScore *float64 `json:"maxScore,omitempty"`
+	// State is what a snapshot carries
+	State *replayState `json:"state,omitempty"`
+}
+
+// valid reports whether ev is an event warm-up knows how to replay
+func (ev SubmissionEvent) valid() bool {
+	switch ev.Type {
+	case "":
+		return ev.Name != ""
+	case eventRename:
+		return ev.Name != "" && ev.To != ""
+	case eventPurge:
+		return ev.MinScore != nil && ev.MaxScore != nil
+	case eventSnapshot:
+		return ev.State != nil
+	}
+	return false
+}
+
+// EventLog appends submission events to a file as newline-delimited JSON.
+// A nil *EventLog discards all events.
+type EventLog struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	enc  *json.Encoder
+}
+
+// OpenEventLog opens (or creates) the event log at path for appending
+func OpenEventLog(path string) (*EventLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLog{path: path, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Record appends ev to the log
+func (el *EventLog) Record(ev SubmissionEvent) error {
+	if el == nil {
+		return nil
+	}
+
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	return el.enc.Encode(ev)
+}
+
+// Close closes the underlying file
+func (el *EventLog) Close() error {
+	if el == nil {
+		return nil
+	}
+	return el.f.Close()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// rateLimitExemptions names trusted players, such as tournament organizers,
+// whose requests skip the per-IP rate limits
+type rateLimitExemptions struct {
+	mu    sync.Mutex
+	path  string
+	names map[string]bool
+}
+
+// loadExemptions reads the exemptions stored at path, if any, and adds
+// names from the configuration
+func loadExemptions(path string, names []string) (*rateLimitExemptions, error) {
+	var stored []string
+	if err := readJSONFile(path, &stored); err != nil {
+		return nil, err
+	}
+	ex := &rateLimitExemptions{path: path, names: make(map[string]bool, len(stored)+len(names))}
+	for _, name := range append(stored, names...) {
+		ex.names[name] = true
+	}
+	return ex, nil
+}
+
+// Exempt reports whether name is exempt
+func (ex *rateLimitExemptions) Exempt(name string) bool {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	return ex.names[name]
+}
+
+// List returns every exempt name, sorted
+func (ex *rateLimitExemptions) List() []string {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	return ex.sorted()
+}
+
+func (ex *rateLimitExemptions) sorted() []string {
+	names := make([]string, 0, len(ex.names))
+	for name := range ex.names {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Set adds or removes name, persisting the change. The change is undone if
+// it can't be saved.
+func (ex *rateLimitExemptions) Set(name string, exempt bool) error {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	was := ex.names[name]
+	if exempt {
+		ex.names[name] = true
+	} else {
+		delete(ex.names, name)
+	}
+	if ex.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(ex.path, ex.sorted()); err != nil {
+		if was {
+			ex.names[name] = true
+		} else {
+			delete(ex.names, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// WithExemptions sets which players bypass the per-IP rate limits
+func WithExemptions(ex *rateLimitExemptions) Option {
+	return func(s *Server) { s.exemptions = ex }
+}
+
+// rateLimitExempt reports whether r comes from an exempt player. Rate
+// limits apply before the body is read, so the player names themselves
+// in X-Player-Name and proves it with their claim token in X-Claim-Token;
+// an exempt name nobody has claimed can't be borrowed. The name is
+// canonicalized as a submission's would be, so padded or lookalike variants
+// of an exempt name are exempt too.
+func (s *Server) rateLimitExempt(r *http.Request) bool {
+	name, err := s.sanitizeName(r.Header.Get("X-Player-Name"))
+	if err != nil {
+		return false
+	}
+	name = s.lb.CanonicalName(name)
+	if !s.exemptions.Exempt(name) || !s.claims.Claimed(name) {
+		return false
+	}
+	return s.claims.Authorized(name, r.Header.Get("X-Claim-Token"))
+}
+
+// handleListExemptions handles GET /api/admin/exemptions
+func (s *Server) handleListExemptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, r, http.StatusOK, capEntries(s, w, s.exemptions.List()))
+}
+
+// handleSetExemption handles PUT and DELETE /api/admin/exemptions/:name
+func (s *Server) handleSetExemption(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	name, err := s.sanitizeName(ps.ByName("name"))
+	if err != nil {
+		http.Error(w, err.Error(), nameErrorStatus(err))
+		return
+	}
+	name = s.lb.CanonicalName(name)
+
+	exempt := r.Method == http.MethodPut
+	if !exempt && !s.exemptions.Exempt(name) {
+		http.Error(w, "Player is not exempt", http.StatusNotFound)
+		return
+	}
+	if err := s.exemptions.Set(name, exempt); err != nil {
+		loggerFrom(r.Context()).Error("failed to store rate limit exemptions", "name", name, "err", err)
+		http.Error(w, "Failed to update exemptions", http.StatusInternalServerError)
+		return
+	}
+
+	action := "exempt"
+	if !exempt {
+		action = "unexempt"
+	}
+	loggerFrom(r.Context()).Info("admin updated rate limit exemption", "name", name, "exempt", exempt)
+	s.auditLog(r, action, name, nil)
+
+	writeJSON(w, r, http.StatusOK, map[string]any{"name": name, "exempt": exempt, "claimed": s.claims.Claimed(name)})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitExempt(t *testing.T) {
+	tests := []struct {
+		name   string
+		player string
+		// token is the claim token to send; "valid" sends the real one
+		token     string
+		throttled bool
+	}{
+		{name: "exempt player", player: "ann", token: "valid"},
+		{name: "padded name", player: "  ann ", token: "valid"},
+		{name: "lookalike name", player: "аnn", token: "valid"},
+		{name: "wrong token", player: "ann", token: "guess", throttled: true},
+		{name: "no name", token: "valid", throttled: true},
+		{name: "normal player", player: "bob", token: "valid", throttled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.homoglyphs = true
+			lb.AddScore(t.Context(), "ann", 10)
+			limiter, err := newRateLimiter([]RateLimitRule{{Route: "POST /api/scores", Rate: 0.001, Burst: 1}}, time.Now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, _ := loadNameClaims("")
+			token, err := claims.Claim("ann")
+			if err != nil {
+				t.Fatal(err)
+			}
+			exemptions, _ := loadExemptions("", []string{"ann", "bob"})
+			s := NewServer(WithLeaderboard(lb), WithRateLimits(limiter), WithNameClaims(claims), WithExemptions(exemptions))
+			h := testHandler(s)
+
+			if tt.token == "valid" {
+				tt.token = token
+			}
+			statuses := make([]int, 3)
+			for i := range statuses {
+				req := httptest.NewRequest(http.MethodPost, "/api/scores", strings.NewReader(`{"name":"ann","score":1}`))
+				req.Header.Set("X-Player-Name", tt.player)
+				req.Header.Set("X-Claim-Token", tt.token)
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				statuses[i] = rec.Code
+			}
+			if got := statuses[2] == http.StatusTooManyRequests; got != tt.throttled {
+				t.Errorf("statuses %v, throttled = %v, want %v", statuses, got, tt.throttled)
+			}
+		})
+	}
+}
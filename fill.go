@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// BoardFill is how full the bounded board is. Once it is full, Cutoff is
+// the lowest score still on it, decayed if decay is on.
+type BoardFill struct {
+	Entries int
+	Size    int
+	Cutoff  float64
+	// Rising is whether the cutoff is higher than at the oldest version,
+	// still in the change log, on which the board was full
+	Rising bool
+}
+
+// Fill reports how full the board is, from state already kept for diffs
+func (lb *Leaderboard) Fill() BoardFill {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	fill := BoardFill{Entries: len(lb.entries), Size: lb.Size()}
+	if fill.Entries < fill.Size {
+		return fill
+	}
+	// With decay on, entries are only sorted as of their last change, so
+	// the lowest is looked for rather than taken from the end
+	value := lb.rankValue(lb.now())
+	fill.Cutoff = lowestValue(lb.entries, value)
+	for _, snap := range lb.changeLog {
+		if len(snap.entries) >= fill.Size {
+			fill.Rising = lowestValue(snap.entries[:fill.Size], value) < fill.Cutoff
+			break
+		}
+	}
+	return fill
+}
+
+// lowestValue returns the lowest value among entries, which must not be
+// empty
+func lowestValue(entries []Score, value func(Score) float64) float64 {
+	lowest := value(entries[0])
+	for _, e := range entries[1:] {
+		lowest = min(lowest, value(e))
+	}
+	return lowest
+}
+
+// header formats the fill for X-Board-Fill, e.g. "40%" or
+// "100%; cutoff=12; rising"
+func (f BoardFill) header() string {
+	v := fmt.Sprintf("%d%%", f.Entries*100/f.Size)
+	if f.Entries < f.Size {
+		return v
+	}
+	v += "; cutoff=" + strconv.FormatFloat(f.Cutoff, 'f', -1, 64)
+	if f.Rising {
+		v += "; rising"
+	}
+	return v
+}
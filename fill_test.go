@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBoardFillHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		want   string
+	}{
+		{name: "empty", want: "0%"},
+		{name: "one entry", scores: []float64{5}, want: "25%"},
+		{name: "nearly full", scores: []float64{5, 10, 20}, want: "75%"},
+		{name: "full", scores: []float64{5, 10, 20, 30}, want: "100%; cutoff=5"},
+		{name: "cutoff rising", scores: []float64{5, 10, 20, 30, 40}, want: "100%; cutoff=10; rising"},
+		{name: "low scores leave the cutoff", scores: []float64{5, 10, 20, 30, 1, 2}, want: "100%; cutoff=5"},
+		{name: "fractional cutoff", scores: []float64{5.5, 10, 20, 30}, want: "100%; cutoff=5.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size = 4
+			h := testHandler(NewServer(WithLeaderboard(lb), WithScoreDecimals(1)))
+			for i, score := range tt.scores {
+				if rec := do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":"p%d","score":%v}`, i, score)); rec.Code != http.StatusCreated {
+					t.Fatalf("submit %v: status %d", score, rec.Code)
+				}
+			}
+
+			rec := do(h, http.MethodGet, "/api/leaderboard", "")
+			if got := rec.Header().Get("X-Board-Fill"); got != tt.want {
+				t.Errorf("X-Board-Fill = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// followTimeout bounds a single snapshot pull from the primary
+const followTimeout = 10 * time.Second
+
+// How a follower answers writes meant for the primary
+const (
+	// followerWritesRedirect answers with a 307 to the primary
+	followerWritesRedirect = "redirect"
+	// followerWritesError answers with a 421 whose JSON body names the
+	// primary, for clients that shouldn't follow redirects blindly
+	followerWritesError = "error"
+)
+
+// LeaderboardSnapshot is the board as served by GET /api/admin/snapshot
+type LeaderboardSnapshot struct {
+	Version uint64    `json:"version"`
+	TakenAt time.Time `json:"takenAt"`
+	Entries []Score   `json:"entries"`
+}
+
+// Snapshot returns a copy of the current board along with its version
+func (lb *Leaderboard) Snapshot() LeaderboardSnapshot {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	return LeaderboardSnapshot{
+		Version: lb.version,
+		TakenAt: lb.now(),
+		Entries: slices.Clone(lb.entries),
+	}
+}
+
+// Replace swaps the board for entries, bumping the version only when they
+// differ from what is already shown
+func (lb *Leaderboard) Replace(entries []Score) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if slices.Equal(lb.entries, entries) {
+		return
+	}
+	lb.entries = slices.Clone(entries)
+	lb.resort()
+	lb.recordChange(BoardReplace)
+}
+
+// follower keeps a read-only copy of a primary server's board
+type follower struct {
+	primary  *url.URL
+	token    string
+	interval time.Duration
+	client   *http.Client
+
+	mu       sync.Mutex
+	lastSync time.Time
+	lastErr  error
+}
+
+// WithFollower makes the server a read-only replica of primary, pulling
+// its snapshot every interval using token for the primary's admin API
+func WithFollower(primary *url.URL, token string, interval time.Duration) Option {
+	return func(s *Server) {
+		s.follow = &follower{
+			primary:  primary,
+			token:    token,
+			interval: interval,
+			client:   &http.Client{Timeout: followTimeout},
+		}
+	}
+}
+
+// parseFollowURL checks that raw is an absolute http(s) URL
+func parseFollowURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid follow URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid follow URL %q: must be an absolute http or https URL", raw)
+	}
+	return u, nil
+}
+
+// primaryURL resolves path against the primary's base URL
+func (f *follower) primaryURL(path string) string {
+	return f.primary.JoinPath(path).String()
+}
+
+// pull fetches the primary's snapshot
+func (f *follower) pull(ctx context.Context) (LeaderboardSnapshot, error) {
+	var snap LeaderboardSnapshot
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.primaryURL("/api/admin/snapshot"), nil)
+	if err != nil {
+		return snap, err
+	}
+	req.Header.Set("Authorization", "Bearer "+f.token)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return snap, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return snap, fmt.Errorf("primary returned %s", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&snap)
+	return snap, err
+}
+
+// sync pulls once and applies the result to lb. On failure the last known
+// board keeps being served.
+func (f *follower) sync(ctx context.Context, lb *Leaderboard, t time.Time) {
+	snap, err := f.pull(ctx)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastErr = err
+	if err != nil {
+		slog.Warn("failed to pull snapshot from primary", "primary", f.primary.String(), "err", err)
+		return
+	}
+	lb.Replace(snap.Entries)
+	f.lastSync = t
+}
+
+// status reports when the board was last synced and the most recent error
+func (f *follower) status() (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lastSync, f.lastErr
+}
+
+// runFollower keeps the board in step with the primary until ctx is
+// cancelled
+func (s *Server) runFollower(ctx context.Context) {
+	ticker := time.NewTicker(s.follow.interval)
+	defer ticker.Stop()
+
+	for {
+		s.follow.sync(ctx, s.lb, s.now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WithFollowerWrites sets how a follower rejects writes: redirected to the
+// primary, or refused with an error body naming it
+func WithFollowerWrites(mode string) Option {
+	return func(s *Server) { s.followerWrites = mode }
+}
+
+// followerError rejects a write on a follower, pointing the client at the
+// same path on the primary
+func (s *Server) followerError(path string) *submitError {
+	target := s.follow.primaryURL(path)
+	if s.followerWrites == followerWritesError {
+		return &submitError{
+			Status:  http.StatusMisdirectedRequest,
+			Message: "This server is a read-only follower",
+			Body:    map[string]string{"error": "This server is a read-only follower", "primaryUrl": target},
+		}
+	}
+	return &submitError{
+		Status:   http.StatusTemporaryRedirect,
+		Message:  "This server is a read-only follower; send writes to " + target,
+		Location: target,
+	}
+}
+
+// primaryOnly redirects requests for h to the primary in follower mode
+func (s *Server) primaryOnly(h httprouter.Handle) httprouter.Handle {
+	if s.follow == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		s.followerError(r.URL.Path).write(w, r)
+	}
+}
+
+// handleSnapshot handles GET /api/admin/snapshot
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, r, http.StatusOK, s.lb.Snapshot())
+}
+
+// handleHealth handles GET /healthz. Followers report how stale their copy
+// of the board is; a stale follower still answers 200 since it keeps
+// serving the last known board.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if s.follow == nil {
+		writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok", "role": "primary"})
+		return
+	}
+
+	lastSync, err := s.follow.status()
+	resp := map[string]any{
+		"status":  "ok",
+		"role":    "follower",
+		"primary": s.follow.primary.String(),
+	}
+	if lastSync.IsZero() {
+		resp["status"] = "stale"
+	} else {
+		staleness := s.now().Sub(lastSync)
+		resp["lastSync"] = lastSync
+		resp["stalenessSeconds"] = staleness.Seconds()
+		if staleness > 3*s.follow.interval {
+			resp["status"] = "stale"
+		}
+	}
+	if err != nil {
+		resp["lastError"] = err.Error()
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFollowerWrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary string
+		mode    string
+		method  string
+		target  string
+		body    string
+		status  int
+		// want is the Location header, or primaryUrl in an error body
+		want string
+	}{
+		{
+			name:    "submission redirected",
+			primary: "https://primary.example",
+			method:  http.MethodPost,
+			target:  "/api/scores",
+			body:    `{"name":"ann","score":10}`,
+			status:  http.StatusTemporaryRedirect,
+			want:    "https://primary.example/api/scores",
+		},
+		{
+			name:    "primary under a base path",
+			primary: "https://example.com/flappy/",
+			method:  http.MethodPost,
+			target:  "/api/scores",
+			body:    `{"name":"ann","score":10}`,
+			status:  http.StatusTemporaryRedirect,
+			want:    "https://example.com/flappy/api/scores",
+		},
+		{
+			name:    "other writes redirected to their own path",
+			primary: "https://primary.example",
+			method:  http.MethodPost,
+			target:  "/api/report",
+			body:    `{"name":"ann","reason":"bot"}`,
+			status:  http.StatusTemporaryRedirect,
+			want:    "https://primary.example/api/report",
+		},
+		{
+			name:    "error body",
+			primary: "https://primary.example",
+			mode:    followerWritesError,
+			method:  http.MethodPost,
+			target:  "/api/scores",
+			body:    `{"name":"ann","score":10}`,
+			status:  http.StatusMisdirectedRequest,
+			want:    "https://primary.example/api/scores",
+		},
+		{
+			name:    "reads served locally",
+			primary: "https://primary.example",
+			method:  http.MethodGet,
+			target:  "/api/leaderboard",
+			status:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, err := parseFollowURL(tt.primary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := []Option{WithFollower(primary, "token", time.Minute)}
+			if tt.mode != "" {
+				opts = append(opts, WithFollowerWrites(tt.mode))
+			}
+			s := NewServer(opts...)
+
+			rec := do(testHandler(s), tt.method, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			got := rec.Header().Get("Location")
+			if tt.mode == followerWritesError {
+				var resp struct {
+					PrimaryURL string `json:"primaryUrl"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				got = resp.PrimaryURL
+			}
+			if got != tt.want {
+				t.Errorf("primary target = %q, want %q", got, tt.want)
+			}
+			if n := len(s.lb.GetTopScores()); n != 0 {
+				t.Errorf("follower recorded %d entries, want none", n)
+			}
+		})
+	}
+}
+
+func TestParseFollowURL(t *testing.T) {
+	for _, raw := range []string{"primary.example", "/api", "ftp://primary.example", "https://", "http://[::1"} {
+		if _, err := parseFollowURL(raw); err == nil {
+			t.Errorf("parseFollowURL(%q) accepted it", raw)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// boardFreeze holds the board shown publicly while the leaderboard is
+// frozen, and the stats as they stood then; submissions keep landing on
+// the live board underneath
+type boardFreeze struct {
+	mu       sync.RWMutex
+	frozen   bool
+	frozenAt time.Time
+	entries  []Score
+	version  uint64
+	stats    StatsResponse
+}
+
+// Freeze captures snap as the visible board, with stats, and reports false
+// if the board was already frozen
+func (f *boardFreeze) Freeze(snap LeaderboardSnapshot, stats StatsResponse) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.frozen {
+		return false
+	}
+	f.frozen = true
+	f.frozenAt = snap.TakenAt
+	f.entries = snap.Entries
+	f.version = snap.Version
+	f.stats = stats
+	return true
+}
+
+// Unfreeze reveals the live board again and reports false if it wasn't
+// frozen
+func (f *boardFreeze) Unfreeze() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.frozen {
+		return false
+	}
+	f.frozen = false
+	f.frozenAt = time.Time{}
+	f.entries = nil
+	f.version = 0
+	f.stats = StatsResponse{}
+	return true
+}
+
+// Frozen returns a copy of the frozen board, its version and when it was
+// frozen; ok is false when the board isn't frozen
+func (f *boardFreeze) Frozen() (entries []Score, version uint64, at time.Time, ok bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.frozen {
+		return nil, 0, time.Time{}, false
+	}
+	return append([]Score(nil), f.entries...), f.version, f.frozenAt, true
+}
+
+// FrozenAt returns when the board was frozen, or the zero time when it
+// isn't frozen
+func (f *boardFreeze) FrozenAt() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.frozenAt
+}
+
+// Stats returns the stats captured at freeze time; ok is false when the
+// board isn't frozen
+func (f *boardFreeze) Stats() (stats StatsResponse, ok bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.stats, f.frozen
+}
+
+// hiddenByFreeze reports whether something that happened at t is hidden by
+// a freeze at frozenAt: it happened after the freeze. The zero frozenAt
+// hides nothing.
+func hiddenByFreeze(t, frozenAt time.Time) bool {
+	return !frozenAt.IsZero() && t.After(frozenAt)
+}
+
+// publicBoard returns the board as the public sees it, best first, and the
+// value it is ranked by: the frozen snapshot while the board is frozen,
+// the live board otherwise
+func (s *Server) publicBoard() ([]Score, func(Score) float64) {
+	value := s.lb.rankValue(s.now())
+	if entries, _, _, ok := s.freeze.Frozen(); ok {
+		return entries, value
+	}
+	return s.lb.GetTopScores(), value
+}
+
+// handleFreeze handles POST /api/admin/freeze
+func (s *Server) handleFreeze(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// Stats are taken first, so a submission landing in between shows on
+	// the frozen board rather than only in its stats
+	stats := s.stats.Stats()
+	snap := s.lb.Snapshot()
+	if !s.freeze.Freeze(snap, stats) {
+		http.Error(w, "Leaderboard is already frozen", http.StatusConflict)
+		return
+	}
+
+	loggerFrom(r.Context()).Info("admin froze leaderboard", "version", snap.Version)
+	s.auditLog(r, "freeze", "", map[string]any{"version": snap.Version})
+	writeJSON(w, r, http.StatusOK, map[string]any{"status": "success", "frozenAt": snap.TakenAt.UTC(), "version": snap.Version})
+}
+
+// handleUnfreeze handles DELETE /api/admin/freeze
+func (s *Server) handleUnfreeze(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !s.freeze.Unfreeze() {
+		http.Error(w, "Leaderboard is not frozen", http.StatusConflict)
+		return
+	}
+
+	version := s.lb.Version()
+	loggerFrom(r.Context()).Info("admin unfroze leaderboard", "version", version)
+	s.auditLog(r, "unfreeze", "", map[string]any{"version": version})
+	writeJSON(w, r, http.StatusOK, map[string]any{"status": "success", "version": version})
+}
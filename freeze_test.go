@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFreezeHidesLaterScores(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	clock := func() time.Time { return now }
+	lb := NewLeaderboard()
+	lb.live = &liveBoard{ttl: time.Hour}
+	lb.milestones = &milestoneTracker{milestones: []Milestone{{Threshold: 10}, {Threshold: 40}}}
+	s := NewServer(WithLeaderboard(lb), WithClock(clock), WithAdminToken(testAdminToken))
+	h := testHandler(s)
+	submit := func(name string, score float64) {
+		t.Helper()
+		if rec := do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":%q,"score":%v}`, name, score)); rec.Code != http.StatusCreated {
+			t.Fatalf("submit %s: status %d", name, rec.Code)
+		}
+	}
+
+	submit("ann", 10)
+	submit("bob", 5)
+	now = now.Add(time.Minute)
+	if rec := doAdmin(h, http.MethodPost, "/api/admin/freeze", nil); rec.Code != http.StatusOK {
+		t.Fatalf("freeze: status %d: %s", rec.Code, rec.Body)
+	}
+
+	// Played after the freeze: bob's 50 would top the board, and ann's and
+	// cat's session results would rank them 3rd and 2nd
+	now = now.Add(time.Minute)
+	submit("bob", 50)
+	var game struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.Unmarshal(do(h, http.MethodGet, "/api/game/start", "").Body.Bytes(), &game); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	rec := do(h, http.MethodPost, "/api/game/"+game.SessionID+"/results", `{"results":[{"name":"ann","score":20},{"name":"cat","score":30}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("session results: status %d: %s", rec.Code, rec.Body)
+	}
+	var results struct {
+		Results []sessionResultStatus `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	ranks := map[string]int{}
+	for _, st := range results.Results {
+		ranks[st.Name] = st.Rank
+	}
+	// ann is ranked on the frozen board; cat isn't on it
+	if ranks["ann"] != 1 || ranks["cat"] != 0 {
+		t.Errorf("session result ranks = %v, want ann 1 and cat omitted", ranks)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		check  func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "player bests",
+			target: "/api/players/bob/bests",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var bests PlayerBests
+				if err := json.Unmarshal(rec.Body.Bytes(), &bests); err != nil {
+					t.Fatal(err)
+				}
+				if bests.AllTime == nil || bests.AllTime.Score != 5 || bests.Today == nil || bests.Today.Score != 5 {
+					t.Errorf("bests = %+v, want 5 both all time and today", bests)
+				}
+			},
+		},
+		{
+			name:   "player first seen after the freeze",
+			target: "/api/players/cat/bests",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				if rec.Code != http.StatusNotFound {
+					t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+				}
+			},
+		},
+		{
+			name:   "live board",
+			target: "/api/leaderboard/live",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var board []LiveScore
+				if err := json.Unmarshal(rec.Body.Bytes(), &board); err != nil {
+					t.Fatal(err)
+				}
+				if len(board) != 2 || board[0].Name != "ann" || board[1].Score.Score != 5 {
+					t.Errorf("live board = %+v, want ann 10 and bob 5", board)
+				}
+			},
+		},
+		{
+			name:   "stats",
+			target: "/api/stats",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var stats StatsResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+					t.Fatal(err)
+				}
+				if stats.Count != 2 || stats.Best != 10 {
+					t.Errorf("stats count %d, best %v; want 2, 10", stats.Count, stats.Best)
+				}
+			},
+		},
+		{
+			name:   "histogram",
+			target: "/api/leaderboard/histogram",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var hist Histogram
+				if err := json.Unmarshal(rec.Body.Bytes(), &hist); err != nil {
+					t.Fatal(err)
+				}
+				if hist.Total != 2 || hist.Max != 10 {
+					t.Errorf("histogram total %d, max %v; want 2, 10", hist.Total, hist.Max)
+				}
+			},
+		},
+		{
+			name:   "milestones",
+			target: "/api/milestones",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var milestones []Milestone
+				if err := json.Unmarshal(rec.Body.Bytes(), &milestones); err != nil {
+					t.Fatal(err)
+				}
+				if len(milestones) != 2 || milestones[0].Name != "ann" || milestones[1].ReachedAt != nil {
+					t.Errorf("milestones = %+v, want 10 won by ann and 40 open", milestones)
+				}
+			},
+		},
+		{
+			name:   "leaderboard envelope",
+			target: "/api/leaderboard?envelope=true",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				if fill := rec.Header().Get("X-Board-Fill"); fill != "" {
+					t.Errorf("X-Board-Fill = %q while frozen, want none", fill)
+				}
+				if body := rec.Body.String(); strings.Contains(body, "totalPlayers") {
+					t.Errorf("envelope %s has totalPlayers while frozen", body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, do(h, http.MethodGet, tt.target, ""))
+		})
+	}
+
+	// Unfreezing reveals what was played meanwhile
+	if rec := doAdmin(h, http.MethodDelete, "/api/admin/freeze", nil); rec.Code != http.StatusOK {
+		t.Fatalf("unfreeze: status %d: %s", rec.Code, rec.Body)
+	}
+	var bests PlayerBests
+	if err := json.Unmarshal(do(h, http.MethodGet, "/api/players/bob/bests", "").Body.Bytes(), &bests); err != nil {
+		t.Fatal(err)
+	}
+	if bests.AllTime == nil || bests.AllTime.Score != 50 {
+		t.Errorf("bests after unfreeze = %+v, want 50 all time", bests)
+	}
+	if rec := do(h, http.MethodGet, "/api/leaderboard?envelope=true", ""); rec.Header().Get("X-Board-Fill") == "" || !strings.Contains(rec.Body.String(), "totalPlayers") {
+		t.Errorf("unfrozen envelope lacks X-Board-Fill or totalPlayers: %s", rec.Body)
+	}
+}
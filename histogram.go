@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultHistogramBuckets = 10
+	maxHistogramBuckets     = 100
+)
+
+// HistogramBucket counts recorded scores in [From, To); the last bucket
+// also includes To
+type HistogramBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// Histogram is the distribution of every recorded score
+type Histogram struct {
+	Total   int               `json:"total"`
+	Min     float64           `json:"min"`
+	Max     float64           `json:"max"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+// bucketScores groups scores into n equal-width buckets between their
+// minimum and maximum. With no scores there are no buckets, and when every
+// score is equal they all land in a single bucket.
+func bucketScores(scores []float64, n int) Histogram {
+	h := Histogram{Total: len(scores), Buckets: []HistogramBucket{}}
+	if len(scores) == 0 {
+		return h
+	}
+
+	h.Min, h.Max = scores[0], scores[0]
+	for _, v := range scores[1:] {
+		h.Min = min(h.Min, v)
+		h.Max = max(h.Max, v)
+	}
+	if h.Min == h.Max {
+		h.Buckets = append(h.Buckets, HistogramBucket{From: h.Min, To: h.Max, Count: len(scores)})
+		return h
+	}
+
+	width := (h.Max - h.Min) / float64(n)
+	h.Buckets = make([]HistogramBucket, n)
+	for i := range h.Buckets {
+		h.Buckets[i].From = h.Min + float64(i)*width
+		h.Buckets[i].To = h.Min + float64(i+1)*width
+	}
+	h.Buckets[n-1].To = h.Max
+
+	for _, v := range scores {
+		i := min(int((v-h.Min)/width), n-1)
+		h.Buckets[i].Count++
+	}
+	return h
+}
+
+// Histogram buckets every recorded submission, not just the board, but
+// those hidden by a freeze at frozenAt
+func (lb *Leaderboard) Histogram(n int, frozenAt time.Time) Histogram {
+	lb.mu.RLock()
+	scores := make([]float64, 0, len(lb.history))
+	for _, entry := range lb.history {
+		if !hiddenByFreeze(entry.Timestamp, frozenAt) {
+			scores = append(scores, entry.Score)
+		}
+	}
+	lb.mu.RUnlock()
+
+	return bucketScores(scores, n)
+}
+
+// handleGetHistogram handles GET /api/leaderboard/histogram?buckets=N
+func (s *Server) handleGetHistogram(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	n, err := queryInt(r, "buckets", defaultHistogramBuckets, 1, maxHistogramBuckets)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s.lb.Histogram(n, s.freeze.FrozenAt()))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// confusables maps characters commonly used to impersonate other names to
+// the Latin letter they look like. It covers Cyrillic and Greek lookalikes
+// and the digit/letter pairs; fullwidth Latin is folded separately.
+// Skeletons are lowercased after mapping, so a lookalike such as '0' or
+// Cyrillic 'м' matches its letter in either case.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'B', 'е': 'e', 'к': 'k', 'м': 'M', 'н': 'H', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 'T', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i',
+	'ј': 'j', 'һ': 'h', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ӏ': 'l', 'ү': 'y',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O',
+	'Р': 'P', 'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X', 'Ѕ': 'S', 'І': 'l',
+	'Ј': 'J', 'Ү': 'Y', 'Ԛ': 'Q', 'Ԝ': 'W', 'Ӏ': 'l',
+	// Greek
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'l', 'Κ': 'K',
+	'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+	'ο': 'o', 'ν': 'v', 'ι': 'i', 'κ': 'k', 'ρ': 'p', 'υ': 'u', 'α': 'a',
+	// Latin and digits
+	'0': 'O', '1': 'l', 'I': 'l', 'ı': 'i',
+}
+
+// nameSkeleton maps name to the form it is compared under when homoglyphs
+// are collapsed, so names that look alike share a skeleton. Case is
+// folded, as names differing only in case are easily passed off as each
+// other too.
+func nameSkeleton(name string) string {
+	return strings.Map(func(r rune) rune {
+		// Fullwidth ASCII, e.g. 'Ａ', to its ASCII form
+		if r >= '！' && r <= '～' {
+			r = r - '！' + '!'
+		}
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// CanonicalName returns the name of the known player that name is a
+// lookalike of, or name itself. It always returns name unless homoglyph
+// collapsing is on.
+func (lb *Leaderboard) CanonicalName(name string) string {
+	if !lb.homoglyphs {
+		return name
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	// The index is built on first use and after renames, from every
+	// known player
+	if lb.nameKeys == nil {
+		lb.nameKeys = make(map[string]string)
+		for known := range lb.playerSet() {
+			lb.indexName(known)
+		}
+	}
+	if known, ok := lb.nameKeys[nameSkeleton(name)]; ok {
+		return known
+	}
+	return name
+}
+
+// indexName records name under its skeleton, keeping whichever name was
+// seen first. lb.mu must be held for writing.
+func (lb *Leaderboard) indexName(name string) {
+	if lb.nameKeys == nil {
+		return
+	}
+	key := nameSkeleton(name)
+	if _, ok := lb.nameKeys[key]; !ok {
+		lb.nameKeys[key] = name
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNameSkeleton(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"Ann", "Аnn", true},         // Cyrillic А
+		{"paypal", "раураl", true},   // Cyrillic р, а, у
+		{"Bob", "Βοb", true},         // Greek Β, ο
+		{"Ann", "Ａｎｎ", true},         // fullwidth
+		{"BILL", "B1LL", true},       // digit one for capital I
+		{"Otto", "0tto", true},       // digit zero for O
+		{"Kate", "Κаtе", true},       // Greek Κ, Cyrillic а, е
+		{"Ann", "Anne", false},       // a different name
+		{"ann", "Ann", true},         // case is folded
+		{"gopher", "g0pher", true},   // digit zero for lowercase o
+		{"momo", "мoмo", true},       // Cyrillic small-capital м
+		{"beth", "вeтн", true},       // Cyrillic в, т, н
+		{"Zoë", "Zoe", false},        // accents aren't lookalikes
+		{"СССР", "CCCP", true},       // all Cyrillic
+		{"player", "player ", false}, // sanitizeName trims, skeletons don't
+	}
+	for _, tt := range tests {
+		if got := nameSkeleton(tt.a) == nameSkeleton(tt.b); got != tt.same {
+			t.Errorf("nameSkeleton(%q) == nameSkeleton(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
+
+func TestCollapseHomoglyphs(t *testing.T) {
+	tests := []struct {
+		name     string
+		collapse bool
+		// want is the board's names after "Ann" and then "Аnn" submit
+		want []string
+	}{
+		{name: "off", collapse: false, want: []string{"Аnn", "Ann"}},
+		{name: "on", collapse: true, want: []string{"Ann"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.dedup, lb.homoglyphs = true, tt.collapse
+			h := testHandler(NewServer(WithLeaderboard(lb)))
+			do(h, http.MethodPost, "/api/scores", `{"name":"Ann","score":10}`)
+			do(h, http.MethodPost, "/api/scores", `{"name":"Аnn","score":20}`)
+
+			top := lb.GetTopScores()
+			if len(top) != len(tt.want) {
+				t.Fatalf("board = %+v, want names %q", top, tt.want)
+			}
+			for i, name := range tt.want {
+				if top[i].Name != name {
+					t.Errorf("entry %d is %q, want %q", i, top[i].Name, name)
+				}
+			}
+			if tt.collapse && top[0].Score != 20 {
+				t.Errorf("merged entry scored %v, want the lookalike's 20", top[0].Score)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// socketDrainTimeout bounds how long shutdown waits for WebSocket clients
+// to acknowledge the close frame
+const socketDrainTimeout = 3 * time.Second
+
+// socketHub tracks open WebSocket connections so shutdown can close them
+// cleanly; http.Server.Shutdown doesn't see hijacked connections
+type socketHub struct {
+	mu      sync.Mutex
+	conns   map[*websocket.Conn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}
+
+func newSocketHub() *socketHub {
+	return &socketHub{conns: make(map[*websocket.Conn]struct{})}
+}
+
+// Add registers conn and reports false if the hub is already shutting down
+func (h *socketHub) Add(conn *websocket.Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closing {
+		return false
+	}
+	h.conns[conn] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+// Remove forgets conn once its handler has finished with it
+func (h *socketHub) Remove(conn *websocket.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.conns[conn]; ok {
+		delete(h.conns, conn)
+		h.wg.Done()
+	}
+}
+
+// Shutdown sends a going-away close frame to every connection and waits
+// for their handlers to return. Connections still open when ctx is done
+// are closed outright.
+func (h *socketHub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	conns := make([]*websocket.Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+	if len(conns) == 0 {
+		return nil
+	}
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	deadline := time.Now().Add(time.Second)
+	for _, conn := range conns {
+		conn.WriteControl(websocket.CloseMessage, msg, deadline)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		h.mu.Lock()
+		for conn := range h.conns {
+			conn.Close()
+		}
+		h.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// idBlock is how many score IDs are reserved per write of the sequence
+// file; a restart skips whatever was left of the last block
+const idBlock = 1000
+
+// idSequence hands out increasing score IDs. With a path, the IDs handed
+// out so far are persisted a block at a time, so they keep increasing
+// across restarts without a disk write per submission.
+type idSequence struct {
+	mu       sync.Mutex
+	path     string
+	next     uint64
+	reserved uint64
+}
+
+// loadIDSequence resumes the sequence stored at path, if any
+func loadIDSequence(path string) (*idSequence, error) {
+	seq := &idSequence{path: path}
+	if err := readJSONFile(path, &seq.reserved); err != nil {
+		return nil, err
+	}
+	seq.next = seq.reserved
+	return seq, nil
+}
+
+// Next returns a new ID, starting at 1
+func (seq *idSequence) Next() uint64 {
+	seq.mu.Lock()
+	defer seq.mu.Unlock()
+
+	seq.next++
+	if seq.next > seq.reserved {
+		seq.reserved = seq.next + idBlock - 1
+		if seq.path != "" {
+			// IDs stay unique in memory even if this fails; only a restart
+			// could then reuse some
+			if err := writeJSONFile(seq.path, seq.reserved); err != nil {
+				slog.Error("failed to persist score ID sequence", "path", seq.path, "err", err)
+			}
+		}
+	}
+	return seq.next
+}
+
+// Skip moves the sequence past id, so IDs restored from elsewhere are
+// never handed out again
+func (seq *idSequence) Skip(id uint64) {
+	seq.mu.Lock()
+	defer seq.mu.Unlock()
+
+	if id <= seq.next {
+		return
+	}
+	seq.next = id
+	if seq.next > seq.reserved {
+		seq.reserved = seq.next
+		if seq.path != "" {
+			if err := writeJSONFile(seq.path, seq.reserved); err != nil {
+				slog.Error("failed to persist score ID sequence", "path", seq.path, "err", err)
+			}
+		}
+	}
+}
+
+// DeleteScore removes the submission with the given ID and re-ranks the
+// board, reporting whether it was found
+func (lb *Leaderboard) DeleteScore(id uint64) bool {
+	match := func(e Score) bool { return e.ID == id }
+
+	// The board can hold entries older than the retained history, which
+	// removeScores doesn't count
+	lb.mu.RLock()
+	found := slices.ContainsFunc(lb.history, match) || slices.ContainsFunc(lb.entries, match)
+	lb.mu.RUnlock()
+
+	if found {
+		lb.removeScores(match)
+	}
+	return found
+}
+
+// handleDeleteScore handles DELETE /api/admin/scores/:id
+func (s *Server) handleDeleteScore(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := strconv.ParseUint(ps.ByName("id"), 10, 64)
+	if err != nil || id == 0 {
+		http.Error(w, "Invalid score ID", http.StatusBadRequest)
+		return
+	}
+	if !s.lb.DeleteScore(id) {
+		http.Error(w, "Score not found", http.StatusNotFound)
+		return
+	}
+
+	loggerFrom(r.Context()).Info("admin deleted score", "id", id)
+	s.auditLog(r, "delete", strconv.FormatUint(id, 10), nil)
+	writeJSON(w, r, http.StatusOK, map[string]any{"status": "success", "id": id})
+}
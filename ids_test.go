@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestScoreIDsMonotonic(t *testing.T) {
+	lb := NewLeaderboard()
+	h := testHandler(NewServer(WithLeaderboard(lb)))
+	// Identical submissions are told apart only by their IDs
+	for range 5 {
+		if rec := do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":10}`); rec.Code != http.StatusCreated {
+			t.Fatalf("submit: status %d", rec.Code)
+		}
+	}
+
+	var prev uint64
+	for i, e := range lb.History() {
+		if e.ID <= prev {
+			t.Errorf("submission %d has ID %d, not above the previous %d", i, e.ID, prev)
+		}
+		prev = e.ID
+	}
+}
+
+func TestScoreIDsUniqueConcurrently(t *testing.T) {
+	lb := NewLeaderboard()
+	lb.size = maxBoardSize
+	var wg sync.WaitGroup
+	for i := range 200 {
+		wg.Go(func() {
+			if _, err := lb.AddScore(t.Context(), fmt.Sprint("p", i%7), 1); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	seen := make(map[uint64]bool)
+	for _, e := range lb.History() {
+		if e.ID == 0 || seen[e.ID] {
+			t.Errorf("ID %d handed out twice or unset", e.ID)
+		}
+		seen[e.ID] = true
+	}
+	if len(seen) != 200 {
+		t.Errorf("got %d distinct IDs, want 200", len(seen))
+	}
+}
+
+func TestIDSequenceSurvivesRestart(t *testing.T) {
+	tests := []struct {
+		name string
+		// used is how many IDs are handed out before the restart
+		used int
+		skip uint64
+	}{
+		{name: "fresh", used: 0},
+		{name: "within a block", used: 3},
+		{name: "across blocks", used: idBlock + 5},
+		{name: "after a skip", used: 2, skip: 5 * idBlock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ids.json")
+			seq, err := loadIDSequence(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var last uint64
+			for range tt.used {
+				last = seq.Next()
+			}
+			if tt.skip > 0 {
+				seq.Skip(tt.skip)
+				last = tt.skip
+			}
+
+			seq, err = loadIDSequence(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id := seq.Next(); id <= last {
+				t.Errorf("first ID after restart = %d, want past %d", id, last)
+			}
+		})
+	}
+}
+
+func TestDeleteScoreByID(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		status int
+		// left is how many of the two identical entries remain
+		left int
+	}{
+		{name: "deletes one of two identical entries", id: "1", status: http.StatusOK, left: 1},
+		{name: "unknown ID", id: "99", status: http.StatusNotFound, left: 2},
+		{name: "zero", id: "0", status: http.StatusBadRequest, left: 2},
+		{name: "not a number", id: "ann", status: http.StatusBadRequest, left: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(WithAdminToken(testAdminToken))
+			h := testHandler(s)
+			do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":10}`)
+			do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":10}`)
+
+			rec := doAdmin(h, http.MethodDelete, "/api/admin/scores/"+tt.id, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			top := s.lb.GetTopScores()
+			if len(top) != tt.left {
+				t.Fatalf("board has %d entries, want %d", len(top), tt.left)
+			}
+			if tt.status == http.StatusOK && top[0].ID != 2 {
+				t.Errorf("remaining entry has ID %d, want 2", top[0].ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"gosuda.org/portal/sdk"
+)
+
+// portalALPNs lists the protocols the lease advertises. http/1.1 stays
+// first since relays show the first one as the service's kind.
+func portalALPNs(http2 bool) []string {
+	if http2 {
+		return []string{"http/1.1", "h2"}
+	}
+	return []string{"http/1.1"}
+}
+
+// serverProtocols enables HTTP/1.1 and, when http2 is set, HTTP/2 over TLS
+// and cleartext h2c. Portal connections are plain net.Conns that don't
+// report which ALPN the client picked, so h2c is detected per connection
+// from the HTTP/2 preface and anything else is served as HTTP/1.1.
+func serverProtocols(http2 bool) *http.Protocols {
+	var p http.Protocols
+	p.SetHTTP1(true)
+	if http2 {
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+	}
+	return &p
+}
+
+// listenPortal connects to the portal relays and registers the lease,
+// retrying with exponential backoff when the relays are unreachable
+func listenPortal(relays []string, name string, alpns []string, retries int, backoff time.Duration) (net.Listener, error) {
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			slog.Warn("retrying portal listen", "attempt", attempt, "of", retries, "backoff", backoff, "err", lastErr)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		client, err := sdk.NewClient(sdk.WithBootstrapServers(relays))
+		if err != nil {
+			lastErr = fmt.Errorf("connect to relays: %w", err)
+			continue
+		}
+
+		cred := sdk.NewCredential()
+		ln, err := client.Listen(cred, name, alpns)
+		if err != nil {
+			client.Close()
+			lastErr = fmt.Errorf("register lease: %w", err)
+			continue
+		}
+
+		return ln, nil
+	}
+
+	return nil, lastErr
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxLiveEntries bounds how many unexpired scores the live board holds;
+// past it the oldest go first, as they would soonest anyway
+const maxLiveEntries = 10000
+
+// liveBoard holds recent scores, each competing for ttl after it was
+// submitted. Scores arrive in time order, so expired ones are always at
+// the front and are dropped lazily on each add and read; no sweeper is
+// needed and a read never sees an expired score.
+type liveBoard struct {
+	ttl     time.Duration
+	entries []Score
+}
+
+// expire drops scores submitted ttl or longer before now
+func (lv *liveBoard) expire(now time.Time) {
+	i := 0
+	for i < len(lv.entries) && !now.Before(lv.entries[i].Timestamp.Add(lv.ttl)) {
+		i++
+	}
+	if excess := len(lv.entries) - i - maxLiveEntries; excess > 0 {
+		i += excess
+	}
+	lv.entries = slices.Delete(lv.entries, 0, i)
+}
+
+// LiveScore is a live board entry and when it drops off
+type LiveScore struct {
+	Score
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Live returns the top Size unexpired scores, each player's best only when
+// dedup is on, or false when the live board is disabled. Scores hidden by
+// a freeze at frozenAt are left out.
+func (lb *Leaderboard) Live(frozenAt time.Time) ([]LiveScore, bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if lb.live == nil {
+		return nil, false
+	}
+	lb.live.expire(lb.now())
+
+	ranked := slices.DeleteFunc(slices.Clone(lb.live.entries), func(e Score) bool { return hiddenByFreeze(e.Timestamp, frozenAt) })
+	// Stable, so equal scores keep arrival order like the main board
+	slices.SortStableFunc(ranked, func(a, b Score) int { return cmp.Compare(b.Score, a.Score) })
+	if lb.dedup {
+		seen := make(map[string]bool)
+		ranked = slices.DeleteFunc(ranked, func(e Score) bool {
+			dup := seen[e.Name]
+			seen[e.Name] = true
+			return dup
+		})
+	}
+	ranked = trimBoard(ranked, lb.Size())
+
+	top := make([]LiveScore, len(ranked))
+	for i, e := range ranked {
+		top[i] = LiveScore{Score: e, ExpiresAt: e.Timestamp.Add(lb.live.ttl)}
+	}
+	return top, true
+}
+
+// handleGetLiveBoard handles GET /api/leaderboard/live
+func (s *Server) handleGetLiveBoard(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	top, ok := s.lb.Live(s.freeze.FrozenAt())
+	if !ok {
+		http.Error(w, "Live board is disabled", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, capEntries(s, w, top))
+}
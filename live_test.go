@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLiveBoardExpires(t *testing.T) {
+	type submit struct {
+		at    time.Duration
+		name  string
+		score float64
+	}
+	tests := []struct {
+		name   string
+		dedup  bool
+		scores []submit
+		// want is the live board's names at each read time
+		want map[time.Duration][]string
+	}{
+		{
+			name:   "entries drop off after the TTL",
+			scores: []submit{{0, "ann", 10}, {30 * time.Second, "bob", 20}},
+			want: map[time.Duration][]string{
+				59 * time.Second: {"bob", "ann"},
+				time.Minute:      {"bob"},
+				90 * time.Second: {},
+			},
+		},
+		{
+			name:   "a fresh low score outlasts an old high one",
+			scores: []submit{{0, "ann", 50}, {45 * time.Second, "bob", 5}},
+			want: map[time.Duration][]string{
+				50 * time.Second: {"ann", "bob"},
+				70 * time.Second: {"bob"},
+			},
+		},
+		{
+			name:   "dedup falls back to a player's unexpired score",
+			dedup:  true,
+			scores: []submit{{0, "ann", 50}, {40 * time.Second, "ann", 5}, {40 * time.Second, "bob", 20}},
+			want: map[time.Duration][]string{
+				50 * time.Second: {"ann", "bob"},
+				70 * time.Second: {"bob", "ann"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			now := start
+			clock := func() time.Time { return now }
+			lb := NewLeaderboard()
+			lb.dedup = tt.dedup
+			lb.live = &liveBoard{ttl: time.Minute}
+			h := testHandler(NewServer(WithLeaderboard(lb), WithClock(clock)))
+			for _, sub := range tt.scores {
+				now = start.Add(sub.at)
+				if rec := do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":%q,"score":%v}`, sub.name, sub.score)); rec.Code != http.StatusCreated {
+					t.Fatalf("submit %s: status %d", sub.name, rec.Code)
+				}
+			}
+
+			reads := make([]time.Duration, 0, len(tt.want))
+			for at := range tt.want {
+				reads = append(reads, at)
+			}
+			slices.Sort(reads)
+			for _, at := range reads {
+				now = start.Add(at)
+				var board []LiveScore
+				if err := json.Unmarshal(do(h, http.MethodGet, "/api/leaderboard/live", "").Body.Bytes(), &board); err != nil {
+					t.Fatal(err)
+				}
+				got := []string{}
+				for _, e := range board {
+					got = append(got, e.Name)
+					if !e.ExpiresAt.Equal(e.Timestamp.Add(time.Minute)) {
+						t.Errorf("%s at %v expires at %v, want a minute after %v", e.Name, at, e.ExpiresAt, e.Timestamp)
+					}
+				}
+				if !slices.Equal(got, tt.want[at]) {
+					t.Errorf("live board at %v = %v, want %v", at, got, tt.want[at])
+				}
+			}
+		})
+	}
+}
+
+func TestLiveBoardDisabled(t *testing.T) {
+	if rec := do(testHandler(NewServer()), http.MethodGet, "/api/leaderboard/live", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
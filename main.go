@@ -1,55 +1,394 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
-	"gosuda.org/portal/sdk"
 )
 
-// Score represents a player's score entry
+// Score represents a player's score entry. Scores are plain numbers, so
+// whole and fractional scores share one ordering; whole scores still
+// encode without a decimal point.
 type Score struct {
+	// ID identifies the recorded submission; IDs only ever increase
+	ID        uint64    `json:"id"`
 	Name      string    `json:"name"`
-	Score     int       `json:"score"`
+	Score     float64   `json:"score"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
+const (
+	// defaultBoardSize is how many entries the board shows unless
+	// configured otherwise
+	defaultBoardSize = 10
+	// defaultBoardLabel names the board when only one is run
+	defaultBoardLabel = "global"
+	// maxBoardSize bounds -leaderboard-size
+	maxBoardSize = 1000
+)
+
 // Leaderboard manages the score entries
 type Leaderboard struct {
 	mu      sync.RWMutex
 	entries []Score
+
+	// history holds every accepted submission, oldest first, bounded by
+	// maxHistory (0 means unbounded)
+	history    []Score
+	maxHistory int
+
+	// lastSubmit is the time of each player's most recent submission
+	lastSubmit map[string]time.Time
+
+	// minDisplayScore is the lowest score placed on the board; lower
+	// scores are still recorded in history
+	minDisplayScore int
+
+	// dedup keeps only each player's best entry on the board
+	dedup bool
+
+	// homoglyphs merges names that only differ by lookalike characters
+	// into the first one seen; nameKeys indexes known names by skeleton
+	homoglyphs bool
+	nameKeys   map[string]string
+
+	// size is how many entries the board shows; see Size
+	size int
+
+	// records logs every new all-time best for the records feed
+	records *recordLog
+
+	// live, when set, ranks only scores submitted within its TTL
+	live *liveBoard
+
+	// decay, when set, ranks each entry by its score lowered linearly to
+	// nothing over decay after it was submitted; see rankValue. Decay
+	// reorders entries as they age, so they are only stored in ranking
+	// order as of rankedAt, when they were last sorted.
+	decay    time.Duration
+	rankedAt time.Time
+
+	// ids numbers recorded submissions
+	ids *idSequence
+
+	// milestones, when set, records the first player to reach each
+	// configured score
+	milestones *milestoneTracker
+
+	// boardSince is when the board was last reset; history from then on
+	// is what the board is ranked from
+	boardSince time.Time
+
+	// version counts changes to entries; changeLog keeps recent versions
+	// so polling clients can fetch diffs
+	version   uint64
+	changeLog []boardSnapshot
+
+	// listeners are called back after every change; see OnChange
+	listeners []*boardListener
+
+	// replaying is set while the event log is replayed; see beginReplay
+	replaying bool
+
+	// now timestamps new entries
+	now func() time.Time
 }
 
-var leaderboard = &Leaderboard{
-	entries: make([]Score, 0),
+// NewLeaderboard returns an empty leaderboard
+func NewLeaderboard() *Leaderboard {
+	return &Leaderboard{
+		entries:    make([]Score, 0),
+		lastSubmit: make(map[string]time.Time),
+		records:    &recordLog{size: defaultRecordFeedSize},
+		ids:        &idSequence{},
+		now:        time.Now,
+	}
+}
+
+// Size is how many entries the board shows. An unset or out-of-range
+// size falls back to defaultBoardSize rather than emptying the board.
+func (lb *Leaderboard) Size() int {
+	if lb.size < 1 || lb.size > maxBoardSize {
+		return defaultBoardSize
+	}
+	return lb.size
 }
 
-// AddScore adds a new score to the leaderboard
-func (lb *Leaderboard) AddScore(name string, score int) {
+// trimBoard cuts entries down to at most size, whatever their length
+func trimBoard(entries []Score, size int) []Score {
+	if size < 0 {
+		size = 0
+	}
+	if len(entries) > size {
+		return entries[:size]
+	}
+	return entries
+}
+
+// AddScore adds a new score to the leaderboard and reports whether it
+// placed on the board. Nothing is recorded if ctx is already done; the
+// in-memory board never blocks, so that is its only use of ctx.
+func (lb *Leaderboard) AddScore(ctx context.Context, name string, score float64) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
-	entry := Score{
-		Name:      name,
-		Score:     score,
-		Timestamp: time.Now(),
+	entry := Score{Name: name, Score: score}
+	if orig, ok := submittedFrom(ctx); ok {
+		entry.ID, entry.Timestamp = orig.ID, orig.Timestamp.UTC()
+	} else {
+		entry.ID, entry.Timestamp = lb.ids.Next(), lb.now().UTC()
+	}
+	return lb.addEntry(entry), nil
+}
+
+// addEntry records entry and reports whether it placed on the board.
+// lb.mu must be held for writing.
+func (lb *Leaderboard) addEntry(entry Score) bool {
+	name, score := entry.Name, entry.Score
+	now := lb.now()
+	value := lb.rankValue(now)
+	if t, ok := lb.lastSubmit[name]; !ok || entry.Timestamp.After(t) {
+		lb.lastSubmit[name] = entry.Timestamp
+	}
+	lb.indexName(name)
+	lb.history = insertByTime(lb.history, entry)
+	if lb.milestones != nil {
+		lb.milestones.reach(entry, !lb.replaying)
+	}
+	if lb.maxHistory > 0 && len(lb.history) > lb.maxHistory {
+		lb.history = lb.history[len(lb.history)-lb.maxHistory:]
 	}
 
+	if score < float64(lb.minDisplayScore) {
+		return false
+	}
+	lb.records.observe(entry)
+	if lb.live != nil {
+		lb.live.expire(lb.now())
+		if now.Before(entry.Timestamp.Add(lb.live.ttl)) {
+			lb.live.entries = insertByTime(lb.live.entries, entry)
+		}
+	}
+	if lb.dedup {
+		// Check and replace under the same lock so concurrent submissions
+		// for one player can never leave two of their entries on the board
+		if i := slices.IndexFunc(lb.entries, func(e Score) bool { return e.Name == name }); i >= 0 {
+			if score <= value(lb.entries[i]) {
+				return false
+			}
+			lb.entries = slices.Delete(lb.entries, i, i+1)
+		}
+	}
 	lb.entries = append(lb.entries, entry)
 
-	// Sort by score (descending)
+	// Sort by score, decayed if decay is on (descending)
 	sort.Slice(lb.entries, func(i, j int) bool {
-		return lb.entries[i].Score > lb.entries[j].Score
+		return value(lb.entries[i]) > value(lb.entries[j])
 	})
+	lb.rankedAt = now
+
+	// Keep only the top Size
+	lb.entries = trimBoard(lb.entries, lb.Size())
+
+	if !slices.Contains(lb.entries, entry) {
+		return false
+	}
+	lb.recordChange(BoardAdd)
+	return true
+}
 
-	// Keep only top 10
-	if len(lb.entries) > 10 {
-		lb.entries = lb.entries[:10]
+// insertByTime adds entry to scores, which are oldest first, keeping them
+// so. Entries almost always arrive in time order; only approved held
+// scores come late, with the time they were submitted.
+func insertByTime(scores []Score, entry Score) []Score {
+	if n := len(scores); n == 0 || !entry.Timestamp.Before(scores[n-1].Timestamp) {
+		return append(scores, entry)
 	}
+	i, _ := slices.BinarySearchFunc(scores, entry, func(e, t Score) int {
+		if e.Timestamp.After(t.Timestamp) {
+			return 1
+		}
+		return -1
+	})
+	return slices.Insert(scores, i, entry)
+}
+
+// RenamePlayer renames every entry recorded under from to to and returns
+// the number of board entries renamed. ok is false, and nothing changes,
+// if from has no board entry, retained history or last submission.
+func (lb *Leaderboard) RenamePlayer(from, to string) (renamed int, ok bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	named := func(e Score) bool { return e.Name == from }
+	if _, submitted := lb.lastSubmit[from]; !submitted &&
+		!slices.ContainsFunc(lb.entries, named) && !slices.ContainsFunc(lb.history, named) {
+		return 0, false
+	}
+
+	for i := range lb.entries {
+		if lb.entries[i].Name == from {
+			lb.entries[i].Name = to
+			renamed++
+		}
+	}
+	for i := range lb.history {
+		if lb.history[i].Name == from {
+			lb.history[i].Name = to
+		}
+	}
+	if renamed > 0 && lb.dedup {
+		// Merging into an existing name keeps that player's best entry,
+		// which comes first since entries are sorted
+		seen := make(map[string]bool, len(lb.entries))
+		lb.entries = slices.DeleteFunc(lb.entries, func(e Score) bool {
+			dup := seen[e.Name]
+			seen[e.Name] = true
+			return dup
+		})
+	}
+	if renamed > 0 {
+		lb.recordChange(BoardRename)
+	}
+	if t, ok := lb.lastSubmit[from]; ok {
+		delete(lb.lastSubmit, from)
+		if prev, ok := lb.lastSubmit[to]; !ok || t.After(prev) {
+			lb.lastSubmit[to] = t
+		}
+	}
+	// Rebuilt on next use, without the old name
+	lb.nameKeys = nil
+	return renamed, true
+}
+
+// ProspectiveRank returns the rank score would take if name submitted it
+// now, or 0 if it wouldn't make the board. Ties rank below the entries
+// already holding that score.
+func (lb *Leaderboard) ProspectiveRank(name string, score float64) int {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	if score < float64(lb.minDisplayScore) {
+		return 0
+	}
+	// A new score hasn't decayed at all yet
+	value := lb.rankValue(lb.now())
+	rank := 1
+	for _, e := range lb.entries {
+		if lb.dedup && e.Name == name {
+			if score <= value(e) {
+				// Dedup keeps the existing, better entry
+				return 0
+			}
+			continue
+		}
+		if value(e) >= score {
+			rank++
+		}
+	}
+	if rank > lb.Size() {
+		return 0
+	}
+	return rank
+}
+
+// PersonalBest returns name's best score recorded since the board was
+// last reset. Only retained history is considered.
+func (lb *Leaderboard) PersonalBest(name string) (float64, bool) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	var best float64
+	found := false
+	for i := len(lb.history) - 1; i >= 0; i-- {
+		e := lb.history[i]
+		if e.Timestamp.Before(lb.boardSince) {
+			break
+		}
+		if e.Name == name && (!found || e.Score > best) {
+			best, found = e.Score, true
+		}
+	}
+	return best, found
+}
+
+// LastSubmission returns the time of name's most recent submission
+func (lb *Leaderboard) LastSubmission(name string) (time.Time, bool) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	t, ok := lb.lastSubmit[name]
+	return t, ok
+}
+
+// SubmittedOn reports whether name has submitted during the UTC day containing day
+func (lb *Leaderboard) SubmittedOn(name string, day time.Time) bool {
+	t, ok := lb.LastSubmission(name)
+	if !ok {
+		return false
+	}
+	y1, m1, d1 := t.UTC().Date()
+	y2, m2, d2 := day.UTC().Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// History returns a copy of every retained submission, oldest first
+func (lb *Leaderboard) History() []Score {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	result := make([]Score, len(lb.history))
+	copy(result, lb.history)
+	return result
+}
+
+// Rank returns the 1-based rank and entry of the best score recorded under
+// name, or false if name is not on the board
+func (lb *Leaderboard) Rank(name string) (int, Score, bool) {
+	return lb.RankWith(name, rankingPositional)
+}
+
+// NextAbove returns the entry ranked immediately above name's best entry,
+// or nil if name is first. ok is false if name isn't on the board.
+func (lb *Leaderboard) NextAbove(name string) (above *Score, ok bool) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return nextAboveIn(lb.ranked(), name)
+}
+
+// nextAboveIn is NextAbove over entries, which must be sorted best first
+func nextAboveIn(entries []Score, name string) (above *Score, ok bool) {
+	for i, entry := range entries {
+		if entry.Name == name {
+			if i == 0 {
+				return nil, true
+			}
+			next := entries[i-1]
+			return &next, true
+		}
+	}
+	return nil, false
 }
 
 // GetTopScores returns the top scores
@@ -58,83 +397,437 @@ func (lb *Leaderboard) GetTopScores() []Score {
 	defer lb.mu.RUnlock()
 
 	// Return a copy to avoid race conditions
-	result := make([]Score, len(lb.entries))
-	copy(result, lb.entries)
-	return result
+	return slices.Clone(lb.ranked())
 }
 
-// handleSubmitScore handles POST /api/scores
-func handleSubmitScore(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	if r.Method != http.MethodPost {
+// handleGetLeaderboard handles GET /api/leaderboard
+func (s *Server) handleGetLeaderboard(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var req struct {
-		Name  string `json:"name"`
-		Score int    `json:"score"`
+	// While frozen the board shown is the snapshot taken at freeze time;
+	// submissions still update the live board behind it. The version is
+	// read first, so the entries are never older than it.
+	version := s.lb.Version()
+	scores := s.store.Top(s.lb.Size())
+	frozenScores, frozenVersion, frozenAt, frozen := s.freeze.Frozen()
+	if frozen {
+		scores, version = frozenScores, frozenVersion
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	// ?version=N serves the board as it stood at an earlier version, for
+	// as long as the change log still holds it
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+		// A frozen board hides every version after the freeze
+		if frozen && n > frozenVersion {
+			http.Error(w, "Version is from the future", http.StatusBadRequest)
+			return
+		}
+		at, oldest, err := s.lb.BoardAt(n)
+		switch {
+		case errors.Is(err, errVersionFuture):
+			http.Error(w, "Version is from the future", http.StatusBadRequest)
+			return
+		case errors.Is(err, errVersionGone):
+			writeJSON(w, r, http.StatusGone, map[string]any{
+				"error":         fmt.Sprintf("Version %d is no longer retained", n),
+				"oldestVersion": oldest,
+			})
+			return
+		}
+		scores, version = at, n
 	}
 
-	if req.Name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
-		return
+	// ?maxName shortens names for fixed-width clients; storage is untouched
+	if v := r.URL.Query().Get("maxName"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxNameLength {
+			http.Error(w, fmt.Sprintf("maxName must be between 1 and %d", maxNameLength), http.StatusBadRequest)
+			return
+		}
+		for i := range scores {
+			scores[i].Name = truncateName(scores[i].Name, n)
+		}
 	}
 
-	if req.Score < 0 {
-		http.Error(w, "Invalid score", http.StatusBadRequest)
-		return
+	pollAfter := s.pollAfter()
+	if pollAfter > 0 {
+		w.Header().Set("X-Poll-After", formatSeconds(pollAfter))
+	}
+	// X-Board-Fill describes the live board, to help tune its size, so a
+	// frozen board leaves it out rather than hint at what it hides
+	if !frozen {
+		w.Header().Set("X-Board-Fill", s.lb.Fill().header())
 	}
 
-	leaderboard.AddScore(req.Name, req.Score)
+	scores = capEntries(s, w, scores)
+	var entries any = scores
+	if r.URL.Query().Get("compact") == "true" {
+		entries = compactScores(scores)
+	} else if decayed, ok := s.lb.Decayed(scores); ok {
+		entries = decayed
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	// ?envelope=true wraps the entries with board metadata; the bare array
+	// stays the default for existing clients
+	if r.URL.Query().Get("envelope") == "true" {
+		resp := map[string]any{
+			"entries":     entries,
+			"board":       s.boardLabel,
+			"version":     version,
+			"generatedAt": s.now().UTC(),
+			"frozen":      frozen,
+		}
+		// The player count could give away players who joined since a
+		// freeze, so it is left out while frozen
+		if frozen {
+			resp["frozenAt"] = frozenAt.UTC()
+		} else {
+			resp["totalPlayers"] = s.lb.PlayerCount()
+		}
+		if pollAfter > 0 {
+			resp["pollAfter"] = pollAfter.Seconds()
+		}
+		writeJSON(w, r, http.StatusOK, resp)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, entries)
 }
 
-// handleGetLeaderboard handles GET /api/leaderboard
-func handleGetLeaderboard(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// compactScores converts scores to the compact leaderboard format used by
+// ?compact=true: an array of [rank, name, score] tuples in rank order,
+// e.g. [[1,"alice",42],[2,"bob",17]]. Timestamps are omitted.
+func compactScores(scores []Score) [][3]any {
+	rows := make([][3]any, len(scores))
+	for i, s := range scores {
+		rows[i] = [3]any{i + 1, s.Name, s.Score}
+	}
+	return rows
+}
+
+// handleGetRank handles GET /api/rank/:name
+func (s *Server) handleGetRank(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ranking, ok := rankingParam(w, r)
+	if !ok {
 		return
 	}
+	name := ps.ByName("name")
+	entries, value := s.publicBoard()
+	rank, entry, ok := rankIn(entries, name, ranking, value)
+	if !ok {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, struct {
+		Rank int `json:"rank"`
+		Score
+		SubmittedToday bool `json:"submittedToday"`
+	}{rank, entry, s.lb.SubmittedOn(name, s.now())})
+}
+
+// shutdown stops srv and then s, including a final backup when backups
+// are on, all within timeout. Connections still open when it runs out are
+// force-closed. It reports whether anything had to be cut short.
+func shutdown(srv *http.Server, s *Server, timeout time.Duration) (forced bool) {
+	slog.Info("shutting down", "timeout", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	scores := leaderboard.GetTopScores()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("graceful shutdown timed out, force-closing remaining connections", "err", err)
+		srv.Close()
+		forced = true
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		slog.Warn("streaming clients or publisher did not finish in time", "err", err)
+		forced = true
+	}
+	if s.backupInterval > 0 {
+		if err := s.finalBackup(ctx); err != nil {
+			slog.Warn("final leaderboard backup failed", "dir", s.backupDir, "err", err)
+			forced = true
+		}
+	}
+	return forced
+}
+
+var (
+	// cfg is the effective configuration: defaults, then the -config file,
+	// then environment variables, then explicitly set flags
+	cfg        = defaultConfig()
+	configPath = flag.String("config", "", "JSON file to load settings from; environment variables and flags override it")
+	// checkOnly validates the merged configuration and exits
+	checkOnly = flag.Bool("check-config", false, "validate the merged configuration, print any problems and exit non-zero if there are some, without starting the server")
+)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scores)
+func init() {
+	cfg.RegisterFlags(flag.CommandLine)
 }
 
 func main() {
-	client, err := sdk.NewClient(
-		sdk.WithBootstrapServers([]string{
-			"wss://portal.gosuda.org/relay",
-			"ws://localhost:4017/relay",
-		}),
-	)
+	flag.Parse()
+
+	// Flags given on the command line win over the file and environment, so
+	// remember them and apply them again on top of the loaded config
+	explicit := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = f.Value.String() })
+	loaded, err := LoadConfig(*configPath)
+	if err != nil {
+		slog.Error("failed to load config", "path", *configPath, "err", err)
+		os.Exit(1)
+	}
+	cfg = loaded
+	for name, value := range explicit {
+		flag.Set(name, value)
+	}
+	if *checkOnly {
+		os.Exit(checkConfig(os.Stdout, cfg))
+	}
+	if err := cfg.Validate(); err != nil {
+		slog.Error("invalid configuration", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sw, err := parseSubmissionWindow(cfg.EventStart, cfg.EventEnd)
+	if err != nil {
+		slog.Error("invalid submission window", "err", err)
+		os.Exit(1)
+	}
+	policy, err := compileNamePolicy(cfg.NamePattern)
 	if err != nil {
-		panic(err)
+		slog.Error("invalid name policy", "err", err)
+		os.Exit(1)
 	}
 
-	cred := sdk.NewCredential()
-	ln, err := client.Listen(cred, "Flappy-Gopher", []string{"http/1.1"})
+	lb := NewLeaderboard()
+	lb.maxHistory = cfg.HistorySize
+	lb.minDisplayScore = cfg.MinDisplayScore
+	lb.dedup = cfg.Dedup
+	lb.decay = cfg.ScoreDecay.Duration
+	lb.homoglyphs = cfg.CollapseHomoglyphs
+	lb.size = cfg.LeaderboardSize
+	lb.records.size = cfg.RecordFeedSize
+	if cfg.LiveTTL.Duration > 0 {
+		lb.live = &liveBoard{ttl: cfg.LiveTTL.Duration}
+	}
+	if len(cfg.Milestones) > 0 {
+		lb.milestones, err = loadMilestones(filepath.Join(cfg.DataDir, "milestones.json"), cfg.Milestones)
+		if err != nil {
+			slog.Error("failed to load milestones", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	lb.ids, err = loadIDSequence(filepath.Join(cfg.DataDir, "score_ids.json"))
+	if err != nil {
+		slog.Error("failed to load score ID sequence", "err", err)
+		os.Exit(1)
+	}
+
+	nc, err := loadNameClaims(filepath.Join(cfg.DataDir, "claims.json"))
 	if err != nil {
-		panic(err)
+		slog.Error("failed to load name claims", "err", err)
+		os.Exit(1)
+	}
+
+	exemptions, err := loadExemptions(filepath.Join(cfg.DataDir, "exemptions.json"), cfg.ExemptNames)
+	if err != nil {
+		slog.Error("failed to load rate limit exemptions", "err", err)
+		os.Exit(1)
+	}
+
+	auditPath := cfg.AuditLog
+	if auditPath == "" {
+		auditPath = filepath.Join(cfg.DataDir, "audit.log")
+	}
+	if err := os.MkdirAll(filepath.Dir(auditPath), 0o755); err != nil {
+		slog.Error("failed to create audit log directory", "err", err)
+		os.Exit(1)
+	}
+	al, err := OpenAuditLog(auditPath)
+	if err != nil {
+		slog.Error("failed to open audit log", "path", auditPath, "err", err)
+		os.Exit(1)
+	}
+	defer al.Close()
+
+	static, err := staticHandler(cfg.WebDir, cfg.Index)
+	if err != nil {
+		slog.Error("invalid static file configuration", "err", err)
+		os.Exit(1)
+	}
+	if err := checkWebDir(cfg.WebDir, cfg.Index); err != nil {
+		if cfg.StrictWebDir {
+			slog.Error("web directory is not servable", "err", err)
+			os.Exit(1)
+		}
+		slog.Warn("web directory is not servable; the game page will 404", "err", err)
+	}
+
+	var el *EventLog
+	if cfg.EventLog != "" {
+		el, err = OpenEventLog(cfg.EventLog)
+		if err != nil {
+			slog.Error("failed to open event log", "path", cfg.EventLog, "err", err)
+			os.Exit(1)
+		}
+		defer el.Close()
+	}
+
+	scoreFn, _ := lookupScoreFormula(cfg.ScoreFormula) // checked by Validate
+
+	origins, _ := parseAllowedOrigins(cfg.AllowedOrigins)  // checked by Validate
+	limiter, _ := newRateLimiter(cfg.RateLimits, time.Now) // checked by Validate
+
+	signatures, _ := newSignatureVerifier(cfg.SignatureAlgs, cfg.SigningSecret, cfg.SigningPublicKey) // checked by Validate
+	opts := []Option{
+		WithLeaderboard(lb),
+		WithQueueSize(cfg.QueueSize),
+		WithSignatureVerifier(signatures),
+		WithBoardCache(cfg.BoardCacheTTL.Duration, cfg.BoardCacheSize),
+		WithAdminToken(cfg.AdminToken),
+		WithNamePolicy(policy),
+		WithSubmissionWindow(sw),
+		WithMaxSubmitRate(cfg.MaxSubmitRate),
+		WithNameClaims(nc),
+		WithExemptions(exemptions),
+		WithSessionPolicy(cfg.RequireSession, cfg.MaxScoreRate),
+		WithJumpDetector(cfg.JumpFactor, cfg.JumpMinHistory),
+		WithScoreDecimals(cfg.ScoreDecimals),
+		WithScoreFormula(scoreFn),
+		WithReplayBounds(cfg.ReplayMaxFrames, cfg.ReplayMaxTaps),
+		WithRecordAllSubmissions(cfg.RecordAllSubmissions),
+		WithPollInterval(cfg.PollInterval.Duration),
+		WithBoardLabel(cfg.BoardLabel),
+		WithMaxResponseEntries(cfg.MaxResponseEntries),
+		WithBackups(cfg.BackupDir, cfg.BackupInterval.Duration, cfg.BackupKeep),
+		WithSelfCheck(cfg.SelfCheckInterval.Duration),
+		WithDailyArchive(filepath.Join(cfg.DataDir, "daily"), cfg.DailyKeep),
+		WithEventLog(el),
+		WithAuditLog(al),
+		WithAllowedOrigins(origins),
+		WithRateLimits(limiter),
+		WithConfigSnapshot(cfg),
+		WithPrettyJSON(cfg.DevMode),
+	}
+	if cfg.Follow != "" {
+		primary, _ := parseFollowURL(cfg.Follow) // checked by Validate
+		opts = append(opts, WithFollower(primary, cfg.FollowToken, cfg.FollowInterval.Duration), WithFollowerWrites(cfg.FollowerWrites))
+	}
+	if cfg.PublishWebhook != "" {
+		opts = append(opts, WithPublisher(NewWebhookPublisher(cfg.PublishWebhook)))
+	}
+	s := NewServer(opts...)
+	// Before listening, so no request sees the board half loaded
+	if cfg.WarmUp {
+		start := time.Now()
+		n, err := s.warmUp(cfg.EventLog)
+		if err != nil {
+			slog.Error("failed to warm up from event log", "path", cfg.EventLog, "err", err)
+			os.Exit(1)
+		}
+		slog.Info("warmed up from event log", "path", cfg.EventLog, "events", n, "duration", time.Since(start))
 	}
 
-	r := httprouter.New()
+	// TLS only applies to the local listener; the portal handles its own
+	var tlsConfig *tls.Config
+	if cfg.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			slog.Error("failed to load TLS certificate", "cert", cfg.TLSCert, "key", cfg.TLSKey, "err", err)
+			os.Exit(1)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
 
-	// API endpoints
-	r.POST("/api/scores", handleSubmitScore)
-	r.GET("/api/leaderboard", handleGetLeaderboard)
+	// With no relays configured the server only listens locally
+	var ln net.Listener
+	local := false
+	if len(cfg.Relays) > 0 {
+		ln, err = listenPortal(cfg.Relays, cfg.ListenName, portalALPNs(cfg.HTTP2), cfg.ListenRetries, cfg.ListenBackoff.Duration)
+		if err != nil {
+			if cfg.Addr == "" {
+				slog.Error("failed to listen on portal", "retries", cfg.ListenRetries, "err", err)
+				os.Exit(1)
+			}
+			slog.Error("failed to listen on portal, falling back to local listener", "retries", cfg.ListenRetries, "addr", cfg.Addr, "err", err)
+		}
+	}
+	if ln == nil {
+		ln, err = net.Listen("tcp", cfg.Addr)
+		if err != nil {
+			slog.Error("failed to listen on local address", "addr", cfg.Addr, "err", err)
+			os.Exit(1)
+		}
+		local = true
+	}
+	useTLS := local && tlsConfig != nil
+	slog.Info("listening", "addr", ln.Addr().String(), "tls", useTLS, "http2", cfg.HTTP2)
 
-	// Static files
-	r.NotFound = http.FileServer(http.Dir("./web"))
+	var wg sync.WaitGroup
+	if s.follow != nil {
+		// The primary schedules resets; a follower picks them up on its
+		// next pull
+		wg.Go(func() { s.runFollower(ctx) })
+	} else if cfg.DailyReset {
+		loc := loadResetLocation(cfg.ResetTimezone)
+		wg.Go(func() { s.runDailyReset(ctx, loc) })
+	}
+	if s.backupInterval > 0 {
+		wg.Go(func() { s.runBackups(ctx) })
+	}
+	if s.selfCheckInterval > 0 {
+		wg.Go(func() { s.runSelfCheck(ctx) })
+	}
+	if cfg.CompactInterval.Duration > 0 {
+		wg.Go(func() {
+			s.runEventLogCompaction(ctx, cfg.CompactInterval.Duration, int64(cfg.CompactMinBytes), cfg.EventMaxAge.Duration)
+		})
+	}
+	if cfg.PushGateway != "" {
+		wg.Go(func() { s.runMetricsPush(ctx, cfg.PushGateway, cfg.PushJob, cfg.PushInterval.Duration) })
+	}
+
+	proxies, _ := parseTrustedProxies(cfg.TrustedProxies) // checked by Validate
+	srv := &http.Server{
+		Handler:   withRequestID(withAPIVersion(withClientIP(proxies, withAccessLog(withRateLimits(s.rateLimits, s.rateLimitExempt, s.routes(static)))))),
+		Protocols: serverProtocols(cfg.HTTP2),
+	}
+	// forced is set when shutdown had to cut connections or the final
+	// flush short, which makes the process exit non-zero
+	var forced atomic.Bool
+	wg.Go(func() {
+		<-ctx.Done()
+		forced.Store(shutdown(srv, s, cfg.ShutdownTimeout.Duration))
+	})
 
-	http.Serve(ln, r)
+	if useTLS {
+		srv.TLSConfig = tlsConfig
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("server failed", "err", err)
+		stop()
+	}
+	wg.Wait()
+
+	if forced.Load() {
+		// os.Exit skips deferred calls, so close the logs first
+		el.Close()
+		al.Close()
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Handlers log every request; keep test output to the failures
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+func TestRankSubmittedToday(t *testing.T) {
+	submitted := time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "same moment", at: submitted, want: true},
+		{name: "later that day", at: time.Date(2024, 5, 1, 23, 59, 59, 0, time.UTC), want: true},
+		{name: "just past midnight", at: time.Date(2024, 5, 2, 0, 0, 1, 0, time.UTC), want: false},
+		{name: "same day in another zone", at: submitted.In(time.FixedZone("UTC+3", 3*60*60)), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := submitted
+			s := NewServer(WithClock(func() time.Time { return now }))
+			h := testHandler(s)
+			if rec := do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":10}`); rec.Code != http.StatusCreated {
+				t.Fatalf("submit: status %d", rec.Code)
+			}
+
+			now = tt.at
+			rec := do(h, http.MethodGet, "/api/rank/ann", "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("rank: status %d", rec.Code)
+			}
+			var body struct {
+				SubmittedToday bool `json:"submittedToday"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.SubmittedToday != tt.want {
+				t.Errorf("submittedToday = %v, want %v", body.SubmittedToday, tt.want)
+			}
+		})
+	}
+
+	if NewLeaderboard().SubmittedOn("nobody", submitted) {
+		t.Error("SubmittedOn reported a submission for an unknown player")
+	}
+}
+
+func TestLeaderboardCompact(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores map[string]float64
+		query  string
+	}{
+		{name: "empty board", query: ""},
+		{name: "several players", scores: map[string]float64{"ann": 5, "bob": 12.5, "cat": 30, "dan": 12}},
+		{name: "shortened names", scores: map[string]float64{"annabelle": 5, "bartholomew": 9}, query: "&maxName=4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHandler(NewServer(WithScoreDecimals(1)))
+			for name, score := range tt.scores {
+				body, _ := json.Marshal(map[string]any{"name": name, "score": score})
+				if rec := do(h, http.MethodPost, "/api/scores", string(body)); rec.Code != http.StatusCreated {
+					t.Fatalf("submit %s: status %d: %s", name, rec.Code, rec.Body)
+				}
+			}
+
+			var verbose []Score
+			if err := json.Unmarshal(do(h, http.MethodGet, "/api/leaderboard?compact=false"+tt.query, "").Body.Bytes(), &verbose); err != nil {
+				t.Fatal(err)
+			}
+			rec := do(h, http.MethodGet, "/api/leaderboard?compact=true"+tt.query, "")
+			var rows [][]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+				t.Fatalf("compact body %s: %v", rec.Body, err)
+			}
+
+			if len(rows) != len(verbose) {
+				t.Fatalf("compact board has %d rows, verbose %d", len(rows), len(verbose))
+			}
+			for i, row := range rows {
+				if len(row) != 3 {
+					t.Fatalf("row %d = %v, want [rank, name, score]", i, row)
+				}
+				rank, name, score := row[0], row[1], row[2]
+				if rank != float64(i+1) || name != verbose[i].Name || score != verbose[i].Score {
+					t.Errorf("row %d = %v, want [%d %q %v]", i, row, i+1, verbose[i].Name, verbose[i].Score)
+				}
+			}
+		})
+	}
+}
+
+func TestMinDisplayScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		floor int
+		want  []string
+	}{
+		{name: "no floor", floor: 0, want: []string{"cat", "bob", "ann"}},
+		{name: "floor between scores", floor: 5, want: []string{"cat", "bob"}},
+		{name: "floor at a score", floor: 12, want: []string{"cat", "bob"}},
+		{name: "floor above every score", floor: 100, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.minDisplayScore = tt.floor
+			s := NewServer(WithLeaderboard(lb))
+			h := testHandler(s)
+			for _, body := range []string{`{"name":"ann","score":1}`, `{"name":"bob","score":12}`, `{"name":"cat","score":30}`} {
+				if rec := do(h, http.MethodPost, "/api/scores", body); rec.Code != http.StatusCreated {
+					t.Fatalf("submit %s: status %d", body, rec.Code)
+				}
+			}
+
+			got := []string{}
+			for _, e := range lb.GetTopScores() {
+				got = append(got, e.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("board = %v, want %v", got, tt.want)
+			}
+			// Sub-floor scores still count
+			if st := s.stats.Stats(); st.Count != 3 {
+				t.Errorf("stats count = %d, want 3", st.Count)
+			}
+		})
+	}
+}
+
+func TestShutdownStuckHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		stuck  bool
+		forced bool
+	}{
+		{name: "idle server", stuck: false, forced: false},
+		{name: "stuck handler", stuck: true, forced: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entered, release := make(chan struct{}), make(chan struct{})
+			defer close(release)
+			srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(entered)
+				<-release
+			})}
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go srv.Serve(ln)
+			if tt.stuck {
+				go http.Get("http://" + ln.Addr().String())
+				<-entered
+			}
+
+			start := time.Now()
+			forced := shutdown(srv, NewServer(), 100*time.Millisecond)
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("shutdown took %v with a 100ms timeout", elapsed)
+			}
+			if forced != tt.forced {
+				t.Errorf("shutdown forced = %v, want %v", forced, tt.forced)
+			}
+		})
+	}
+}
+
+func TestAddScoreConcurrentDedup(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		players int
+	}{
+		{name: "few players, roomy board", size: 10, players: 3},
+		{name: "more players than fit", size: 5, players: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size, lb.dedup = tt.size, true
+
+			var writers, readers sync.WaitGroup
+			done := make(chan struct{})
+			for range 4 {
+				readers.Go(func() {
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						seen := make(map[string]bool)
+						for _, e := range lb.GetTopScores() {
+							if seen[e.Name] {
+								t.Errorf("%s appears twice on the board", e.Name)
+								return
+							}
+							seen[e.Name] = true
+						}
+					}
+				})
+			}
+			for w := range 8 {
+				writers.Go(func() {
+					for i := range 200 {
+						lb.AddScore(t.Context(), fmt.Sprint("p", (w+i)%tt.players), float64(i))
+					}
+				})
+			}
+			writers.Wait()
+			close(done)
+			readers.Wait()
+
+			if got, want := len(lb.GetTopScores()), min(tt.size, tt.players); got != want {
+				t.Errorf("board has %d entries, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestBoardSizeBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		entries int
+		want    int
+	}{
+		{name: "negative falls back", size: -3, entries: 12, want: defaultBoardSize},
+		{name: "zero falls back", size: 0, entries: 12, want: defaultBoardSize},
+		{name: "one", size: 1, entries: 5, want: 1},
+		{name: "larger than the entries", size: 8, entries: 3, want: 3},
+		{name: "past the maximum falls back", size: maxBoardSize + 1, entries: 12, want: defaultBoardSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size = tt.size
+			for i := range tt.entries {
+				if _, err := lb.AddScore(t.Context(), fmt.Sprint("p", i), float64(i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := len(lb.GetTopScores()); got != tt.want {
+				t.Errorf("GetTopScores has %d entries, want %d", got, tt.want)
+			}
+			if got := len(lb.Top(tt.size)); got != min(max(tt.size, 0), tt.want) {
+				t.Errorf("Top(%d) has %d entries, want %d", tt.size, got, min(max(tt.size, 0), tt.want))
+			}
+		})
+	}
+}
+
+func TestTrimBoard(t *testing.T) {
+	entries := []Score{{Name: "ann"}, {Name: "bob"}, {Name: "cat"}}
+	for _, tt := range []struct{ size, want int }{{-1, 0}, {0, 0}, {1, 1}, {3, 3}, {10, 3}} {
+		if got := len(trimBoard(entries, tt.size)); got != tt.want {
+			t.Errorf("trimBoard(3 entries, %d) has %d entries, want %d", tt.size, got, tt.want)
+		}
+	}
+	if got := trimBoard(nil, 5); len(got) != 0 {
+		t.Errorf("trimBoard(nil, 5) = %v, want empty", got)
+	}
+}
+
+func TestLeaderboardLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "single board", want: defaultBoardLabel},
+		{name: "labelled", opts: []Option{WithBoardLabel("hard-eu")}, want: "hard-eu"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(testHandler(NewServer(tt.opts...)), http.MethodGet, "/api/leaderboard?envelope=true", "")
+			var resp struct {
+				Board string `json:"board"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Board != tt.want {
+				t.Errorf("board = %q, want %q", resp.Board, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serverMetrics holds the Prometheus collectors exposed on a Server's
+// /metrics endpoint
+type serverMetrics struct {
+	registry *prometheus.Registry
+	handler  http.Handler
+
+	submissionsShed     prometheus.Counter
+	submissionsHeld     prometheus.Counter
+	rejections          *prometheus.CounterVec
+	invariantViolations *prometheus.CounterVec
+	boardChanges        *prometheus.CounterVec
+}
+
+func newServerMetrics(s *Server) *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		submissionsShed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "flappy_submissions_shed_total",
+			Help: "Score submissions rejected by the global rate breaker.",
+		}),
+		submissionsHeld: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "flappy_submissions_held_total",
+			Help: "Score submissions held for admin review.",
+		}),
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flappy_submission_rejections_total",
+			Help: "Score submissions rejected, by reason.",
+		}, []string{"reason"}),
+		invariantViolations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flappy_board_invariant_violations_total",
+			Help: "Leaderboard invariant violations found by the periodic self-check, by invariant.",
+		}, []string{"invariant"}),
+		boardChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flappy_board_changes_total",
+			Help: "Changes to the leaderboard, by kind.",
+		}, []string{"kind"}),
+	}
+
+	submissionRate := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "flappy_submission_rate",
+		Help: "Score submissions per second across all clients over the breaker window.",
+	}, func() float64 {
+		return s.submitRate.RateAt(s.now())
+	})
+
+	m.registry.MustRegister(
+		m.submissionsShed, m.submissionsHeld, m.rejections, m.invariantViolations, m.boardChanges, submissionRate,
+		// Runtime and process stats, for spotting goroutine or FD leaks in
+		// long-lived connections
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	m.handler = promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	return m
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	h := testHandler(NewServer())
+	do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":-1}`)
+
+	rec := do(h, http.MethodGet, "/metrics", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"go_goroutines ",
+		"go_memstats_heap_alloc_bytes ",
+		"go_gc_duration_seconds",
+		"process_open_fds ",
+		"flappy_submission_rate ",
+		`flappy_submission_rejections_total{reason="invalid_score"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q", want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	prettyJSONKey
+	clientIPKey
+	submittedKey
+)
+
+// maxRequestIDLength bounds client supplied request IDs so they can't bloat logs
+const maxRequestIDLength = 128
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// validRequestID reports whether a client supplied request ID is safe to reuse
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDFrom returns the request ID stored in ctx, if any
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// loggerFrom returns a logger annotated with the request ID stored in ctx
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if id := requestIDFrom(ctx); id != "" {
+		return slog.With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// withRequestID reads X-Request-ID (or generates one), stores it in the
+// request context and echoes it back in the response
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newUUID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// apiVersion is sent as X-API-Version on every /api/ response. Bump it
+// on breaking changes to the API's contract.
+const apiVersion = "1"
+
+// withAPIVersion sets X-API-Version on /api/ responses so clients can
+// detect the contract they are talking to
+func withAPIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			w.Header().Set("X-API-Version", apiVersion)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades take over the connection
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	sr.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(sr.ResponseWriter).Hijack()
+}
+
+// withAccessLog logs every request along with its request ID
+func withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sr, r)
+
+		loggerFrom(r.Context()).Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"ip", clientIP(r),
+			"status", sr.status,
+			"duration", time.Since(start),
+		)
+	})
+}
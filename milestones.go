@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Milestone is a score threshold and the first player to reach it
+type Milestone struct {
+	Threshold float64    `json:"threshold"`
+	Name      string     `json:"name,omitempty"`
+	Score     float64    `json:"score,omitempty"`
+	ReachedAt *time.Time `json:"reachedAt,omitempty"`
+}
+
+// milestoneTracker records who first reached each configured threshold.
+// Winners are never replaced and survive daily resets. It is guarded by
+// the owning Leaderboard's lock.
+type milestoneTracker struct {
+	path       string
+	milestones []Milestone // ascending by threshold
+	// unsaved is set when winners were awarded quietly and not yet saved
+	unsaved bool
+}
+
+// loadMilestones tracks thresholds, restoring winners stored at path so a
+// restart doesn't reopen a race that was already won
+func loadMilestones(path string, thresholds []float64) (*milestoneTracker, error) {
+	var stored []Milestone
+	if err := readJSONFile(path, &stored); err != nil {
+		return nil, err
+	}
+
+	thresholds = slices.Clone(thresholds)
+	slices.Sort(thresholds)
+	mt := &milestoneTracker{path: path, milestones: make([]Milestone, 0, len(thresholds))}
+	for _, t := range slices.Compact(thresholds) {
+		m := Milestone{Threshold: t}
+		if i := slices.IndexFunc(stored, func(s Milestone) bool { return s.Threshold == t }); i >= 0 && stored[i].ReachedAt != nil {
+			m = stored[i]
+		}
+		mt.milestones = append(mt.milestones, m)
+	}
+	return mt, nil
+}
+
+// reach awards every unclaimed milestone at or below entry's score to
+// entry. With announce set, awards are logged and the winners persisted at
+// once; otherwise they are left unsaved for the caller.
+func (mt *milestoneTracker) reach(entry Score, announce bool) {
+	awarded := false
+	for i := range mt.milestones {
+		m := &mt.milestones[i]
+		if m.Threshold > entry.Score {
+			break
+		}
+		if m.ReachedAt != nil {
+			continue
+		}
+		at := entry.Timestamp
+		m.Name, m.Score, m.ReachedAt = entry.Name, entry.Score, &at
+		awarded = true
+		if announce {
+			slog.Info("milestone reached", "threshold", m.Threshold, "name", entry.Name, "score", entry.Score)
+		}
+	}
+
+	if awarded && !announce {
+		mt.unsaved = true
+	} else if awarded {
+		// The award stands even if it can't be saved; the in-memory winner
+		// is still first
+		mt.save()
+	}
+}
+
+// save persists the winners, if the tracker has a path, logging failures
+func (mt *milestoneTracker) save() {
+	if mt.path == "" {
+		return
+	}
+	if err := writeJSONFile(mt.path, mt.milestones); err != nil {
+		slog.Error("failed to persist milestones", "path", mt.path, "err", err)
+	}
+}
+
+// Milestones returns every configured milestone, lowest threshold first
+func (lb *Leaderboard) Milestones() []Milestone {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	if lb.milestones == nil {
+		return []Milestone{}
+	}
+	return slices.Clone(lb.milestones.milestones)
+}
+
+// handleGetMilestones handles GET /api/milestones
+func (s *Server) handleGetMilestones(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	milestones := s.lb.Milestones()
+	// A milestone reached since a freeze is shown as still open
+	frozenAt := s.freeze.FrozenAt()
+	for i, m := range milestones {
+		if m.ReachedAt != nil && hiddenByFreeze(*m.ReachedAt, frozenAt) {
+			milestones[i] = Milestone{Threshold: m.Threshold}
+		}
+	}
+	writeJSON(w, r, http.StatusOK, milestones)
+}
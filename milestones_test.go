@@ -0,0 +1,93 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMilestonesFirstWins(t *testing.T) {
+	type entry struct {
+		name  string
+		score float64
+	}
+	tests := []struct {
+		name   string
+		scores []entry
+		// want is each threshold's winner, "" while unreached
+		want map[float64]string
+	}{
+		{name: "unreached", scores: []entry{{"ann", 5}}, want: map[float64]string{10: "", 50: ""}},
+		{name: "exact threshold counts", scores: []entry{{"ann", 10}}, want: map[float64]string{10: "ann", 50: ""}},
+		{name: "one score crosses several", scores: []entry{{"ann", 60}}, want: map[float64]string{10: "ann", 50: "ann"}},
+		{
+			name:   "later higher scores don't take over",
+			scores: []entry{{"ann", 12}, {"bob", 55}, {"cat", 90}},
+			want:   map[float64]string{10: "ann", 50: "bob"},
+		},
+		{name: "own later score doesn't move the time", scores: []entry{{"ann", 12}, {"ann", 20}}, want: map[float64]string{10: "ann", 50: ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			lb := NewLeaderboard()
+			lb.now = func() time.Time { return now }
+			lb.milestones = &milestoneTracker{milestones: []Milestone{{Threshold: 10}, {Threshold: 50}}}
+			first := map[string]time.Time{}
+			for _, e := range tt.scores {
+				if _, ok := first[e.name]; !ok {
+					first[e.name] = now
+				}
+				if _, err := lb.AddScore(t.Context(), e.name, e.score); err != nil {
+					t.Fatal(err)
+				}
+				now = now.Add(time.Minute)
+			}
+
+			for _, m := range lb.Milestones() {
+				if m.Name != tt.want[m.Threshold] {
+					t.Errorf("milestone %v won by %q, want %q", m.Threshold, m.Name, tt.want[m.Threshold])
+				}
+				if m.Name != "" && !m.ReachedAt.Equal(first[m.Name]) {
+					t.Errorf("milestone %v reached at %v, want %v", m.Threshold, m.ReachedAt, first[m.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestMilestonesSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "milestones.json")
+	mt, err := loadMilestones(path, []float64{50, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb := NewLeaderboard()
+	lb.milestones = mt
+	if _, err := lb.AddScore(t.Context(), "ann", 20); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart with a new threshold keeps the old winner and opens the new one
+	mt, err = loadMilestones(path, []float64{10, 50, 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb = NewLeaderboard()
+	lb.milestones = mt
+	if _, err := lb.AddScore(t.Context(), "bob", 200); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ann", "bob", "bob"}
+	got := lb.Milestones()
+	if len(got) != len(want) {
+		t.Fatalf("got %d milestones, want %d", len(got), len(want))
+	}
+	for i, m := range got {
+		if m.Name != want[i] {
+			t.Errorf("milestone %v won by %q, want %q", m.Threshold, m.Name, want[i])
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxNameLength is the maximum length of a player name in runes
+const maxNameLength = 20
+
+// defaultNamePattern allows letters, digits, spaces and a little punctuation
+const defaultNamePattern = `^[\p{L}\p{N} _.,!?'-]+$`
+
+// compileNamePolicy compiles an operator supplied name pattern. The pattern
+// must match the whole name, so it is always anchored as a whole; anchors
+// it already has are harmless, while anchors on only some alternatives
+// would otherwise leave the rest matching any part of the name.
+func compileNamePolicy(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid name pattern: %w", err)
+	}
+	return re, nil
+}
+
+// errNameEncoding rejects names that aren't valid UTF-8, which counts as a
+// malformed request rather than a rule violation
+var errNameEncoding = errors.New("Name must be valid UTF-8")
+
+// nameErrorStatus is the status for a sanitizeName error: 400 for a
+// malformed name, 422 for a well-formed one the rules reject
+func nameErrorStatus(err error) int {
+	if errors.Is(err, errNameEncoding) {
+		return http.StatusBadRequest
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// sanitizeName trims surrounding whitespace from a player name and checks
+// that the result is acceptable
+func (s *Server) sanitizeName(name string) (string, error) {
+	// encoding/json decodes invalid UTF-8 to U+FFFD, so a replacement
+	// character is treated as invalid input too rather than stored as
+	// mojibake
+	if !utf8.ValidString(name) || strings.ContainsRune(name, utf8.RuneError) {
+		return "", errNameEncoding
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("Name is required")
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return "", fmt.Errorf("Name must be at most %d characters", maxNameLength)
+	}
+	if !s.namePolicy.MatchString(name) {
+		return "", fmt.Errorf("Name contains characters not allowed by the name policy %s", s.namePolicy)
+	}
+	return name, nil
+}
+
+// truncateName shortens name to at most n runes for display, replacing
+// the cut-off tail with an ellipsis
+func truncateName(name string, n int) string {
+	if utf8.RuneCountInString(name) <= n {
+		return name
+	}
+	runes := []rune(name)
+	return string(runes[:n-1]) + "…"
+}
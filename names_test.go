@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNamePolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		name   string
+		want   string
+		// err is part of the expected error, if the name is rejected
+		err string
+	}{
+		{policy: defaultNamePattern, name: "Ann Lee", want: "Ann Lee"},
+		{policy: defaultNamePattern, name: "  José-María! ", want: "José-María!"},
+		{policy: defaultNamePattern, name: "김민수", want: "김민수"},
+		{policy: defaultNamePattern, name: "<script>", err: "name policy"},
+		{policy: defaultNamePattern, name: "  ", err: "required"},
+		{policy: defaultNamePattern, name: strings.Repeat("a", maxNameLength+1), err: "at most"},
+		{policy: `[a-z0-9]+`, name: "ann99", want: "ann99"},
+		{policy: `[a-z0-9]+`, name: "Ann", err: "[a-z0-9]+"},
+		{policy: `[a-z0-9]+`, name: "ann lee", err: "name policy"},
+		// Unanchored alternatives must still match the whole name
+		{policy: `ann|bob`, name: "bob", want: "bob"},
+		{policy: `ann|bob`, name: "bobby", err: "name policy"},
+		{policy: `^[A-Z][a-z]+$`, name: "Ann", want: "Ann"},
+		{policy: `^[A-Z][a-z]+$`, name: "ann", err: "name policy"},
+		// Anchors on the outer alternatives don't anchor the whole pattern
+		{policy: `^[a-z]+|[0-9]+$`, name: "ann", want: "ann"},
+		{policy: `^[a-z]+|[0-9]+$`, name: "ann!!", err: "name policy"},
+		{policy: `^[a-z]+|[0-9]+$`, name: "!!99", err: "name policy"},
+		{policy: `[a-z]+\$`, name: "ann$", want: "ann$"},
+		{policy: `[a-z]+\$`, name: "ann$$", err: "name policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy+" "+tt.name, func(t *testing.T) {
+			policy, err := compileNamePolicy(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewServer(WithNamePolicy(policy))
+			got, err := s.sanitizeName(tt.name)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("sanitizeName(%q) = %v, want %q", tt.name, err, tt.want)
+			case tt.err == "" && got != tt.want:
+				t.Errorf("sanitizeName(%q) = %q, want %q", tt.name, got, tt.want)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("sanitizeName(%q) = %q, %v; want an error mentioning %q", tt.name, got, err, tt.err)
+			case tt.err != "" && nameErrorStatus(err) != http.StatusUnprocessableEntity:
+				t.Errorf("status for %v = %d, want %d", err, nameErrorStatus(err), http.StatusUnprocessableEntity)
+			}
+		})
+	}
+}
+
+func TestCompileNamePolicyInvalid(t *testing.T) {
+	if _, err := compileNamePolicy(`[a-z`); err == nil {
+		t.Error("compileNamePolicy accepted an unterminated class")
+	}
+}
+
+func TestSubmitInvalidUTF8Name(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "lone continuation byte", body: "{\"name\":\"ann\x80\",\"score\":10}"},
+		{name: "truncated sequence", body: "{\"name\":\"\xe2\x82\",\"score\":10}"},
+		{name: "overlong encoding", body: "{\"name\":\"\xc0\xafann\",\"score\":10}"},
+		{name: "escaped surrogate", body: `{"name":"ann\ud800","score":10}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			h := testHandler(s)
+			rec := do(h, http.MethodPost, "/api/scores", tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+			}
+			if top := s.lb.GetTopScores(); len(top) != 0 {
+				t.Errorf("board holds %+v, want nothing stored", top)
+			}
+		})
+	}
+}
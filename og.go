@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	ogWidth  = 1200
+	ogHeight = 630
+
+	// ogCacheTTL is how long a rendered preview image is reused
+	ogCacheTTL = time.Minute
+	// ogCacheMaxEntries bounds the number of cached preview images
+	ogCacheMaxEntries = 256
+)
+
+var (
+	ogSky  = color.RGBA{0x70, 0xc5, 0xce, 0xff}
+	ogPipe = color.RGBA{0x2e, 0x8b, 0x3e, 0xff}
+	ogText = color.RGBA{0x11, 0x11, 0x11, 0xff}
+)
+
+type ogCacheEntry struct {
+	png     []byte
+	expires time.Time
+}
+
+// ogImageCache caches rendered preview images keyed by player name ("" for
+// the default image)
+type ogImageCache struct {
+	mu      sync.Mutex
+	entries map[string]ogCacheEntry
+}
+
+func newOGImageCache() *ogImageCache {
+	return &ogImageCache{entries: make(map[string]ogCacheEntry)}
+}
+
+var ogFaces = sync.OnceValues(func() (map[float64]font.Face, error) {
+	f, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+
+	faces := make(map[float64]font.Face)
+	for _, size := range []float64{40, 64, 96} {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, err
+		}
+		faces[size] = face
+	}
+	return faces, nil
+})
+
+// renderOGImage draws the preview card with the given lines of text, each
+// paired with its font size
+func renderOGImage(lines []string, sizes []float64) ([]byte, error) {
+	faces, err := ogFaces()
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, ogWidth, ogHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(ogSky), image.Point{}, draw.Src)
+
+	// A pair of pipes on either side, like in the game
+	for _, x := range []int{60, ogWidth - 140} {
+		draw.Draw(img, image.Rect(x, 0, x+80, 150), image.NewUniform(ogPipe), image.Point{}, draw.Src)
+		draw.Draw(img, image.Rect(x, ogHeight-150, x+80, ogHeight), image.NewUniform(ogPipe), image.Point{}, draw.Src)
+	}
+
+	y := 200
+	for i, line := range lines {
+		face := faces[sizes[i]]
+		d := &font.Drawer{Dst: img, Src: image.NewUniform(ogText), Face: face}
+		width := d.MeasureString(line).Ceil()
+		d.Dot = fixed.P((ogWidth-width)/2, y)
+		d.DrawString(line)
+		y += face.Metrics().Height.Ceil() + 30
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ogImageFor renders (or returns the cached) preview image for name
+func (s *Server) ogImageFor(name string) ([]byte, error) {
+	ts := s.now()
+
+	s.og.mu.Lock()
+	if e, ok := s.og.entries[name]; ok && ts.Before(e.expires) {
+		s.og.mu.Unlock()
+		return e.png, nil
+	}
+	s.og.mu.Unlock()
+
+	var lines []string
+	entries, value := s.publicBoard()
+	rank, entry, ok := rankIn(entries, name, rankingPositional, value)
+	if ok {
+		lines = []string{entry.Name, "Score " + formatScore(entry.Score), fmt.Sprintf("Rank #%d on Flappy Gopher", rank)}
+	} else {
+		name = ""
+		lines = []string{"Flappy Gopher", "Can you beat the leaderboard?", "Play now!"}
+	}
+
+	img, err := renderOGImage(lines, []float64{96, 64, 40})
+	if err != nil {
+		return nil, err
+	}
+
+	s.og.mu.Lock()
+	defer s.og.mu.Unlock()
+	if len(s.og.entries) >= ogCacheMaxEntries {
+		for k, e := range s.og.entries {
+			if !ts.Before(e.expires) {
+				delete(s.og.entries, k)
+			}
+		}
+		if len(s.og.entries) >= ogCacheMaxEntries {
+			clear(s.og.entries)
+		}
+	}
+	s.og.entries[name] = ogCacheEntry{png: img, expires: ts.Add(ogCacheTTL)}
+	return img, nil
+}
+
+// handleOGImage handles GET /api/og/:name.png
+func (s *Server) handleOGImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	name, ok := strings.CutSuffix(ps.ByName("name"), ".png")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	img, err := s.ogImageFor(name)
+	if err != nil {
+		loggerFrom(r.Context()).Error("failed to render preview image", "name", name, "err", err)
+		http.Error(w, "Failed to render image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ogCacheTTL.Seconds())))
+	w.Write(img)
+}
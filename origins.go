@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originPolicy decides which browser origins may open streaming
+// connections. A nil policy only admits same-host origins.
+type originPolicy struct {
+	any     bool
+	origins map[string]bool
+}
+
+// parseAllowedOrigins parses origins such as "https://example.com" or
+// "http://localhost:8080"; "*" admits every origin. An empty list yields a
+// nil policy.
+func parseAllowedOrigins(list []string) (*originPolicy, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	p := &originPolicy{origins: make(map[string]bool, len(list))}
+	for _, item := range list {
+		if item == "*" {
+			p.any = true
+			continue
+		}
+		u, err := url.Parse(item)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+			return nil, fmt.Errorf("invalid allowed origin %q: must be scheme://host[:port] or *", item)
+		}
+		p.origins[u.Scheme+"://"+strings.ToLower(u.Host)] = true
+	}
+	return p, nil
+}
+
+// WithAllowedOrigins restricts which origins may open WebSocket
+// connections; nil keeps the same-host check
+func WithAllowedOrigins(p *originPolicy) Option {
+	return func(s *Server) { s.origins = p }
+}
+
+// originAllowed reports whether r's Origin header passes the policy.
+// Requests without one don't come from a browser and are let through.
+func (s *Server) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if s.origins == nil {
+		return strings.EqualFold(u.Host, r.Host)
+	}
+	return s.origins.any || s.origins.origins[strings.ToLower(u.Scheme)+"://"+strings.ToLower(u.Host)]
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestGameSocketOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		// origin is the Origin header sent; "self" is the server's own
+		origin string
+		ok     bool
+	}{
+		{name: "same host by default", origin: "self", ok: true},
+		{name: "other host by default", origin: "https://evil.example", ok: false},
+		{name: "no origin", origin: "", ok: true},
+		{name: "listed origin", allowed: []string{"https://game.example"}, origin: "https://game.example", ok: true},
+		{name: "listed origin in another case", allowed: []string{"https://game.example"}, origin: "https://GAME.example", ok: true},
+		{name: "unlisted origin", allowed: []string{"https://game.example"}, origin: "https://evil.example", ok: false},
+		{name: "listed host on another scheme", allowed: []string{"https://game.example"}, origin: "http://game.example", ok: false},
+		{name: "listed host on another port", allowed: []string{"https://game.example"}, origin: "https://game.example:8443", ok: false},
+		{name: "own host once a list is set", allowed: []string{"https://game.example"}, origin: "self", ok: false},
+		{name: "any origin", allowed: []string{"*"}, origin: "https://evil.example", ok: true},
+		{name: "malformed origin", allowed: []string{"*"}, origin: "null", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parseAllowedOrigins(tt.allowed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewServer(testHandler(NewServer(WithAllowedOrigins(policy))))
+			defer srv.Close()
+
+			header := http.Header{}
+			switch tt.origin {
+			case "":
+			case "self":
+				header.Set("Origin", srv.URL)
+			default:
+				header.Set("Origin", tt.origin)
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/game", header)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("upgrade refused: %v", err)
+				}
+				conn.Close()
+				return
+			}
+			if err == nil {
+				conn.Close()
+				t.Fatal("upgrade succeeded, want it refused")
+			}
+			if resp == nil || resp.StatusCode != http.StatusForbidden {
+				t.Errorf("refused upgrade: %v, want a %d", err, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestParseAllowedOriginsInvalid(t *testing.T) {
+	for _, item := range []string{"game.example", "ftp://game.example", "https://game.example/path", "https://user@game.example", "https://game.example?x=1"} {
+		if _, err := parseAllowedOrigins([]string{item}); err == nil {
+			t.Errorf("parseAllowedOrigins accepted %q", item)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultPlayersLimit = 20
+	maxPlayersLimit     = 100
+)
+
+// playerSet returns every distinct player name. lb.mu must be held.
+func (lb *Leaderboard) playerSet() map[string]struct{} {
+	seen := make(map[string]struct{}, len(lb.lastSubmit))
+	for name := range lb.lastSubmit {
+		seen[name] = struct{}{}
+	}
+	// A follower only knows the names on the board it mirrors
+	for _, entry := range lb.entries {
+		seen[entry.Name] = struct{}{}
+	}
+	return seen
+}
+
+// PlayerCount returns how many distinct players have been recorded
+func (lb *Leaderboard) PlayerCount() int {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	// Only board names missing from lastSubmit (as on a follower) need the
+	// extra set, which keeps this cheap on every leaderboard read
+	extra := make(map[string]struct{})
+	for _, entry := range lb.entries {
+		if _, ok := lb.lastSubmit[entry.Name]; !ok {
+			extra[entry.Name] = struct{}{}
+		}
+	}
+	return len(lb.lastSubmit) + len(extra)
+}
+
+// Players returns every distinct player name starting with prefix, compared
+// case-insensitively, sorted alphabetically
+func (lb *Leaderboard) Players(prefix string) []string {
+	prefix = strings.ToLower(prefix)
+
+	lb.mu.RLock()
+	seen := lb.playerSet()
+	lb.mu.RUnlock()
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		if strings.HasPrefix(strings.ToLower(name), prefix) {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(strings.ToLower(a), strings.ToLower(b)), cmp.Compare(a, b))
+	})
+	return names
+}
+
+// queryInt parses the query parameter key as an integer in [lo, hi],
+// returning def when it is absent
+func queryInt(r *http.Request, key string, def, lo, hi int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%s must be between %d and %d", key, lo, hi)
+	}
+	return n, nil
+}
+
+// handleListPlayers handles GET /api/players?q=<prefix>&limit=&offset=
+func (s *Server) handleListPlayers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	limit, err := queryInt(r, "limit", defaultPlayersLimit, 1, maxPlayersLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0, 0, math.MaxInt32)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	names := s.lb.Players(r.URL.Query().Get("q"))
+	total := len(names)
+	// Clamped before adding, as offset+limit can overflow a 32-bit int
+	lo := min(offset, total)
+	hi := lo + min(limit, total-lo)
+	page := capEntries(s, w, names[lo:hi])
+
+	resp := map[string]any{"players": page, "total": total}
+	// A page cut by the response cap resumes where the cut was made
+	if next := offset + len(page); next < total {
+		resp["nextOffset"] = next
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// PlayerBests is a player's all-time best and their best of the current
+// UTC day, nil when they haven't played today
+type PlayerBests struct {
+	AllTime *Score `json:"allTime"`
+	Today   *Score `json:"today"`
+}
+
+// Bests returns name's best retained score overall and since the start of
+// the UTC day containing now, leaving out scores hidden by a freeze at
+// frozenAt. ok is false for a player with no scores.
+func (lb *Leaderboard) Bests(name string, now, frozenAt time.Time) (bests PlayerBests, ok bool) {
+	y, m, d := now.UTC().Date()
+	dayStart := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	consider := func(e Score) {
+		if e.Name != name || hiddenByFreeze(e.Timestamp, frozenAt) {
+			return
+		}
+		if bests.AllTime == nil || e.Score > bests.AllTime.Score {
+			bests.AllTime = &e
+		}
+		if !e.Timestamp.Before(dayStart) && (bests.Today == nil || e.Score > bests.Today.Score) {
+			bests.Today = &e
+		}
+	}
+	for _, e := range lb.history {
+		consider(e)
+	}
+	// The board can hold entries older than the retained history, or be
+	// all a follower knows
+	for _, e := range lb.entries {
+		consider(e)
+	}
+	return bests, bests.AllTime != nil
+}
+
+// handleGetPlayerBests handles GET /api/players/:name/bests
+func (s *Server) handleGetPlayerBests(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	bests, ok := s.lb.Bests(ps.ByName("name"), s.now(), s.freeze.FrozenAt())
+	if !ok {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, bests)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTotalPlayers(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		submissions []string
+		want        int
+	}{
+		{name: "no submissions", size: 10, want: 0},
+		{name: "distinct players", size: 10, submissions: []string{"ann", "bob", "cat"}, want: 3},
+		{name: "repeat submissions", size: 10, submissions: []string{"ann", "bob", "ann", "ann", "bob"}, want: 2},
+		{name: "players off the board", size: 1, submissions: []string{"ann", "bob", "cat", "bob"}, want: 3},
+		{name: "names differing in case", size: 10, submissions: []string{"ann", "Ann", "ANN"}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size = tt.size
+			h := testHandler(NewServer(WithLeaderboard(lb)))
+			for i, name := range tt.submissions {
+				body, _ := json.Marshal(map[string]any{"name": name, "score": i})
+				if rec := do(h, http.MethodPost, "/api/scores", string(body)); rec.Code != http.StatusCreated {
+					t.Fatalf("submit %s: status %d", name, rec.Code)
+				}
+			}
+
+			var resp struct {
+				TotalPlayers int `json:"totalPlayers"`
+			}
+			if err := json.Unmarshal(do(h, http.MethodGet, "/api/leaderboard?envelope=true", "").Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.TotalPlayers != tt.want {
+				t.Errorf("totalPlayers = %d, want %d", resp.TotalPlayers, tt.want)
+			}
+			if got := len(lb.Players("")); got != tt.want {
+				t.Errorf("Players lists %d names, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlayerCountFollower(t *testing.T) {
+	// A follower's board names count even without their submissions
+	lb := NewLeaderboard()
+	lb.entries = []Score{{ID: 1, Name: "ann", Score: 3}, {ID: 2, Name: "bob", Score: 2}}
+	lb.lastSubmit["bob"] = lb.now()
+	if got := lb.PlayerCount(); got != 2 {
+		t.Errorf("PlayerCount() = %d, want 2", got)
+	}
+}
+
+func TestPlayerBests(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	type play struct {
+		at    time.Time
+		name  string
+		score float64
+	}
+	tests := []struct {
+		name   string
+		plays  []play
+		now    time.Time
+		status int
+		// allTime and today are the expected bests; a negative today means
+		// none today
+		allTime float64
+		today   float64
+	}{
+		{
+			name: "played today and earlier",
+			plays: []play{
+				{day.Add(-4 * time.Hour), "ann", 50},
+				{day.Add(9 * time.Hour), "ann", 20},
+				{day.Add(10 * time.Hour), "bob", 90},
+				{day.Add(11 * time.Hour), "ann", 10},
+			},
+			now:     day.Add(12 * time.Hour),
+			status:  http.StatusOK,
+			allTime: 50,
+			today:   20,
+		},
+		{
+			name:    "no plays today",
+			plays:   []play{{day.Add(-14 * time.Hour), "ann", 30}, {day.Add(time.Hour), "bob", 5}},
+			now:     day.Add(12 * time.Hour),
+			status:  http.StatusOK,
+			allTime: 30,
+			today:   -1,
+		},
+		{
+			name:    "just after midnight",
+			plays:   []play{{day.Add(-time.Millisecond), "ann", 40}, {day, "ann", 15}},
+			now:     day.Add(time.Second),
+			status:  http.StatusOK,
+			allTime: 40,
+			today:   15,
+		},
+		{
+			name:    "just before midnight",
+			plays:   []play{{day.Add(-2 * time.Hour), "ann", 40}},
+			now:     day.Add(-time.Millisecond),
+			status:  http.StatusOK,
+			allTime: 40,
+			today:   40,
+		},
+		{
+			name:    "day taken in UTC",
+			plays:   []play{{day.Add(-2 * time.Hour), "ann", 25}},
+			now:     day.Add(-time.Hour).In(time.FixedZone("UTC+9", 9*60*60)),
+			status:  http.StatusOK,
+			allTime: 25,
+			today:   25,
+		},
+		{
+			name:   "unknown player",
+			plays:  []play{{day, "bob", 5}},
+			now:    day.Add(time.Hour),
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var now time.Time
+			h := testHandler(NewServer(WithClock(func() time.Time { return now })))
+			for _, p := range tt.plays {
+				now = p.at
+				if rec := do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":%q,"score":%v}`, p.name, p.score)); rec.Code != http.StatusCreated {
+					t.Fatalf("submit %s: status %d", p.name, rec.Code)
+				}
+			}
+
+			now = tt.now
+			rec := do(h, http.MethodGet, "/api/players/ann/bests", "")
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var bests PlayerBests
+			if err := json.Unmarshal(rec.Body.Bytes(), &bests); err != nil {
+				t.Fatal(err)
+			}
+			if bests.AllTime == nil || bests.AllTime.Score != tt.allTime {
+				t.Errorf("allTime = %+v, want %v", bests.AllTime, tt.allTime)
+			}
+			switch {
+			case tt.today < 0 && !strings.Contains(rec.Body.String(), `"today":null`):
+				t.Errorf("body %s, want \"today\": null", rec.Body)
+			case tt.today >= 0 && (bests.Today == nil || bests.Today.Score != tt.today):
+				t.Errorf("today = %+v, want %v", bests.Today, tt.today)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// pollJitter is the fraction the poll hint may vary either side of the
+// base interval
+const pollJitter = 0.2
+
+// pollAfter returns a next-poll delay spread randomly around the base
+// interval so polling clients drift apart instead of arriving together
+func (s *Server) pollAfter() time.Duration {
+	if s.pollInterval <= 0 {
+		return 0
+	}
+	factor := 1 + pollJitter*(2*rand.Float64()-1)
+	return time.Duration(float64(s.pollInterval) * factor).Round(time.Millisecond)
+}
+
+// formatSeconds renders d as seconds with millisecond precision
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
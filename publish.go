@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// submissionTopic is the topic accepted submissions are published on
+	submissionTopic = "submission.accepted"
+	// publishQueueSize bounds how many messages wait for the publisher
+	// before new ones are dropped
+	publishQueueSize = 1024
+	// publishFlushInterval is how often a batching publisher is flushed
+	publishFlushInterval = 2 * time.Second
+	// webhookBatchSize is how many messages a webhook POST carries at most
+	webhookBatchSize = 100
+	// webhookTimeout bounds a single webhook POST
+	webhookTimeout = 10 * time.Second
+)
+
+// Publisher forwards submission outcomes to an external system
+type Publisher interface {
+	Publish(topic string, payload []byte) error
+}
+
+// flusher is implemented by publishers that batch messages
+type flusher interface {
+	Flush() error
+}
+
+// nopPublisher discards every message
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(string, []byte) error { return nil }
+
+// publishedMessage is a topic and payload waiting to be published
+type publishedMessage struct {
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// asyncPublisher hands messages to a Publisher on its own goroutine so the
+// request path never waits on it. Messages arriving while the queue is
+// full are dropped.
+type asyncPublisher struct {
+	p       Publisher
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	// mu guards ch against sends after Close
+	mu     sync.RWMutex
+	ch     chan publishedMessage
+	closed bool
+}
+
+func newAsyncPublisher(p Publisher) *asyncPublisher {
+	ap := &asyncPublisher{
+		p:    p,
+		ch:   make(chan publishedMessage, publishQueueSize),
+		done: make(chan struct{}),
+	}
+	go ap.run()
+	return ap
+}
+
+// Publish queues payload on topic and reports false if it was dropped
+func (ap *asyncPublisher) Publish(topic string, payload []byte) bool {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+
+	if ap.closed {
+		ap.dropped.Add(1)
+		return false
+	}
+	select {
+	case ap.ch <- publishedMessage{Topic: topic, Payload: payload}:
+		return true
+	default:
+		ap.dropped.Add(1)
+		return false
+	}
+}
+
+func (ap *asyncPublisher) run() {
+	defer close(ap.done)
+
+	ticker := time.NewTicker(publishFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-ap.ch:
+			if !ok {
+				ap.flush()
+				return
+			}
+			if err := ap.p.Publish(msg.Topic, msg.Payload); err != nil {
+				slog.Warn("failed to publish message", "topic", msg.Topic, "err", err)
+			}
+		case <-ticker.C:
+			ap.flush()
+		}
+	}
+}
+
+func (ap *asyncPublisher) flush() {
+	if f, ok := ap.p.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			slog.Warn("failed to flush publisher", "err", err)
+		}
+	}
+}
+
+// Close stops accepting messages and waits for queued ones to be
+// published, giving up when ctx is done
+func (ap *asyncPublisher) Close(ctx context.Context) error {
+	ap.mu.Lock()
+	if !ap.closed {
+		ap.closed = true
+		close(ap.ch)
+	}
+	ap.mu.Unlock()
+
+	select {
+	case <-ap.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// webhookPublisher POSTs messages to a URL as JSON arrays of up to
+// webhookBatchSize {topic, payload} objects
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+	batch  []publishedMessage
+}
+
+// NewWebhookPublisher returns a Publisher batching messages to url. It is
+// not safe for concurrent use; WithPublisher serializes calls to it.
+func NewWebhookPublisher(url string) Publisher {
+	return &webhookPublisher{url: url, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (wp *webhookPublisher) Publish(topic string, payload []byte) error {
+	wp.batch = append(wp.batch, publishedMessage{Topic: topic, Payload: payload})
+	if len(wp.batch) >= webhookBatchSize {
+		return wp.Flush()
+	}
+	return nil
+}
+
+// Flush POSTs the pending batch. A failed batch is dropped rather than
+// retried so a dead endpoint can't grow it without bound.
+func (wp *webhookPublisher) Flush() error {
+	if len(wp.batch) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(wp.batch)
+	wp.batch = wp.batch[:0]
+	if err != nil {
+		return err
+	}
+
+	resp, err := wp.client.Post(wp.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SubmissionOutcome is the payload published for each accepted submission
+type SubmissionOutcome struct {
+	SubmissionEvent
+	Placed bool `json:"placed"`
+}
+
+// publishSubmission queues ev for the configured publisher
+func (s *Server) publishSubmission(ev SubmissionEvent, placed bool) {
+	if s.publisher == nil {
+		return
+	}
+	payload, err := json.Marshal(SubmissionOutcome{SubmissionEvent: ev, Placed: placed})
+	if err != nil {
+		slog.Error("failed to encode submission outcome", "name", ev.Name, "err", err)
+		return
+	}
+	if !s.publisher.Publish(submissionTopic, payload) {
+		slog.Warn("publish queue full, dropping submission outcome", "name", ev.Name, "dropped", s.publisher.dropped.Load())
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePublisher records every message it is given
+type fakePublisher struct {
+	mu       sync.Mutex
+	messages []publishedMessage
+	// block, when set, holds every Publish until it is closed
+	block chan struct{}
+}
+
+func (fp *fakePublisher) Publish(topic string, payload []byte) error {
+	if fp.block != nil {
+		<-fp.block
+	}
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.messages = append(fp.messages, publishedMessage{Topic: topic, Payload: payload})
+	return nil
+}
+
+func (fp *fakePublisher) outcomes(t *testing.T) []SubmissionOutcome {
+	t.Helper()
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	var out []SubmissionOutcome
+	for _, msg := range fp.messages {
+		if msg.Topic != submissionTopic {
+			t.Errorf("published on topic %q, want %q", msg.Topic, submissionTopic)
+		}
+		var o SubmissionOutcome
+		if err := json.Unmarshal(msg.Payload, &o); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+func TestPublishSubmissions(t *testing.T) {
+	type outcome struct {
+		name   string
+		score  float64
+		placed bool
+	}
+	tests := []struct {
+		name        string
+		submissions []string
+		want        []outcome
+	}{
+		{
+			name:        "accepted submissions",
+			submissions: []string{`{"name":"ann","score":10,"mode":"hard"}`, `{"name":"bob","score":20}`},
+			want:        []outcome{{"ann", 10, true}, {"bob", 20, true}},
+		},
+		{
+			name:        "recorded but off the board",
+			submissions: []string{`{"name":"ann","score":10}`, `{"name":"bob","score":5}`},
+			want:        []outcome{{"ann", 10, true}, {"bob", 5, false}},
+		},
+		{
+			name:        "rejected submissions",
+			submissions: []string{`{"name":"ann","score":-1}`, `{"name":"","score":3}`, `{"name":"bob","score":3}`},
+			want:        []outcome{{"bob", 3, true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := &fakePublisher{}
+			lb := NewLeaderboard()
+			lb.size = 1
+			s := NewServer(WithLeaderboard(lb), WithPublisher(fp))
+			h := testHandler(s)
+			for _, body := range tt.submissions {
+				do(h, http.MethodPost, "/api/scores", body)
+			}
+			if err := s.publisher.Close(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := fp.outcomes(t)
+			if len(got) != len(tt.want) {
+				t.Fatalf("published %d outcomes, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got[i].Name != want.name || got[i].Score != want.score || got[i].Placed != want.placed {
+					t.Errorf("outcome %d = %+v, want %+v", i, got[i], want)
+				}
+				if got[i].Mode == "" || got[i].Timestamp.IsZero() || got[i].RequestID == "" {
+					t.Errorf("outcome %d lacks mode, time or request ID: %+v", i, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPublishDoesNotBlockSubmissions(t *testing.T) {
+	fp := &fakePublisher{block: make(chan struct{})}
+	s := NewServer(WithPublisher(fp))
+	h := testHandler(s)
+
+	start := time.Now()
+	for i := range 10 {
+		if rec := do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":"p%d","score":%d}`, i, i)); rec.Code != http.StatusCreated {
+			t.Fatalf("submit: status %d", rec.Code)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("submissions took %v behind a stuck publisher", elapsed)
+	}
+
+	close(fp.block)
+	if err := s.publisher.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(fp.outcomes(t)); got != 10 {
+		t.Errorf("published %d outcomes once unblocked, want 10", got)
+	}
+}
+
+func TestWebhookPublisherBatches(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]publishedMessage
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []publishedMessage
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	ap := newAsyncPublisher(NewWebhookPublisher(srv.URL))
+	for i := range webhookBatchSize + 5 {
+		ap.Publish(submissionTopic, fmt.Appendf(nil, `{"n":%d}`, i))
+	}
+	if err := ap.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 2 || len(batches[0]) != webhookBatchSize || len(batches[1]) != 5 {
+		sizes := []int{}
+		for _, b := range batches {
+			sizes = append(sizes, len(b))
+		}
+		t.Errorf("webhook got batches of %v, want [%d 5]", sizes, webhookBatchSize)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// PurgeScores removes every recorded submission with a score in
+// [minScore, maxScore] and re-ranks the board from what remains. It
+// returns how many submissions were removed.
+func (lb *Leaderboard) PurgeScores(minScore, maxScore float64) int {
+	return lb.removeScores(func(e Score) bool { return e.Score >= minScore && e.Score <= maxScore })
+}
+
+// removeScores removes every recorded submission matching remove and
+// re-ranks the board from what remains, returning how many were removed
+func (lb *Leaderboard) removeScores(remove func(Score) bool) int {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	before := len(lb.history)
+	lb.history = slices.DeleteFunc(lb.history, remove)
+	removed := before - len(lb.history)
+
+	// Re-rank from the surviving board plus the history since the last
+	// reset, so entries the purged scores had pushed off come back
+	seen := make(map[Score]bool)
+	var candidates []Score
+	add := func(e Score) {
+		if !seen[e] && !remove(e) && e.Score >= float64(lb.minDisplayScore) {
+			seen[e] = true
+			candidates = append(candidates, e)
+		}
+	}
+	for _, e := range lb.entries {
+		add(e)
+	}
+	for _, e := range lb.history {
+		if !e.Timestamp.Before(lb.boardSince) {
+			add(e)
+		}
+	}
+
+	now := lb.now()
+	value := lb.rankValue(now)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return value(candidates[i]) > value(candidates[j])
+	})
+	if lb.dedup {
+		best := make(map[string]bool, len(candidates))
+		candidates = slices.DeleteFunc(candidates, func(e Score) bool {
+			dup := best[e.Name]
+			best[e.Name] = true
+			return dup
+		})
+	}
+	candidates = trimBoard(candidates, lb.Size())
+
+	lb.rankedAt = now
+	if !slices.Equal(candidates, lb.entries) {
+		lb.entries = candidates
+		lb.recordChange(BoardRemove)
+	}
+	return removed
+}
+
+// handlePurgeScores handles POST /api/admin/scores/purge
+func (s *Server) handlePurgeScores(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req struct {
+		MinScore *float64 `json:"minScore"`
+		MaxScore *float64 `json:"maxScore"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.MinScore == nil || req.MaxScore == nil {
+		http.Error(w, "minScore and maxScore are required", http.StatusUnprocessableEntity)
+		return
+	}
+	if *req.MinScore > *req.MaxScore {
+		http.Error(w, "minScore must not exceed maxScore", http.StatusUnprocessableEntity)
+		return
+	}
+
+	removed := s.lb.PurgeScores(*req.MinScore, *req.MaxScore)
+
+	logger := loggerFrom(r.Context())
+	logger.Info("admin purged scores", "min", *req.MinScore, "max", *req.MaxScore, "removed", removed)
+	s.auditLog(r, "purge", "", map[string]any{"minScore": *req.MinScore, "maxScore": *req.MaxScore, "removed": removed})
+
+	if removed > 0 {
+		// Logged so warm-up doesn't bring the purged scores back
+		ev := SubmissionEvent{Type: eventPurge, Timestamp: s.now(), RequestID: requestIDFrom(r.Context()), MinScore: req.MinScore, MaxScore: req.MaxScore}
+		if err := s.events.Record(ev); err != nil {
+			logger.Error("failed to record purge event", "min", *req.MinScore, "max", *req.MaxScore, "err", err)
+		}
+	}
+	if removed > 0 && s.backupInterval > 0 {
+		if _, err := s.backupNow(); err != nil {
+			logger.Error("failed to persist board after purge", "dir", s.backupDir, "err", err)
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]any{"status": "success", "removed": removed})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// defaultPushJob is the job label metrics are pushed under
+const defaultPushJob = "flappy_gopher"
+
+// pushTimeout bounds a single push to the Pushgateway
+const pushTimeout = 10 * time.Second
+
+// runMetricsPush pushes the server's metrics to the Pushgateway at url
+// under job every interval until ctx is cancelled, then pushes once more
+// so the gateway keeps the final values. A failed push is logged and
+// retried on the next tick.
+func (s *Server) runMetricsPush(ctx context.Context, url, job string, interval time.Duration) {
+	pusher := push.New(url, job).Gatherer(s.metrics.registry)
+	pushOnce := func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+		defer cancel()
+		if err := pusher.PushContext(ctx); err != nil {
+			slog.Warn("failed to push metrics", "url", url, "job", job, "err", err)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			pushOnce(context.Background())
+			return
+		case <-ticker.C:
+			pushOnce(ctx)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMetricsPush(t *testing.T) {
+	tests := []struct {
+		name string
+		job  string
+		// fail is how many pushes the gateway rejects before accepting
+		fail int
+	}{
+		{name: "default job", job: defaultPushJob},
+		{name: "custom job", job: "flappy_eu"},
+		{name: "retried after failures", job: defaultPushJob, fail: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pushes := make(chan string, 100)
+			failed := 0
+			gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if failed < tt.fail {
+					failed++
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				pushes <- r.Method + " " + r.URL.Path
+			}))
+			defer gw.Close()
+
+			s := NewServer()
+			ctx, cancel := context.WithCancel(t.Context())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				s.runMetricsPush(ctx, gw.URL, tt.job, 5*time.Millisecond)
+			}()
+
+			select {
+			case got := <-pushes:
+				if want := "PUT /metrics/job/" + tt.job; got != want {
+					t.Errorf("push = %q, want %q", got, want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no push reached the gateway")
+			}
+
+			cancel()
+			<-done
+		})
+	}
+}
+
+func TestMetricsPushOnShutdown(t *testing.T) {
+	pushes := make(chan string, 10)
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushes <- r.URL.Path
+	}))
+	defer gw.Close()
+
+	// No tick comes within the test, so only the final push goes out
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	NewServer().runMetricsPush(ctx, gw.URL, defaultPushJob, time.Hour)
+	if len(pushes) != 1 {
+		t.Fatalf("got %d pushes on shutdown, want 1", len(pushes))
+	}
+	if got := <-pushes; got != "/metrics/job/"+defaultPushJob {
+		t.Errorf("final push to %q, want the %s job", got, defaultPushJob)
+	}
+}
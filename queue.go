@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// errQueueFull is returned by submitQueue.Submit when no slot is free
+	errQueueFull = errors.New("submission queue full")
+	// errQueueClosed is returned by submitQueue.Submit after Close
+	errQueueClosed = errors.New("submission queue closed")
+)
+
+// submission is a score waiting to be applied by the queue worker
+type submission struct {
+	ctx   context.Context
+	name  string
+	score float64
+	done  chan submissionResult
+}
+
+// submissionResult is what the worker reports back for a submission
+type submissionResult struct {
+	placed bool
+	err    error
+}
+
+// submitQueue serializes leaderboard writes through a single worker
+// goroutine so concurrent submissions don't contend on the write lock
+type submitQueue struct {
+	done chan struct{}
+
+	// mu guards ch against sends after Close
+	mu     sync.RWMutex
+	ch     chan submission
+	closed bool
+}
+
+// newSubmitQueue starts a worker applying queued submissions to store
+// until Close. At most size submissions may be waiting at once.
+func newSubmitQueue(store Store, size int) *submitQueue {
+	q := &submitQueue{ch: make(chan submission, size), done: make(chan struct{})}
+	go func() {
+		defer close(q.done)
+		for s := range q.ch {
+			if err := s.ctx.Err(); err != nil {
+				s.done <- submissionResult{false, err}
+				continue
+			}
+			placed, err := store.Add(s.ctx, s.name, s.score)
+			s.done <- submissionResult{placed, err}
+		}
+	}()
+	return q
+}
+
+// Submit enqueues a score, waits until it has been applied and reports
+// whether it placed on the board. It fails with errQueueFull, without
+// blocking, if the queue is full. It stops waiting with ctx's error once
+// ctx is done, and the worker then drops the submission if it hasn't
+// reached it yet. After Close it fails with errQueueClosed.
+func (q *submitQueue) Submit(ctx context.Context, name string, score float64) (placed bool, err error) {
+	s := submission{ctx: ctx, name: name, score: score, done: make(chan submissionResult, 1)}
+	q.mu.RLock()
+	if q.closed {
+		err = errQueueClosed
+	} else {
+		select {
+		case q.ch <- s:
+		default:
+			err = errQueueFull
+		}
+	}
+	q.mu.RUnlock()
+	if err != nil {
+		return false, err
+	}
+	select {
+	case res := <-s.done:
+		return res.placed, res.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Close stops accepting submissions and waits for the worker to apply the
+// queued ones and exit, giving up when ctx is done
+func (q *submitQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// submitScore applies a score through the queue when one is configured,
+// reporting whether it made the board
+func (s *Server) submitScore(ctx context.Context, name string, score float64) (placed bool, err error) {
+	if s.queue == nil {
+		return s.store.Add(ctx, name, score)
+	}
+	return s.queue.Submit(ctx, name, score)
+}
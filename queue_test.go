@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingStore is a fake store whose writes wait until release is closed
+type blockingStore struct {
+	started chan struct{}
+	release chan struct{}
+	added   atomic.Int64
+}
+
+func newBlockingStore() *blockingStore {
+	return &blockingStore{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (bs *blockingStore) Add(ctx context.Context, name string, score float64) (bool, error) {
+	bs.started <- struct{}{}
+	select {
+	case <-bs.release:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	bs.added.Add(1)
+	return true, nil
+}
+
+func (bs *blockingStore) Top(n int) []Score { return nil }
+
+// fillQueue occupies q's worker and every free slot, returning a channel
+// receiving each of those submissions' errors once they are applied
+func fillQueue(t *testing.T, q *submitQueue, store *blockingStore) chan error {
+	t.Helper()
+	results := make(chan error, cap(q.ch)+1)
+	submit := func(name string) {
+		go func() {
+			_, err := q.Submit(t.Context(), name, 1)
+			results <- err
+		}()
+	}
+	submit("busy")
+	<-store.started
+	for i := range cap(q.ch) {
+		submit(fmt.Sprint("waiting", i))
+	}
+	for len(q.ch) < cap(q.ch) {
+		time.Sleep(time.Millisecond)
+	}
+	return results
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	for _, size := range []int{1, 4} {
+		t.Run(fmt.Sprint("size ", size), func(t *testing.T) {
+			store := newBlockingStore()
+			q := newSubmitQueue(store, size)
+			results := fillQueue(t, q, store)
+
+			if _, err := q.Submit(t.Context(), "late", 1); !errors.Is(err, errQueueFull) {
+				t.Fatalf("Submit on a full queue = %v, want errQueueFull", err)
+			}
+
+			close(store.release)
+			for range size + 1 {
+				if err := <-results; err != nil {
+					t.Errorf("queued Submit = %v, want nil", err)
+				}
+			}
+			if n := store.added.Load(); n != int64(size+1) {
+				t.Errorf("store got %d writes, want %d", n, size+1)
+			}
+			if err := q.Close(t.Context()); err != nil {
+				t.Errorf("Close = %v", err)
+			}
+		})
+	}
+}
+
+func TestSubmitQueueFullResponse(t *testing.T) {
+	s := NewServer()
+	store := newBlockingStore()
+	s.queue = newSubmitQueue(store, 1)
+	results := fillQueue(t, s.queue, store)
+	defer func() {
+		close(store.release)
+		for range 2 {
+			<-results
+		}
+		s.queue.Close(t.Context())
+	}()
+
+	rec := do(testHandler(s), http.MethodPost, "/api/scores", `{"name":"ann","score":1}`)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestSubmitQueueCancelledWhileWaiting(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    func(context.Context) (context.Context, context.CancelFunc)
+		status int
+	}{
+		{
+			name: "deadline passes in the queue",
+			ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				return context.WithTimeout(ctx, 20*time.Millisecond)
+			},
+			status: http.StatusGatewayTimeout,
+		},
+		{
+			name: "client goes away in the queue",
+			ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(ctx)
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			status: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			store := newBlockingStore()
+			s.queue = newSubmitQueue(store, 4)
+
+			// A slow write holds the worker, parking the request behind it
+			busy := make(chan error, 1)
+			go func() {
+				_, err := s.queue.Submit(t.Context(), "busy", 1)
+				busy <- err
+			}()
+			<-store.started
+
+			ctx, cancel := tt.ctx(t.Context())
+			defer cancel()
+			req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/scores", strings.NewReader(`{"name":"ann","score":10}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			testHandler(s).ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			close(store.release)
+			if err := <-busy; err != nil {
+				t.Fatalf("slow write = %v, want nil", err)
+			}
+			// Queued after the abandoned submission, so applied after the
+			// worker has dropped it
+			if _, err := s.queue.Submit(t.Context(), "later", 1); err != nil {
+				t.Fatal(err)
+			}
+			if n := store.added.Load(); n != 2 {
+				t.Errorf("store got %d writes, want 2 without the abandoned one", n)
+			}
+			if got := s.stats.Stats().Count; got != 0 {
+				t.Errorf("stats count = %d, want the abandoned write left out", got)
+			}
+			if err := s.queue.Close(t.Context()); err != nil {
+				t.Errorf("Close = %v", err)
+			}
+		})
+	}
+}
+
+func TestSubmitQueueClose(t *testing.T) {
+	store := newBlockingStore()
+	q := newSubmitQueue(store, 4)
+	results := fillQueue(t, q, store)
+
+	// The worker is stuck on a write, so Close gives up with ctx
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close with a stuck worker = %v, want context.DeadlineExceeded", err)
+	}
+	if _, err := q.Submit(t.Context(), "late", 1); !errors.Is(err, errQueueClosed) {
+		t.Errorf("Submit after Close = %v, want errQueueClosed", err)
+	}
+
+	// Once unstuck, the worker applies what was queued before it exits
+	close(store.release)
+	if err := q.Close(t.Context()); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+	for range 5 {
+		if err := <-results; err != nil {
+			t.Errorf("queued Submit = %v, want nil", err)
+		}
+	}
+	if n := store.added.Load(); n != 5 {
+		t.Errorf("store got %d writes, want 5", n)
+	}
+}
+
+func TestServerShutdownStopsQueue(t *testing.T) {
+	s := NewServer(WithQueueSize(4))
+	if rec := do(testHandler(s), http.MethodPost, "/api/scores", `{"name":"ann","score":1}`); rec.Code != http.StatusCreated {
+		t.Fatalf("submit: status %d", rec.Code)
+	}
+	if err := s.Shutdown(t.Context()); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	select {
+	case <-s.queue.done:
+	default:
+		t.Error("queue worker still running after Shutdown")
+	}
+}
+
+// BenchmarkSubmit compares writing under the leaderboard lock with writing
+// through the queue, from many goroutines at once
+func BenchmarkSubmit(b *testing.B) {
+	for _, size := range []int{0, 1024} {
+		name := "lock"
+		if size > 0 {
+			name = "queue"
+		}
+		b.Run(name, func(b *testing.B) {
+			s := NewServer(WithQueueSize(size))
+			if s.queue != nil {
+				defer s.queue.Close(context.Background())
+			}
+			var n atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := n.Add(1)
+					if _, err := s.submitScore(context.Background(), fmt.Sprint("p", i%500), float64(i%1000)); err != nil && !errors.Is(err, errQueueFull) {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+// How ?ranking= numbers entries that share a score
+const (
+	// rankingPositional numbers entries by board position, so tied scores
+	// get distinct ranks in submission order (1, 2, 3, 4)
+	rankingPositional = "positional"
+	// rankingStandard is competition ranking: tied scores share the best
+	// rank among them and the next score skips ahead (1, 2, 2, 4)
+	rankingStandard = "standard"
+)
+
+var rankings = []string{rankingPositional, rankingStandard}
+
+// rankingParam reads ?ranking=, defaulting to positional, and replies 400
+// if it names an unknown scheme
+func rankingParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	ranking := r.URL.Query().Get("ranking")
+	if ranking == "" {
+		return rankingPositional, true
+	}
+	if !slices.Contains(rankings, ranking) {
+		http.Error(w, "Ranking must be positional or standard", http.StatusBadRequest)
+		return "", false
+	}
+	return ranking, true
+}
+
+// rankAt returns the 1-based rank of entries[i] under ranking. entries
+// must be sorted best first by value.
+func rankAt(entries []Score, i int, ranking string, value func(Score) float64) int {
+	if ranking == rankingStandard {
+		for i > 0 && value(entries[i-1]) == value(entries[i]) {
+			i--
+		}
+	}
+	return i + 1
+}
+
+// RankWith is Rank numbering tied scores according to ranking
+func (lb *Leaderboard) RankWith(name, ranking string) (int, Score, bool) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return rankIn(lb.ranked(), name, ranking, lb.rankValue(lb.now()))
+}
+
+// rankIn is RankWith over entries, which must be sorted best first by
+// value
+func rankIn(entries []Score, name, ranking string, value func(Score) float64) (int, Score, bool) {
+	for i, entry := range entries {
+		if entry.Name == name {
+			return rankAt(entries, i, ranking, value), entry, true
+		}
+	}
+	return 0, Score{}, false
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestRankAt(t *testing.T) {
+	// Submitted in this order, so ties stay in it
+	board := []Score{
+		{Name: "ann", Score: 40}, {Name: "bob", Score: 30}, {Name: "cat", Score: 30},
+		{Name: "dan", Score: 30}, {Name: "eve", Score: 10}, {Name: "fay", Score: 10},
+	}
+	tests := []struct {
+		ranking string
+		want    []int
+	}{
+		{rankingPositional, []int{1, 2, 3, 4, 5, 6}},
+		{rankingStandard, []int{1, 2, 2, 2, 5, 5}},
+	}
+	value := func(e Score) float64 { return e.Score }
+	for _, tt := range tests {
+		got := make([]int, len(board))
+		for i := range board {
+			got[i] = rankAt(board, i, tt.ranking, value)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s ranks = %v, want %v", tt.ranking, got, tt.want)
+		}
+	}
+}
+
+func TestRankingParam(t *testing.T) {
+	board := []string{`{"name":"ann","score":40}`, `{"name":"bob","score":30}`, `{"name":"cat","score":30}`, `{"name":"dan","score":10}`}
+	tests := []struct {
+		name  string
+		query string
+		bad   bool
+		// want is each player's rank from GET /api/rank/:name, once the
+		// tying score is in
+		want map[string]int
+		// submitted is the rank in the response to a new tying score
+		submitted int
+	}{
+		{name: "positional by default", want: map[string]int{"ann": 1, "bob": 2, "cat": 3, "eve": 4, "dan": 5}, submitted: 4},
+		{name: "positional", query: "?ranking=positional", want: map[string]int{"ann": 1, "bob": 2, "cat": 3, "eve": 4, "dan": 5}, submitted: 4},
+		{name: "standard", query: "?ranking=standard", want: map[string]int{"ann": 1, "bob": 2, "cat": 2, "eve": 2, "dan": 5}, submitted: 2},
+		{name: "unknown", query: "?ranking=dense", bad: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHandler(NewServer())
+			for _, body := range board {
+				do(h, http.MethodPost, "/api/scores", body)
+			}
+
+			// A new tying score ranks after the scores it ties
+			rec := do(h, http.MethodPost, "/api/scores"+tt.query, `{"name":"eve","score":30}`)
+			if tt.bad {
+				if rec.Code != http.StatusBadRequest {
+					t.Errorf("submit: status %d, want %d", rec.Code, http.StatusBadRequest)
+				}
+				if rec := do(h, http.MethodGet, "/api/rank/ann"+tt.query, ""); rec.Code != http.StatusBadRequest {
+					t.Errorf("rank: status %d, want %d", rec.Code, http.StatusBadRequest)
+				}
+				return
+			}
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("submit: status %d, want %d", rec.Code, http.StatusCreated)
+			}
+			var resp struct {
+				Rank int `json:"rank"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Rank != tt.submitted {
+				t.Errorf("submitted tying score ranked %d, want %d", resp.Rank, tt.submitted)
+			}
+
+			for name, want := range tt.want {
+				rec := do(h, http.MethodGet, fmt.Sprintf("/api/rank/%s%s", name, tt.query), "")
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Rank != want {
+					t.Errorf("%s ranked %d, want %d", name, resp.Rank, want)
+				}
+			}
+		})
+	}
+}
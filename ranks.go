@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// maxRankLookups caps the names in one POST /api/ranks
+	maxRankLookups = 100
+	// maxRankLookupBytes caps the body of POST /api/ranks
+	maxRankLookupBytes = 16 << 10
+)
+
+// RankLookup is one name's entry in a bulk rank lookup; Rank and Score
+// are null for a name not on the board
+type RankLookup struct {
+	Name  string   `json:"name"`
+	Rank  *int     `json:"rank"`
+	Score *float64 `json:"score"`
+}
+
+// Ranks looks up the board rank and best score of each name, in order,
+// under a single read of the board, numbering ties according to ranking
+func (lb *Leaderboard) Ranks(names []string, ranking string) []RankLookup {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return ranksIn(lb.ranked(), names, ranking, lb.rankValue(lb.now()))
+}
+
+// ranksIn is Ranks over entries, which must be sorted best first by value
+func ranksIn(entries []Score, names []string, ranking string, value func(Score) float64) []RankLookup {
+	// Entries are sorted, so a player's first entry is their best
+	first := make(map[string]int, len(entries))
+	for i, e := range entries {
+		if _, ok := first[e.Name]; !ok {
+			first[e.Name] = i
+		}
+	}
+
+	lookups := make([]RankLookup, len(names))
+	for i, name := range names {
+		lookups[i].Name = name
+		if j, ok := first[name]; ok {
+			rank, score := rankAt(entries, j, ranking, value), entries[j].Score
+			lookups[i].Rank, lookups[i].Score = &rank, &score
+		}
+	}
+	return lookups
+}
+
+// handleGetRanks handles POST /api/ranks, looking up the ranks of a JSON
+// array of names
+func (s *Server) handleGetRanks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ranking, ok := rankingParam(w, r)
+	if !ok {
+		return
+	}
+	var names []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRankLookupBytes)).Decode(&names); err != nil {
+		http.Error(w, "Request body must be a JSON array of names", http.StatusBadRequest)
+		return
+	}
+	if len(names) > maxRankLookups {
+		http.Error(w, fmt.Sprintf("At most %d names can be looked up at once", maxRankLookups), http.StatusUnprocessableEntity)
+		return
+	}
+	entries, value := s.publicBoard()
+	writeJSON(w, r, http.StatusOK, ranksIn(entries, names, ranking, value))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBulkRanks(t *testing.T) {
+	board := []string{`{"name":"ann","score":30}`, `{"name":"bob","score":20}`, `{"name":"cat","score":20}`, `{"name":"ann","score":5}`}
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+		// want is "name:rank:score" per lookup, "name:-" when unranked
+		want []string
+	}{
+		{
+			name:   "ranked and unranked in input order",
+			body:   `["cat","zed","ann","bob"]`,
+			status: http.StatusOK,
+			want:   []string{"cat:3:20", "zed:-", "ann:1:30", "bob:2:20"},
+		},
+		{name: "standard ranking shares ties", query: "?ranking=standard", body: `["cat","bob"]`, status: http.StatusOK, want: []string{"cat:2:20", "bob:2:20"}},
+		{name: "repeated names", body: `["ann","ann"]`, status: http.StatusOK, want: []string{"ann:1:30", "ann:1:30"}},
+		{name: "nobody", body: `[]`, status: http.StatusOK, want: []string{}},
+		{name: "names are matched exactly", body: `["Ann"," ann"]`, status: http.StatusOK, want: []string{"Ann:-", " ann:-"}},
+		{name: "too many names", body: `[` + strings.TrimSuffix(strings.Repeat(`"x",`, maxRankLookups+1), ",") + `]`, status: http.StatusUnprocessableEntity},
+		{name: "not an array", body: `{"names":["ann"]}`, status: http.StatusBadRequest},
+		{name: "unknown ranking", query: "?ranking=dense", body: `["ann"]`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHandler(NewServer())
+			for _, body := range board {
+				do(h, http.MethodPost, "/api/scores", body)
+			}
+
+			rec := do(h, http.MethodPost, "/api/ranks"+tt.query, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var lookups []RankLookup
+			if err := json.Unmarshal(rec.Body.Bytes(), &lookups); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, l := range lookups {
+				if l.Rank == nil || l.Score == nil {
+					got = append(got, l.Name+":-")
+				} else {
+					got = append(got, fmt.Sprintf("%s:%d:%v", l.Name, *l.Rank, *l.Score))
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("lookups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
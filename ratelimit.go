@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLimitedClients is how many per-client buckets a rule keeps before
+// idle ones are swept
+const maxLimitedClients = 10000
+
+// RateLimitRule limits each client to Rate requests per second, with
+// bursts of up to Burst, on requests matching Route. Route is a path,
+// optionally preceded by a method ("POST /api/scores"); a trailing "*"
+// matches any path with that prefix ("/api/admin/*").
+type RateLimitRule struct {
+	Route string  `json:"route"`
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// validate checks that the rule can be enforced
+func (rule RateLimitRule) validate() error {
+	_, path := rule.split()
+	switch {
+	case path == "" || !strings.HasPrefix(path, "/"):
+		return fmt.Errorf("rate limit route %q must be a path, optionally preceded by a method", rule.Route)
+	case rule.Rate <= 0 || math.IsInf(rule.Rate, 0) || math.IsNaN(rule.Rate):
+		return fmt.Errorf("rate limit for %q must have a positive rate", rule.Route)
+	case rule.Burst < 1:
+		return fmt.Errorf("rate limit for %q must allow a burst of at least 1", rule.Route)
+	}
+	return nil
+}
+
+// split separates the optional method from the path pattern
+func (rule RateLimitRule) split() (method, path string) {
+	if m, p, ok := strings.Cut(strings.TrimSpace(rule.Route), " "); ok {
+		return strings.ToUpper(m), strings.TrimSpace(p)
+	}
+	return "", strings.TrimSpace(rule.Route)
+}
+
+// tokenBucket is one client's allowance under a rule
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// routeLimiter enforces one rule
+type routeLimiter struct {
+	method string
+	path   string
+	prefix bool
+	rate   float64
+	burst  float64
+
+	mu      sync.Mutex
+	clients map[string]*tokenBucket
+}
+
+func (rl *routeLimiter) matches(method, path string) bool {
+	if rl.method != "" && rl.method != method {
+		return false
+	}
+	if rl.prefix {
+		return strings.HasPrefix(path, rl.path)
+	}
+	return path == rl.path
+}
+
+// allow takes a token for client at t, or reports how long until one is
+// available
+func (rl *routeLimiter) allow(client string, t time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, ok := rl.clients[client]
+	if !ok {
+		if len(rl.clients) >= maxLimitedClients {
+			rl.sweep(t)
+		}
+		b = &tokenBucket{tokens: rl.burst, last: t}
+		rl.clients[client] = b
+	}
+
+	b.tokens = min(rl.burst, b.tokens+t.Sub(b.last).Seconds()*rl.rate)
+	b.last = t
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep forgets clients whose buckets have refilled, since a fresh bucket
+// would be identical. rl.mu must be held.
+func (rl *routeLimiter) sweep(t time.Time) {
+	for client, b := range rl.clients {
+		if b.tokens+t.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.clients, client)
+		}
+	}
+}
+
+// rateLimiter applies the first matching rule to each request
+type rateLimiter struct {
+	routes []*routeLimiter
+	now    func() time.Time
+}
+
+// newRateLimiter builds a limiter from rules, in order of precedence
+func newRateLimiter(rules []RateLimitRule, now func() time.Time) (*rateLimiter, error) {
+	rl := &rateLimiter{now: now}
+	var errs []error
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		method, path := rule.split()
+		prefix := strings.HasSuffix(path, "*")
+		rl.routes = append(rl.routes, &routeLimiter{
+			method:  method,
+			path:    strings.TrimSuffix(path, "*"),
+			prefix:  prefix,
+			rate:    rule.Rate,
+			burst:   float64(rule.Burst),
+			clients: make(map[string]*tokenBucket),
+		})
+	}
+	return rl, errors.Join(errs...)
+}
+
+// allow applies the first rule matching method and path to client,
+// reporting how long until it may retry when the allowance is spent. A nil
+// limiter allows everything.
+func (rl *rateLimiter) allow(method, path, client string) (bool, time.Duration) {
+	if rl == nil {
+		return true, 0
+	}
+	for _, route := range rl.routes {
+		if route.matches(method, path) {
+			return route.allow(client, rl.now())
+		}
+	}
+	return true, 0
+}
+
+// WithRateLimits sets the per-route limits, which withRateLimits applies to
+// requests and the game socket to each submission it receives
+func WithRateLimits(rl *rateLimiter) Option {
+	return func(s *Server) { s.rateLimits = rl }
+}
+
+// withRateLimits throttles each client per route, answering 429 with
+// Retry-After once a route's allowance is spent. Requests matching no rule,
+// or for which exempt returns true, pass through untouched. It must run
+// inside withClientIP.
+func withRateLimits(rl *rateLimiter, exempt func(*http.Request) bool, next http.Handler) http.Handler {
+	if rl == nil || len(rl.routes) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := rl.allow(r.Method, r.URL.Path, clientIP(r)); !ok {
+			loggerFrom(r.Context()).Warn("rate limited", "ip", clientIP(r), "path", r.URL.Path)
+			writeThrottled(w, http.StatusTooManyRequests, "Too many requests, slow down", wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
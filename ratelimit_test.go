@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRoutesIndependent(t *testing.T) {
+	rules := []RateLimitRule{
+		{Route: "GET /api/leaderboard", Rate: 1, Burst: 3},
+		{Route: "POST /api/scores", Rate: 1, Burst: 1},
+		{Route: "/api/admin/*", Rate: 1, Burst: 1},
+	}
+	type step struct {
+		method, path, ip string
+		// wait advances the clock before the request
+		wait   time.Duration
+		status int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "spent writes leave reads alone",
+			steps: []step{
+				{method: "POST", path: "/api/scores", ip: "192.0.2.1", status: http.StatusCreated},
+				{method: "POST", path: "/api/scores", ip: "192.0.2.1", status: http.StatusTooManyRequests},
+				{method: "GET", path: "/api/leaderboard", ip: "192.0.2.1", status: http.StatusOK},
+			},
+		},
+		{
+			name: "spent reads leave writes alone",
+			steps: []step{
+				{method: "GET", path: "/api/leaderboard", ip: "192.0.2.1", status: http.StatusOK},
+				{method: "GET", path: "/api/leaderboard", ip: "192.0.2.1", status: http.StatusOK},
+				{method: "GET", path: "/api/leaderboard", ip: "192.0.2.1", status: http.StatusOK},
+				{method: "GET", path: "/api/leaderboard", ip: "192.0.2.1", status: http.StatusTooManyRequests},
+				{method: "POST", path: "/api/scores", ip: "192.0.2.1", status: http.StatusCreated},
+			},
+		},
+		{
+			name: "clients have their own buckets",
+			steps: []step{
+				{method: "POST", path: "/api/scores", ip: "192.0.2.1", status: http.StatusCreated},
+				{method: "POST", path: "/api/scores", ip: "192.0.2.2", status: http.StatusCreated},
+				{method: "POST", path: "/api/scores", ip: "192.0.2.1", status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "buckets refill",
+			steps: []step{
+				{method: "POST", path: "/api/scores", ip: "192.0.2.1", status: http.StatusCreated},
+				{method: "POST", path: "/api/scores", ip: "192.0.2.1", wait: time.Second, status: http.StatusCreated},
+			},
+		},
+		{
+			name: "prefix rules cover every route under them",
+			steps: []step{
+				{method: "GET", path: "/api/admin/review", ip: "192.0.2.1", status: http.StatusUnauthorized},
+				{method: "GET", path: "/api/admin/reports", ip: "192.0.2.1", status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "unmatched routes are unlimited",
+			steps: []step{
+				{method: "GET", path: "/api/stats", ip: "192.0.2.1", status: http.StatusOK},
+				{method: "GET", path: "/api/stats", ip: "192.0.2.1", status: http.StatusOK},
+				{method: "GET", path: "/api/stats", ip: "192.0.2.1", status: http.StatusOK},
+				{method: "GET", path: "/api/stats", ip: "192.0.2.1", status: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			limiter, err := newRateLimiter(rules, func() time.Time { return now })
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := testHandler(NewServer(WithRateLimits(limiter), WithAdminToken(testAdminToken)))
+
+			for i, st := range tt.steps {
+				now = now.Add(st.wait)
+				var body string
+				if st.method == http.MethodPost {
+					body = `{"name":"ann","score":10}`
+				}
+				req := httptest.NewRequest(st.method, st.path, strings.NewReader(body))
+				req.RemoteAddr = st.ip + ":1234"
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				if rec.Code != st.status {
+					t.Errorf("step %d %s %s from %s: status %d, want %d", i, st.method, st.path, st.ip, rec.Code, st.status)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitGameMessages(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter, err := newRateLimiter([]RateLimitRule{{Route: "POST /api/scores", Rate: 1, Burst: 2}}, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(WithRateLimits(limiter))
+	upgrade := httptest.NewRequest(http.MethodGet, "/ws/game", nil)
+	msg := []byte(`{"name":"ann","score":10}`)
+
+	// Each message on one socket spends the submit allowance
+	want := []int{http.StatusCreated, http.StatusCreated, http.StatusTooManyRequests}
+	for i, status := range want {
+		reply := s.limitGameMessage(upgrade, msg)
+		if reply.Status != status {
+			t.Errorf("message %d: status %d, want %d", i, reply.Status, status)
+		}
+	}
+	if reply := s.limitGameMessage(upgrade, msg); reply.RetryAfter != 1 {
+		t.Errorf("throttled reply's retryAfter = %d, want 1", reply.RetryAfter)
+	}
+
+	// HTTP submissions draw on the same bucket
+	if rec := do(testHandler(s), http.MethodPost, "/api/scores", string(msg)); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("HTTP submission after the socket's: status %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimitRuleValidate(t *testing.T) {
+	tests := []struct {
+		rule RateLimitRule
+		ok   bool
+	}{
+		{RateLimitRule{Route: "POST /api/scores", Rate: 1, Burst: 1}, true},
+		{RateLimitRule{Route: "/api/admin/*", Rate: 0.5, Burst: 5}, true},
+		{RateLimitRule{Route: "api/scores", Rate: 1, Burst: 1}, false},
+		{RateLimitRule{Route: "POST", Rate: 1, Burst: 1}, false},
+		{RateLimitRule{Route: "/api/scores", Rate: 0, Burst: 1}, false},
+		{RateLimitRule{Route: "/api/scores", Rate: 1, Burst: 0}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.rule.validate(); (err == nil) != tt.ok {
+			t.Errorf("validate(%+v) = %v, want ok %v", tt.rule, err, tt.ok)
+		}
+	}
+}
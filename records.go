@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// defaultRecordFeedSize is how many record events the feed keeps unless
+// configured otherwise
+const defaultRecordFeedSize = 50
+
+// RecordEvent is a score that beat every score before it
+type RecordEvent struct {
+	Score
+	Previous *Score
+}
+
+// recordLog keeps the most recent all-time records, oldest first. It is
+// guarded by the owning Leaderboard's lock. Records are tracked from server
+// start and aren't affected by daily resets.
+type recordLog struct {
+	size   int
+	best   *Score
+	events []RecordEvent
+}
+
+// observe logs entry if it beats the best score seen so far
+func (rl *recordLog) observe(entry Score) {
+	if rl.best != nil && entry.Score <= rl.best.Score {
+		return
+	}
+	rl.events = append(rl.events, RecordEvent{Score: entry, Previous: rl.best})
+	if len(rl.events) > rl.size {
+		rl.events = rl.events[len(rl.events)-rl.size:]
+	}
+	best := entry
+	rl.best = &best
+}
+
+// Records returns the logged record events, newest first
+func (lb *Leaderboard) Records() []RecordEvent {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	events := slices.Clone(lb.records.events)
+	slices.Reverse(events)
+	return events
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Updated   string   `xml:"updated"`
+	Published string   `xml:"published"`
+	Author    string   `xml:"author>name"`
+	Summary   string   `xml:"summary"`
+	Link      atomLink `xml:"link"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Link    []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// recordEntryID is a stable tag URI for a record, derived from when it was
+// set so it survives restarts
+func recordEntryID(t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("tag:flappy-gopher,%s:record:%d", t.Format(time.DateOnly), t.UnixNano())
+}
+
+// handleRecordsFeed handles GET /api/records.atom
+func (s *Server) handleRecordsFeed(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host
+
+	events := s.lb.Records()
+	// Records set since a freeze would reveal the hidden board
+	if _, _, frozenAt, ok := s.freeze.Frozen(); ok {
+		events = slices.DeleteFunc(events, func(ev RecordEvent) bool { return ev.Timestamp.After(frozenAt) })
+	}
+	updated := s.now()
+	if len(events) > 0 {
+		updated = events[0].Timestamp
+	}
+
+	feed := atomFeed{
+		ID:      base + "/api/records.atom",
+		Title:   "Flappy Gopher records",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  "Flappy Gopher",
+		Link: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: base + "/api/records.atom"},
+			{Rel: "alternate", Type: "text/html", Href: base + "/"},
+		},
+		Entries: make([]atomEntry, 0, len(events)),
+	}
+	for _, ev := range events {
+		summary := fmt.Sprintf("%s scored %s, the first record on the board", ev.Name, formatScore(ev.Score.Score))
+		if ev.Previous != nil {
+			summary = fmt.Sprintf("%s scored %s, beating %s's %s", ev.Name, formatScore(ev.Score.Score), ev.Previous.Name, formatScore(ev.Previous.Score))
+		}
+		ts := ev.Timestamp.UTC().Format(time.RFC3339Nano)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        recordEntryID(ev.Timestamp),
+			Title:     fmt.Sprintf("New record: %s by %s", formatScore(ev.Score.Score), ev.Name),
+			Updated:   ts,
+			Published: ts,
+			Author:    ev.Name,
+			Summary:   summary,
+			Link:      atomLink{Rel: "alternate", Href: base + "/api/rank/" + url.PathEscape(ev.Name)},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		loggerFrom(r.Context()).Warn("failed to write records feed", "err", err)
+	}
+}
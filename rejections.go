@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Reasons a score submission can be rejected
+const (
+	rejectInvalidBody   = "invalid_body"
+	rejectInvalidName   = "invalid_name"
+	rejectInvalidScore  = "invalid_score"
+	rejectInvalidMeta   = "invalid_meta"
+	rejectInvalidMode   = "invalid_mode"
+	rejectNameClaimed   = "name_claimed"
+	rejectNoSession     = "invalid_session"
+	rejectImplausible   = "implausible_score"
+	rejectOutsideWindow = "outside_window"
+	rejectOverloaded    = "overloaded"
+	rejectFollower      = "read_only"
+	rejectCancelled     = "cancelled"
+	rejectBadSignature  = "bad_signature"
+	rejectBadReplay     = "replay_mismatch"
+	rejectScoreJump     = "score_jump"
+)
+
+// rejectionCounter mirrors the rejection metric for the admin JSON view
+type rejectionCounter struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// countRejection records a rejected submission under reason and logs it
+func (s *Server) countRejection(logger *slog.Logger, reason string, args ...any) {
+	s.metrics.rejections.WithLabelValues(reason).Inc()
+
+	s.rejections.mu.Lock()
+	s.rejections.counts[reason]++
+	s.rejections.mu.Unlock()
+
+	logger.Warn("rejected score submission", append([]any{"reason", reason}, args...)...)
+}
+
+// handleRejectionStats handles GET /api/admin/stats/rejections
+func (s *Server) handleRejectionStats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.rejections.mu.Lock()
+	counts := make(map[string]uint64, len(s.rejections.counts))
+	for reason, n := range s.rejections.counts {
+		counts[reason] = n
+	}
+	s.rejections.mu.Unlock()
+
+	writeJSON(w, r, http.StatusOK, counts)
+}
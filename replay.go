@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// Game physics, per frame, as implemented by web/index.html
+const (
+	simGravity          = 0.1
+	simTerminalVelocity = 5
+	simJumpPower        = -5
+	simPipeGap          = 200
+	simPipeWidth        = 50
+	simPipeSpeed        = 2
+	simGopherSize       = 50
+)
+
+// Defaults for the replay bounds
+const (
+	defaultReplayMaxFrames = 60 * 60 * 60 // an hour at 60 fps
+	defaultReplayMaxTaps   = 100000
+	minReplayCanvas        = 400
+	maxReplayCanvas        = 8192
+)
+
+// RunLog is a game's input log: the canvas it was played on, the pipe seed
+// from /api/game/start and the frames on which the player tapped
+type RunLog struct {
+	Seed   uint32 `json:"seed"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Taps are frame numbers, in order; a tap on frame n takes effect
+	// before that frame's physics step
+	Taps []int `json:"taps"`
+}
+
+// replayBounds limits how much simulation a single submission may cost
+type replayBounds struct {
+	MaxFrames int
+	MaxTaps   int
+}
+
+// mulberry32 is the seeded generator the client uses for pipe heights,
+// chosen because it is exact to reproduce in JavaScript
+type mulberry32 uint32
+
+func (m *mulberry32) next() float64 {
+	*m += 0x6D2B79F5
+	t := uint32(*m)
+	t = (t ^ t>>15) * (1 | t)
+	t = (t + (t^t>>7)*(61|t)) ^ t
+	return float64(t^t>>14) / 4294967296
+}
+
+var (
+	errRunTooLong     = errors.New("run did not end within the replay frame limit")
+	errTapsAfterCrash = errors.New("input log continues after the run ended")
+)
+
+// validateRun re-simulates a game from seed and its input log and returns
+// the score it ends with
+func (rb replayBounds) validateRun(seed uint32, run RunLog) (int, error) {
+	if run.Width < minReplayCanvas || run.Width > maxReplayCanvas || run.Height < minReplayCanvas || run.Height > maxReplayCanvas {
+		return 0, fmt.Errorf("replay canvas must be between %d and %d pixels each way", minReplayCanvas, maxReplayCanvas)
+	}
+	if len(run.Taps) > rb.MaxTaps {
+		return 0, fmt.Errorf("replay has more than %d taps", rb.MaxTaps)
+	}
+	for i, f := range run.Taps {
+		if f < 0 || (i > 0 && f < run.Taps[i-1]) {
+			return 0, errors.New("replay taps must be non-negative frame numbers in order")
+		}
+	}
+
+	w, h := float64(run.Width), float64(run.Height)
+	rng := mulberry32(seed)
+	x := w * 0.2
+	y := h/2 - 25
+	velocity := 0.0
+	pipeX := w
+	pipeY := rng.next()*(h-simPipeGap-200) + 100
+	score := 0
+
+	tap := 0
+	for frame := 0; frame < rb.MaxFrames; frame++ {
+		for tap < len(run.Taps) && run.Taps[tap] == frame {
+			velocity = simJumpPower
+			tap++
+		}
+
+		velocity += simGravity
+		if velocity > simTerminalVelocity {
+			velocity = simTerminalVelocity
+		}
+		y += velocity
+
+		pipeX -= simPipeSpeed
+		if pipeX+simPipeWidth < 0 {
+			pipeX = w
+			pipeY = rng.next()*(h-simPipeGap-200) + 100
+			score++
+		}
+
+		crashed := y+simGopherSize > h || y < 0 ||
+			(x+simGopherSize > pipeX && x < pipeX+simPipeWidth &&
+				(y < pipeY || y+simGopherSize > pipeY+simPipeGap))
+		if crashed {
+			if tap < len(run.Taps) {
+				return 0, errTapsAfterCrash
+			}
+			return score, nil
+		}
+	}
+	return 0, errRunTooLong
+}
+
+// checkReplay rejects req unless its replay, played from seed, ends with
+// the claimed score
+func (s *Server) checkReplay(logger *slog.Logger, req *submitRequest, seed uint32) *submitError {
+	if req.Replay.Seed != seed {
+		s.countRejection(logger, rejectBadReplay, "name", req.Name, "err", "seed mismatch")
+		return &submitError{Status: http.StatusUnprocessableEntity, Message: "Replay seed does not match the game session"}
+	}
+	score, err := s.replay.validateRun(seed, *req.Replay)
+	if err != nil {
+		s.countRejection(logger, rejectBadReplay, "name", req.Name, "err", err)
+		return &submitError{Status: http.StatusUnprocessableEntity, Message: "Invalid replay: " + err.Error()}
+	}
+	// A score computed from params is checked by its pipe count, which is
+	// what the replay counts
+	claimed := req.Score
+	if req.Params != nil {
+		claimed = float64(req.Params.Pipes)
+	}
+	if float64(score) != claimed {
+		s.countRejection(logger, rejectBadReplay, "name", req.Name, "score", claimed, "replayed", score)
+		return &submitError{Status: http.StatusUnprocessableEntity, Message: fmt.Sprintf("Score does not match the replay, which ends at %d", score)}
+	}
+	return nil
+}
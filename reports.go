@@ -0,0 +1,166 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// reportDedupWindow is how long repeat reports of one player from the
+	// same address are ignored
+	reportDedupWindow = 24 * time.Hour
+	// maxReportReason caps a report's reason in runes
+	maxReportReason = 200
+	// reportReasonsKept is how many recent reasons are kept per player
+	reportReasonsKept = 10
+	// reportRate and reportBurst limit how fast one address may report
+	reportRate  = 1.0 / 60
+	reportBurst = 5
+)
+
+// PlayerReport is one reason given for reporting a player
+type PlayerReport struct {
+	Reason     string    `json:"reason"`
+	ReportedAt time.Time `json:"reportedAt"`
+}
+
+// ReportedPlayer aggregates every report against a player
+type ReportedPlayer struct {
+	Name      string         `json:"name"`
+	Count     int            `json:"count"`
+	FirstAt   time.Time      `json:"firstReportedAt"`
+	LastAt    time.Time      `json:"lastReportedAt"`
+	Reasons   []PlayerReport `json:"recentReasons"`
+	reporters map[string]bool
+}
+
+// reportQueue collects community reports for moderators to review. It is
+// kept in memory, and in state archives, and never acts on a report by
+// itself.
+type reportQueue struct {
+	limiter *routeLimiter
+
+	mu      sync.Mutex
+	players map[string]*ReportedPlayer
+	// seen is when each reporter address last reported each player
+	seen map[[2]string]time.Time
+}
+
+func newReportQueue() *reportQueue {
+	return &reportQueue{
+		limiter: &routeLimiter{rate: reportRate, burst: reportBurst, clients: make(map[string]*tokenBucket)},
+		players: make(map[string]*ReportedPlayer),
+		seen:    make(map[[2]string]time.Time),
+	}
+}
+
+// Add records a report of name from reporter at t and reports false if the
+// same reporter already reported name within reportDedupWindow
+func (rq *reportQueue) Add(reporter, name, reason string, t time.Time) bool {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	key := [2]string{reporter, name}
+	if last, ok := rq.seen[key]; ok && t.Sub(last) < reportDedupWindow {
+		return false
+	}
+	if len(rq.seen) >= maxLimitedClients {
+		for k, last := range rq.seen {
+			if t.Sub(last) >= reportDedupWindow {
+				delete(rq.seen, k)
+			}
+		}
+	}
+	rq.seen[key] = t
+
+	p, ok := rq.players[name]
+	if !ok {
+		p = &ReportedPlayer{Name: name, FirstAt: t, reporters: make(map[string]bool)}
+		rq.players[name] = p
+	}
+	p.Count++
+	p.LastAt = t
+	p.reporters[reporter] = true
+	p.Reasons = append(p.Reasons, PlayerReport{Reason: reason, ReportedAt: t})
+	if len(p.Reasons) > reportReasonsKept {
+		p.Reasons = p.Reasons[len(p.Reasons)-reportReasonsKept:]
+	}
+	return true
+}
+
+// ReportedPlayerView is a reported player as listed for admins
+type ReportedPlayerView struct {
+	ReportedPlayer
+	Reporters int `json:"distinctReporters"`
+}
+
+// List returns every reported player, most reported first
+func (rq *reportQueue) List() []ReportedPlayerView {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	list := make([]ReportedPlayerView, 0, len(rq.players))
+	for _, p := range rq.players {
+		v := ReportedPlayerView{ReportedPlayer: *p, Reporters: len(p.reporters)}
+		v.Reasons = slices.Clone(p.Reasons)
+		list = append(list, v)
+	}
+	slices.SortFunc(list, func(a, b ReportedPlayerView) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), b.LastAt.Compare(a.LastAt), cmp.Compare(a.Name, b.Name))
+	})
+	return list
+}
+
+// handleReportPlayer handles POST /api/report
+func (s *Server) handleReportPlayer(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ip := clientIP(r)
+	if ok, wait := s.reports.limiter.allow(ip, s.now()); !ok {
+		writeThrottled(w, http.StatusTooManyRequests, "Too many reports, try again later", wait)
+		return
+	}
+
+	var req struct {
+		Name   string `json:"name"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmitBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if _, ok := s.lb.LastSubmission(name); !ok {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		http.Error(w, "Reason is required", http.StatusUnprocessableEntity)
+		return
+	}
+	if !utf8.ValidString(reason) || utf8.RuneCountInString(reason) > maxReportReason {
+		http.Error(w, fmt.Sprintf("Reason must be valid UTF-8 of at most %d characters", maxReportReason), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// A duplicate is acknowledged the same way so reporters can't probe
+	// the dedup window
+	if s.reports.Add(ip, name, reason, s.now()) {
+		loggerFrom(r.Context()).Info("player reported", "name", name, "ip", ip)
+	}
+	writeJSON(w, r, http.StatusAccepted, map[string]string{"status": "received"})
+}
+
+// handleListReports handles GET /api/admin/reports
+func (s *Server) handleListReports(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, r, http.StatusOK, capEntries(s, w, s.reports.List()))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// withPrettyJSON makes every JSON response to requests through h indented
+func withPrettyJSON(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), prettyJSONKey, true)))
+	})
+}
+
+// writeJSON writes v as a JSON response with the given status. The output
+// is indented when the request has ?pretty=true or dev mode is enabled.
+//
+// v is encoded before anything is written, so a value that can't be
+// encoded is answered with a 500 instead of a truncated body. Failures to
+// write, usually a client that went away, can only be logged.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if r.Context().Value(prettyJSONKey) != nil || r.URL.Query().Get("pretty") == "true" {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		loggerFrom(r.Context()).Error("failed to encode response", "path", r.URL.Path, "err", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		loggerFrom(r.Context()).Warn("failed to write response", "path", r.URL.Path, "err", err)
+	}
+}
+
+// defaultMaxResponseEntries is the default cap on entries in one list
+// response
+const defaultMaxResponseEntries = 1000
+
+// capEntries cuts entries down to the server's response cap, after any
+// pagination or filtering, and flags a cut with X-Truncated: true
+func capEntries[T any](s *Server, w http.ResponseWriter, entries []T) []T {
+	if s.maxResponseEntries <= 0 || len(entries) <= s.maxResponseEntries {
+		return entries
+	}
+	w.Header().Set("X-Truncated", "true")
+	return entries[:s.maxResponseEntries]
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLogs sends the default logger's output to the returned buffer
+// for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// failingWriter is a ResponseWriter whose client has gone away
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (fw failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriteJSONFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		v      any
+		broken bool
+		status int
+		// log is the expected log line's message, "" for none
+		log string
+	}{
+		{name: "written", v: map[string]int{"n": 1}, status: http.StatusOK},
+		{name: "unencodable value", v: math.Inf(1), status: http.StatusInternalServerError, log: "failed to encode response"},
+		{name: "client went away", v: map[string]int{"n": 1}, broken: true, status: http.StatusOK, log: "failed to write response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			h := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, r, http.StatusOK, tt.v)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+			req.Header.Set("X-Request-ID", "req-42")
+			rec := httptest.NewRecorder()
+			var w http.ResponseWriter = rec
+			if tt.broken {
+				w = failingWriter{rec}
+			}
+			h.ServeHTTP(w, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			out := logs.String()
+			if tt.log == "" {
+				if out != "" {
+					t.Errorf("logged %q, want nothing", out)
+				}
+				return
+			}
+			if !strings.Contains(out, tt.log) || !strings.Contains(out, "request_id=req-42") {
+				t.Errorf("logged %q, want %q with the request ID", out, tt.log)
+			}
+		})
+	}
+}
+
+func TestResponseEntryCap(t *testing.T) {
+	tests := []struct {
+		name   string
+		cap    int
+		target string
+		want   int
+		// players lists are wrapped, with a nextOffset once cut
+		players    bool
+		truncated  bool
+		nextOffset int
+	}{
+		{name: "board under the cap", cap: 10, target: "/api/leaderboard", want: 6},
+		{name: "board at the cap", cap: 6, target: "/api/leaderboard", want: 6},
+		{name: "board over the cap", cap: 4, target: "/api/leaderboard", want: 4, truncated: true},
+		{name: "board cap disabled", cap: 0, target: "/api/leaderboard", want: 6},
+		{name: "large page", cap: 4, target: "/api/players?limit=50", want: 4, players: true, truncated: true, nextOffset: 4},
+		{name: "page cut after the offset", cap: 4, target: "/api/players?limit=50&offset=1", want: 4, players: true, truncated: true, nextOffset: 5},
+		{name: "page under the cap", cap: 4, target: "/api/players?limit=3", want: 3, players: true, nextOffset: 3},
+		{name: "offset past the end", cap: 4, target: "/api/players?limit=100&offset=2147483647", want: 0, players: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHandler(NewServer(WithMaxResponseEntries(tt.cap)))
+			for i := range 6 {
+				do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":"p%d","score":%d}`, i, i))
+			}
+
+			rec := do(h, http.MethodGet, tt.target, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var got int
+			if tt.players {
+				var resp struct {
+					Players    []string `json:"players"`
+					NextOffset int      `json:"nextOffset"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				got = len(resp.Players)
+				if resp.NextOffset != tt.nextOffset {
+					t.Errorf("nextOffset = %d, want %d", resp.NextOffset, tt.nextOffset)
+				}
+			} else {
+				var board []Score
+				if err := json.Unmarshal(rec.Body.Bytes(), &board); err != nil {
+					t.Fatal(err)
+				}
+				got = len(board)
+			}
+			if got != tt.want {
+				t.Errorf("response has %d entries, want %d", got, tt.want)
+			}
+			if truncated := rec.Header().Get("X-Truncated") == "true"; truncated != tt.truncated {
+				t.Errorf("X-Truncated = %q, want truncated %v", rec.Header().Get("X-Truncated"), tt.truncated)
+			}
+		})
+	}
+}
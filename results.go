@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxSessionPlayers caps how many players can submit from one game session
+const maxSessionPlayers = 8
+
+// sessionResult is one player's result in a session's results
+type sessionResult struct {
+	Name  string          `json:"name"`
+	Score float64         `json:"score"`
+	Meta  json.RawMessage `json:"meta"`
+	Token string          `json:"token"`
+	Mode  string          `json:"mode"`
+}
+
+// sessionResultStatus is how one player's result was handled
+type sessionResultStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Rank is the player's rank after the submission, omitted when off
+	// the board
+	Rank  int    `json:"rank,omitempty"`
+	Error string `json:"error,omitempty"`
+	Code  int    `json:"code,omitempty"`
+}
+
+// handleSessionResults handles POST /api/game/:sessionID/results, recording
+// the scores of several players taking turns on one device. The session is
+// consumed once, and since the players shared its time, their scores
+// together must be plausible for how long it ran. Each result is then
+// accepted or rejected on its own.
+func (s *Server) handleSessionResults(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+	logger := loggerFrom(r.Context())
+
+	if serr := s.admitSubmission(r.Context()); serr != nil {
+		serr.write(w, r)
+		return
+	}
+
+	data, ok := s.readSubmissionBody(w, r, maxSubmitBodyBytes*maxSessionPlayers)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Results []sessionResult `json:"results"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		s.countRejection(logger, rejectInvalidBody, "err", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Results) == 0 || len(req.Results) > maxSessionPlayers {
+		s.countRejection(logger, rejectInvalidBody, "players", len(req.Results))
+		http.Error(w, fmt.Sprintf("results must list between 1 and %d players", maxSessionPlayers), http.StatusUnprocessableEntity)
+		return
+	}
+
+	session, ok := s.sessions.Finish(ps.ByName("sessionID"))
+	if !ok {
+		s.countRejection(logger, rejectNoSession, "players", len(req.Results))
+		http.Error(w, "Invalid or expired game session", http.StatusForbidden)
+		return
+	}
+	elapsed := s.now().Sub(session.Started)
+	budget := s.maxPlausibleScore(elapsed)
+
+	statuses := make([]sessionResultStatus, 0, len(req.Results))
+	for _, res := range req.Results {
+		st := sessionResultStatus{Name: res.Name}
+		if res.Score > budget {
+			s.countRejection(logger, rejectImplausible, "name", res.Name, "score", res.Score, "elapsed", elapsed)
+			st.Status, st.Error, st.Code = "rejected", "Score is not plausible for the game duration", http.StatusUnprocessableEntity
+			statuses = append(statuses, st)
+			continue
+		}
+
+		sub := submitRequest{
+			Name:           res.Name,
+			Score:          res.Score,
+			Meta:           res.Meta,
+			Token:          res.Token,
+			Mode:           res.Mode,
+			SessionID:      ps.ByName("sessionID"),
+			sessionChecked: true,
+		}
+		result, serr := s.acceptSubmission(r.Context(), &sub)
+		st.Name = sub.Name
+		if serr != nil {
+			st.Status, st.Error, st.Code = "rejected", serr.Message, serr.Status
+			statuses = append(statuses, st)
+			continue
+		}
+
+		// Even an ignored score took its share of the session's time
+		budget -= sub.Score
+		st.Status = "success"
+		switch {
+		case result.Held:
+			st.Status = "held"
+		case result.Ignored:
+			st.Status = "ignored"
+		}
+		// Looked up on the public board, so a frozen board isn't revealed
+		entries, value := s.publicBoard()
+		if rank, _, ok := rankIn(entries, sub.Name, rankingPositional, value); ok {
+			st.Rank = rank
+		}
+		statuses = append(statuses, st)
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"results":       statuses,
+		"processedInMs": processedInMs(start),
+	})
+}
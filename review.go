@@ -0,0 +1,223 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// defaultJumpMinHistory is how many earlier submissions a player needs
+	// before their scores are checked for jumps
+	defaultJumpMinHistory = 3
+	// maxHeldScores bounds the review queue; suspicious scores past it are
+	// refused rather than silently dropped
+	maxHeldScores = 1000
+)
+
+// HeldScore is a submission withheld from the board until an admin
+// reviews it. It takes its score ID when held and keeps it, along with
+// the time it was held, once approved.
+type HeldScore struct {
+	ID           uint64    `json:"id"`
+	Name         string    `json:"name"`
+	Score        float64   `json:"score"`
+	Mode         string    `json:"mode"`
+	PreviousBest float64   `json:"previousBest"`
+	HeldAt       time.Time `json:"heldAt"`
+	RequestID    string    `json:"requestId,omitempty"`
+	// Meta is the submission's metadata, recorded with it on approval
+	Meta json.RawMessage `json:"meta,omitempty"`
+}
+
+// reviewQueue holds suspicious submissions for moderators. Like reports
+// it is kept in memory, and in state archives.
+type reviewQueue struct {
+	mu   sync.Mutex
+	held map[uint64]*HeldScore
+}
+
+func newReviewQueue() *reviewQueue {
+	return &reviewQueue{held: make(map[uint64]*HeldScore)}
+}
+
+// Hold queues h under its score ID and reports false if the queue is full
+func (rq *reviewQueue) Hold(h HeldScore) bool {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	if len(rq.held) >= maxHeldScores {
+		return false
+	}
+	rq.held[h.ID] = &h
+	return true
+}
+
+// withSubmitted makes a score recorded under ctx keep the ID and time h
+// was submitted with, rather than taking new ones at approval
+func withSubmitted(ctx context.Context, h HeldScore) context.Context {
+	return context.WithValue(ctx, submittedKey, Score{ID: h.ID, Timestamp: h.HeldAt})
+}
+
+// submittedFrom returns the ID and time set by withSubmitted, if any
+func submittedFrom(ctx context.Context) (Score, bool) {
+	orig, ok := ctx.Value(submittedKey).(Score)
+	return orig, ok
+}
+
+// Take removes and returns the held score with the given ID
+func (rq *reviewQueue) Take(id uint64) (HeldScore, bool) {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	h, ok := rq.held[id]
+	if !ok {
+		return HeldScore{}, false
+	}
+	delete(rq.held, id)
+	return *h, true
+}
+
+// List returns every held score, oldest first
+func (rq *reviewQueue) List() []HeldScore {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	list := make([]HeldScore, 0, len(rq.held))
+	for _, h := range rq.held {
+		list = append(list, *h)
+	}
+	slices.SortFunc(list, func(a, b HeldScore) int { return cmp.Compare(a.ID, b.ID) })
+	return list
+}
+
+// jumpDetector flags a score more than factor times the player's best
+// once they have at least minHistory earlier submissions
+type jumpDetector struct {
+	factor     float64
+	minHistory int
+}
+
+// WithJumpDetector holds scores over factor times the player's previous
+// best for review once they have minHistory earlier submissions; a factor
+// of 0 turns detection off
+func WithJumpDetector(factor float64, minHistory int) Option {
+	return func(s *Server) { s.jumps = jumpDetector{factor: factor, minHistory: minHistory} }
+}
+
+// PriorBest returns name's best recorded score and how many of its
+// submissions are retained in history
+func (lb *Leaderboard) PriorBest(name string) (best float64, n int) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	for _, e := range lb.history {
+		if e.Name == name {
+			best = max(best, e.Score)
+			n++
+		}
+	}
+	for _, e := range lb.entries {
+		if e.Name == name {
+			best = max(best, e.Score)
+		}
+	}
+	return best, n
+}
+
+// checkJump holds req for review if its score leaps too far past the
+// player's previous best, reporting whether it did
+func (s *Server) checkJump(ctx context.Context, logger *slog.Logger, req *submitRequest) (bool, *submitError) {
+	if s.jumps.factor == 0 {
+		return false, nil
+	}
+	best, n := s.lb.PriorBest(req.Name)
+	// No multiple of a best of 0 is a meaningful bar
+	if n < s.jumps.minHistory || best <= 0 || req.Score <= best*s.jumps.factor {
+		return false, nil
+	}
+
+	id := s.lb.ids.Next()
+	ok := s.review.Hold(HeldScore{
+		ID:           id,
+		Name:         req.Name,
+		Score:        req.Score,
+		Mode:         req.Mode,
+		PreviousBest: best,
+		HeldAt:       s.now().UTC(),
+		RequestID:    requestIDFrom(ctx),
+		Meta:         req.Meta,
+	})
+	if !ok {
+		s.countRejection(logger, rejectScoreJump, "name", req.Name, "score", req.Score, "best", best, "queue", "full")
+		return false, &submitError{Status: http.StatusServiceUnavailable, Message: "Score needs review but the review queue is full"}
+	}
+	// Held rather than rejected: it is accepted with 202 and may yet be
+	// approved
+	s.metrics.submissionsHeld.Inc()
+	logger.Info("held score submission for review", "name", req.Name, "score", req.Score, "best", best, "reviewId", id)
+	return true, nil
+}
+
+// handleListHeldScores handles GET /api/admin/review
+func (s *Server) handleListHeldScores(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, r, http.StatusOK, capEntries(s, w, s.review.List()))
+}
+
+// takeHeldScore removes the held score named by the :id route parameter,
+// replying 400 or 404 when there isn't one
+func (s *Server) takeHeldScore(w http.ResponseWriter, ps httprouter.Params) (HeldScore, bool) {
+	id, err := strconv.ParseUint(ps.ByName("id"), 10, 64)
+	if err != nil || id == 0 {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return HeldScore{}, false
+	}
+	h, ok := s.review.Take(id)
+	if !ok {
+		http.Error(w, "Held score not found", http.StatusNotFound)
+	}
+	return h, ok
+}
+
+// handleApproveHeldScore handles POST /api/admin/review/:id/approve,
+// recording the held score under the ID and time it was held with
+func (s *Server) handleApproveHeldScore(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h, ok := s.takeHeldScore(w, ps)
+	if !ok {
+		return
+	}
+
+	req := submitRequest{Name: h.Name, Score: h.Score, Mode: h.Mode, Meta: h.Meta}
+	placed, serr := s.recordSubmission(withSubmitted(r.Context(), h), &req)
+	if serr != nil {
+		// Put it back so the approval can be retried
+		s.review.Hold(h)
+		serr.write(w, r)
+		return
+	}
+	loggerFrom(r.Context()).Info("admin approved held score", "name", h.Name, "score", h.Score)
+	s.auditLog(r, "approve", h.Name, map[string]any{"score": h.Score, "previousBest": h.PreviousBest})
+
+	writeJSON(w, r, http.StatusOK, map[string]any{"status": "success", "madeTopTen": placed})
+}
+
+// handleRejectHeldScore handles DELETE /api/admin/review/:id, discarding
+// the held score
+func (s *Server) handleRejectHeldScore(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h, ok := s.takeHeldScore(w, ps)
+	if !ok {
+		return
+	}
+	loggerFrom(r.Context()).Info("admin rejected held score", "name", h.Name, "score", h.Score)
+	s.auditLog(r, "reject", h.Name, map[string]any{"score": h.Score, "previousBest": h.PreviousBest})
+
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "success"})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestJumpDetector(t *testing.T) {
+	tests := []struct {
+		name       string
+		factor     float64
+		minHistory int
+		scores     []float64
+		// held is which of scores end up in the review queue
+		held []float64
+	}{
+		{name: "smooth progression", factor: 10, minHistory: 2, scores: []float64{12, 20, 35, 60, 150}},
+		{name: "sudden spike", factor: 10, minHistory: 2, scores: []float64{12, 15, 900}, held: []float64{900}},
+		{name: "measured against the best, not the last", factor: 10, minHistory: 2, scores: []float64{50, 5, 60}},
+		{name: "exactly the factor", factor: 10, minHistory: 2, scores: []float64{10, 10, 100}},
+		{name: "too little history", factor: 10, minHistory: 2, scores: []float64{12, 900}},
+		{name: "no best to compare to", factor: 10, minHistory: 2, scores: []float64{0, 0, 50}},
+		{name: "detection off", factor: 0, minHistory: 2, scores: []float64{12, 15, 900}},
+		{name: "held scores don't raise the bar", factor: 10, minHistory: 1, scores: []float64{10, 500, 400}, held: []float64{500, 400}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(WithJumpDetector(tt.factor, tt.minHistory))
+			h := testHandler(s)
+			for _, score := range tt.scores {
+				rec := do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":"ann","score":%v}`, score))
+				if rec.Code != http.StatusCreated && rec.Code != http.StatusAccepted {
+					t.Fatalf("submit %v: status %d: %s", score, rec.Code, rec.Body)
+				}
+			}
+
+			var held []float64
+			for _, h := range s.review.List() {
+				held = append(held, h.Score)
+			}
+			if fmt.Sprint(held) != fmt.Sprint(tt.held) {
+				t.Errorf("held scores = %v, want %v", held, tt.held)
+			}
+			for _, e := range s.lb.History() {
+				for _, score := range tt.held {
+					if e.Score == score {
+						t.Errorf("held score %v was published", score)
+					}
+				}
+			}
+
+			// Held scores are counted as held, not rejected
+			if n := s.rejections.counts[rejectScoreJump]; n != 0 {
+				t.Errorf("%d held scores counted as rejections", n)
+			}
+			want := fmt.Sprintf("flappy_submissions_held_total %d\n", len(tt.held))
+			if body := do(h, http.MethodGet, "/metrics", "").Body.String(); !strings.Contains(body, want) {
+				t.Errorf("metrics lack %q", want)
+			}
+		})
+	}
+}
+
+func TestReviewDecision(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		// top is the board's best score after the decision
+		top float64
+	}{
+		{name: "approved", method: http.MethodPost, path: "/approve", top: 900},
+		{name: "rejected", method: http.MethodDelete, top: 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(WithJumpDetector(10, 2), WithAdminToken(testAdminToken))
+			h := testHandler(s)
+			for _, score := range []int{12, 15, 900} {
+				do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":"ann","score":%d}`, score))
+			}
+			var list []HeldScore
+			if err := json.Unmarshal(doAdmin(h, http.MethodGet, "/api/admin/review", nil).Body.Bytes(), &list); err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 1 || list[0].PreviousBest != 15 {
+				t.Fatalf("review queue = %+v, want the 900 held against a best of 15", list)
+			}
+
+			target := fmt.Sprintf("/api/admin/review/%d%s", list[0].ID, tt.path)
+			if rec := doAdmin(h, tt.method, target, nil); rec.Code != http.StatusOK {
+				t.Fatalf("decision: status %d: %s", rec.Code, rec.Body)
+			}
+			if top := s.lb.GetTopScores(); top[0].Score != tt.top {
+				t.Errorf("top score = %v, want %v", top[0].Score, tt.top)
+			}
+			if n := len(s.review.List()); n != 0 {
+				t.Errorf("review queue still holds %d scores", n)
+			}
+			// Decided scores can't be decided again
+			if rec := doAdmin(h, tt.method, target, nil); rec.Code != http.StatusNotFound {
+				t.Errorf("second decision: status %d, want %d", rec.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
+
+func TestReviewQueueFull(t *testing.T) {
+	s := NewServer(WithJumpDetector(10, 2))
+	h := testHandler(s)
+	for _, score := range []int{12, 15} {
+		do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":"ann","score":%d}`, score))
+	}
+	for i := range maxHeldScores {
+		s.review.Hold(HeldScore{ID: uint64(1000 + i), Name: "bob", Score: 1})
+	}
+
+	// With nowhere to hold it, the score is refused and counted as such
+	if rec := do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":900}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body)
+	}
+	if n := s.rejections.counts[rejectScoreJump]; n != 1 {
+		t.Errorf("%s rejections = %d, want 1", rejectScoreJump, n)
+	}
+	if body := do(h, http.MethodGet, "/metrics", "").Body.String(); !strings.Contains(body, "flappy_submissions_held_total 0\n") {
+		t.Error("refused score counted as held")
+	}
+}
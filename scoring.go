@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+const (
+	// defaultScoreFormula scores a game by its pipe count alone
+	defaultScoreFormula = "raw"
+	// maxParamPipes bounds ScoreParams.Pipes so weighting by the largest
+	// mode weight can't overflow int, even on 32-bit targets
+	maxParamPipes = math.MaxInt32 / 200
+)
+
+// ScoreParams are the raw game parameters a submission may carry for the
+// server to score instead of a final score
+type ScoreParams struct {
+	// Pipes is how many pipes the player passed
+	Pipes int `json:"pipes"`
+	// Mode is the submission's difficulty mode, filled in by the server
+	Mode string `json:"-"`
+}
+
+// ScoreFn turns a game's parameters into its score
+type ScoreFn func(ScoreParams) int
+
+// modeWeights are the difficulty-weighted formula's multipliers, in
+// percent; modes not listed count at 100
+var modeWeights = map[string]int{
+	"easy":   50,
+	"hard":   150,
+	"expert": 200,
+}
+
+// scoreFormulas are the built-in formulas selectable with -score-formula
+var scoreFormulas = map[string]ScoreFn{
+	"raw": func(p ScoreParams) int { return p.Pipes },
+	"difficulty-weighted": func(p ScoreParams) int {
+		weight, ok := modeWeights[p.Mode]
+		if !ok {
+			weight = 100
+		}
+		return p.Pipes * weight / 100
+	},
+}
+
+// lookupScoreFormula returns the built-in formula called name
+func lookupScoreFormula(name string) (ScoreFn, error) {
+	fn, ok := scoreFormulas[name]
+	if !ok {
+		names := make([]string, 0, len(scoreFormulas))
+		for n := range scoreFormulas {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return nil, fmt.Errorf("unknown score formula %q, want one of %s", name, strings.Join(names, ", "))
+	}
+	return fn, nil
+}
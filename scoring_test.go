@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestScoreFormulas(t *testing.T) {
+	tests := []struct {
+		formula string
+		params  ScoreParams
+		want    int
+	}{
+		{"raw", ScoreParams{Pipes: 0}, 0},
+		{"raw", ScoreParams{Pipes: 42, Mode: "hard"}, 42},
+		{"difficulty-weighted", ScoreParams{Pipes: 42, Mode: defaultMode}, 42},
+		{"difficulty-weighted", ScoreParams{Pipes: 42, Mode: "easy"}, 21},
+		{"difficulty-weighted", ScoreParams{Pipes: 42, Mode: "hard"}, 63},
+		{"difficulty-weighted", ScoreParams{Pipes: 42, Mode: "expert"}, 84},
+		{"difficulty-weighted", ScoreParams{Pipes: 42, Mode: "custom"}, 42},
+		// Weighted scores round down
+		{"difficulty-weighted", ScoreParams{Pipes: 5, Mode: "easy"}, 2},
+		{"difficulty-weighted", ScoreParams{Pipes: maxParamPipes, Mode: "expert"}, maxParamPipes * 2},
+	}
+	for _, tt := range tests {
+		fn, err := lookupScoreFormula(tt.formula)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fn(tt.params); got != tt.want {
+			t.Errorf("%s(%+v) = %d, want %d", tt.formula, tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestLookupScoreFormulaUnknown(t *testing.T) {
+	for _, name := range []string{"", "Raw", "weighted"} {
+		if _, err := lookupScoreFormula(name); err == nil {
+			t.Errorf("lookupScoreFormula(%q) accepted it", name)
+		}
+	}
+}
+
+func TestSubmitScoreParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   float64
+	}{
+		{name: "scored by the server", body: `{"name":"ann","params":{"pipes":20},"mode":"hard"}`, status: http.StatusCreated, want: 30},
+		{name: "matching client score", body: `{"name":"ann","score":10,"params":{"pipes":20},"mode":"easy"}`, status: http.StatusCreated, want: 10},
+		{name: "mismatched client score", body: `{"name":"ann","score":99,"params":{"pipes":20},"mode":"easy"}`, status: http.StatusUnprocessableEntity},
+		{name: "negative pipes", body: `{"name":"ann","params":{"pipes":-1}}`, status: http.StatusUnprocessableEntity},
+		{name: "too many pipes", body: `{"name":"ann","params":{"pipes":` + strconv.Itoa(maxParamPipes+1) + `}}`, status: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(WithScoreFormula(scoreFormulas["difficulty-weighted"]))
+			rec := do(testHandler(s), http.MethodPost, "/api/scores", tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusCreated {
+				return
+			}
+			var board []Score
+			if err := json.Unmarshal(do(testHandler(s), http.MethodGet, "/api/leaderboard", "").Body.Bytes(), &board); err != nil {
+				t.Fatal(err)
+			}
+			if len(board) != 1 || board[0].Score != tt.want {
+				t.Errorf("board = %+v, want one score of %v", board, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Board invariants checked by the self-check, used as metric labels
+const (
+	invariantSorted = "sorted"
+	invariantSize   = "size"
+	invariantDedup  = "dedup"
+	invariantUTC    = "utc"
+)
+
+// boardViolation is a broken board invariant and the first rank it was
+// seen at
+type boardViolation struct {
+	Invariant string
+	Rank      int
+}
+
+// checkInvariants verifies, read-only, what every write path must keep
+// true of the board: sorted best first, no longer than Size, one entry
+// per player under dedup, and UTC timestamps. Each broken invariant is
+// reported once.
+func (lb *Leaderboard) checkInvariants() []boardViolation {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	var violations []boardViolation
+	found := make(map[string]bool)
+	report := func(invariant string, rank int) {
+		if !found[invariant] {
+			found[invariant] = true
+			violations = append(violations, boardViolation{Invariant: invariant, Rank: rank})
+		}
+	}
+
+	if len(lb.entries) > lb.Size() {
+		report(invariantSize, lb.Size()+1)
+	}
+	// Sorted by the same value, as of the same time, as the last sort
+	value := lb.rankValue(lb.rankedAt)
+	seen := make(map[string]bool, len(lb.entries))
+	for i, e := range lb.entries {
+		if i > 0 && value(lb.entries[i-1]) < value(e) {
+			report(invariantSorted, i+1)
+		}
+		if lb.dedup && seen[e.Name] {
+			report(invariantDedup, i+1)
+		}
+		seen[e.Name] = true
+		if e.Timestamp.Location() != time.UTC {
+			report(invariantUTC, i+1)
+		}
+	}
+	return violations
+}
+
+// WithSelfCheck checks the board's invariants every interval; 0 disables
+// the check
+func WithSelfCheck(interval time.Duration) Option {
+	return func(s *Server) { s.selfCheckInterval = interval }
+}
+
+// selfCheck checks the board once, logging and counting every violation
+func (s *Server) selfCheck() {
+	for _, v := range s.lb.checkInvariants() {
+		s.metrics.invariantViolations.WithLabelValues(v.Invariant).Inc()
+		slog.Error("leaderboard invariant violated", "invariant", v.Invariant, "rank", v.Rank)
+	}
+}
+
+// runSelfCheck checks the board on the configured schedule until ctx is
+// cancelled
+func (s *Server) runSelfCheck(ctx context.Context) {
+	ticker := time.NewTicker(s.selfCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.selfCheck()
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfCheckDetectsCorruption(t *testing.T) {
+	tests := []struct {
+		name    string
+		dedup   bool
+		corrupt func(lb *Leaderboard)
+		want    []boardViolation
+	}{
+		{name: "healthy", corrupt: func(*Leaderboard) {}},
+		{
+			name:    "out of order",
+			corrupt: func(lb *Leaderboard) { lb.entries[1], lb.entries[2] = lb.entries[2], lb.entries[1] },
+			want:    []boardViolation{{Invariant: invariantSorted, Rank: 3}},
+		},
+		{
+			name: "over size",
+			corrupt: func(lb *Leaderboard) {
+				lb.entries = append(lb.entries, Score{ID: 99, Name: "zed", Score: 0, Timestamp: lb.entries[0].Timestamp})
+			},
+			want: []boardViolation{{Invariant: invariantSize, Rank: 4}},
+		},
+		{
+			name:    "duplicate under dedup",
+			dedup:   true,
+			corrupt: func(lb *Leaderboard) { lb.entries[2].Name = lb.entries[0].Name },
+			want:    []boardViolation{{Invariant: invariantDedup, Rank: 3}},
+		},
+		{
+			name:    "duplicates allowed without dedup",
+			corrupt: func(lb *Leaderboard) { lb.entries[2].Name = lb.entries[0].Name },
+		},
+		{
+			name: "local timestamp",
+			corrupt: func(lb *Leaderboard) {
+				lb.entries[1].Timestamp = lb.entries[1].Timestamp.In(time.FixedZone("CEST", 2*60*60))
+			},
+			want: []boardViolation{{Invariant: invariantUTC, Rank: 2}},
+		},
+		{
+			name: "each invariant reported once",
+			corrupt: func(lb *Leaderboard) {
+				for i := range lb.entries {
+					lb.entries[i].Timestamp = lb.entries[i].Timestamp.In(time.FixedZone("CEST", 2*60*60))
+				}
+			},
+			want: []boardViolation{{Invariant: invariantUTC, Rank: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size, lb.dedup = 3, tt.dedup
+			s := NewServer(WithLeaderboard(lb))
+			h := testHandler(s)
+			for i, name := range []string{"ann", "bob", "cat"} {
+				do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":%q,"score":%d}`, name, 30-i*10))
+			}
+
+			lb.mu.Lock()
+			tt.corrupt(lb)
+			lb.mu.Unlock()
+
+			if got := lb.checkInvariants(); !slices.Equal(got, tt.want) {
+				t.Errorf("checkInvariants() = %+v, want %+v", got, tt.want)
+			}
+
+			s.selfCheck()
+			metrics := do(h, http.MethodGet, "/metrics", "").Body.String()
+			for _, v := range tt.want {
+				if line := fmt.Sprintf("flappy_board_invariant_violations_total{invariant=%q} 1", v.Invariant); !strings.Contains(metrics, line) {
+					t.Errorf("metrics lack %q", line)
+				}
+			}
+			if len(tt.want) == 0 && strings.Contains(metrics, "flappy_board_invariant_violations_total{") {
+				t.Error("metrics count violations on a healthy board")
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Server serves the game API on top of a Leaderboard. Everything a handler
+// depends on hangs off the Server so independent instances can coexist.
+type Server struct {
+	lb *Leaderboard
+	// store is what submissions are written to and the board is read
+	// from: lb, possibly behind a read cache
+	store         Store
+	cacheTTL      time.Duration
+	cacheMaxItems int
+	// queue serializes writes to store; nil writes directly
+	queue     *submitQueue
+	queueSize int
+	og        *ogImageCache
+	sockets   *socketHub
+	origins   *originPolicy
+	// follow, when set, makes this server a read-only copy of a primary;
+	// followerWrites is how it rejects writes, see WithFollowerWrites
+	follow         *follower
+	followerWrites string
+
+	// now is the clock used for sessions, windows, rate limiting and new
+	// entries
+	now func() time.Time
+
+	adminToken string
+	namePolicy *regexp.Regexp
+	window     SubmissionWindow
+
+	// maxSubmitRate sheds submissions above this many per second; 0 disables
+	maxSubmitRate float64
+	submitRate    *slidingCounter
+
+	claims     *nameClaims
+	exemptions *rateLimitExemptions
+	// rateLimits are the per-route limits, also applied to each game
+	// socket submission
+	rateLimits     *rateLimiter
+	sessions       *gameSessions
+	requireSession bool
+	maxScoreRate   float64
+	// scoreFn computes the score of submissions carrying game params
+	scoreFn ScoreFn
+	// scoreDecimals is how many decimal places a score may have; 0 keeps
+	// scores whole
+	scoreDecimals int
+	// recordAll records every accepted score; when false only scores
+	// beating the player's best are recorded
+	recordAll bool
+	// replay bounds the simulation run for submissions carrying a replay
+	replay replayBounds
+
+	// boardLabel names the board in leaderboard envelopes
+	boardLabel string
+	// config is the effective configuration, redacted before it is served
+	// or archived
+	config Config
+	// maxResponseEntries caps the entries in any list response; see
+	// capEntries
+	maxResponseEntries int
+
+	// pollInterval is the base of the jittered next-poll hint sent with
+	// the leaderboard; 0 sends no hint
+	pollInterval time.Duration
+
+	// backupDir receives a board snapshot every backupInterval (0 disables
+	// backups), keeping the newest backupKeep
+	backupDir      string
+	backupInterval time.Duration
+	backupKeep     int
+
+	// selfCheckInterval is how often the board's invariants are checked;
+	// 0 disables the check
+	selfCheckInterval time.Duration
+
+	// dailyDir holds archived boards of past days; dailyKeep bounds how
+	// many are retained
+	dailyDir  string
+	dailyKeep int
+
+	events    *EventLog
+	audit     *AuditLog
+	publisher *asyncPublisher
+
+	reports *reportQueue
+	// review holds scores the jump detector flagged
+	review *reviewQueue
+	jumps  jumpDetector
+	// signatures, when set, requires a valid X-Signature on submissions
+	signatures *signatureVerifier
+
+	// freeze, while set, pins the publicly served board
+	freeze boardFreeze
+
+	metrics    *serverMetrics
+	stats      *statsAccumulator
+	rejections rejectionCounter
+	prettyJSON bool
+}
+
+// Option configures a Server built by NewServer
+type Option func(*Server)
+
+// WithLeaderboard backs the server with lb instead of an empty board
+func WithLeaderboard(lb *Leaderboard) Option {
+	return func(s *Server) { s.lb = lb }
+}
+
+// WithClock replaces time.Now as the server's clock
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) { s.now = now }
+}
+
+// WithQueueSize routes writes through a submission queue of size entries;
+// 0 writes directly under the leaderboard lock
+func WithQueueSize(size int) Option {
+	return func(s *Server) { s.queueSize = size }
+}
+
+// WithBoardCache caches board reads for ttl, keeping up to maxItems
+// distinct board lengths; a ttl of 0 reads the board every time
+func WithBoardCache(ttl time.Duration, maxItems int) Option {
+	return func(s *Server) {
+		s.cacheTTL = ttl
+		s.cacheMaxItems = maxItems
+	}
+}
+
+// WithSignatureVerifier requires every submission to be signed in a way
+// sv accepts; nil leaves submissions unsigned
+func WithSignatureVerifier(sv *signatureVerifier) Option {
+	return func(s *Server) { s.signatures = sv }
+}
+
+// WithAdminToken enables the admin API behind token
+func WithAdminToken(token string) Option {
+	return func(s *Server) { s.adminToken = token }
+}
+
+// WithNamePolicy sets the allowlist every player name must match
+func WithNamePolicy(policy *regexp.Regexp) Option {
+	return func(s *Server) { s.namePolicy = policy }
+}
+
+// WithSubmissionWindow only accepts submissions inside w
+func WithSubmissionWindow(w SubmissionWindow) Option {
+	return func(s *Server) { s.window = w }
+}
+
+// WithMaxSubmitRate sheds submissions above rate per second across all
+// clients
+func WithMaxSubmitRate(rate float64) Option {
+	return func(s *Server) { s.maxSubmitRate = rate }
+}
+
+// WithNameClaims uses nc for name reservations instead of an in-memory set
+func WithNameClaims(nc *nameClaims) Option {
+	return func(s *Server) { s.claims = nc }
+}
+
+// WithSessionPolicy sets whether submissions need a game session and the
+// highest plausible score per second of play
+func WithSessionPolicy(require bool, maxScoreRate float64) Option {
+	return func(s *Server) {
+		s.requireSession = require
+		s.maxScoreRate = maxScoreRate
+	}
+}
+
+// WithScoreDecimals accepts scores with up to decimals fractional digits,
+// e.g. survival times in seconds
+func WithScoreDecimals(decimals int) Option {
+	return func(s *Server) { s.scoreDecimals = decimals }
+}
+
+// WithScoreFormula scores submissions that carry game params with fn
+func WithScoreFormula(fn ScoreFn) Option {
+	return func(s *Server) { s.scoreFn = fn }
+}
+
+// WithRecordAllSubmissions sets whether scores that don't beat the
+// player's best are still recorded in history, stats and the event log.
+// The board itself only ever shows a player's best when dedup is on.
+func WithRecordAllSubmissions(all bool) Option {
+	return func(s *Server) { s.recordAll = all }
+}
+
+// WithReplayBounds limits replays to maxFrames simulated frames and
+// maxTaps taps
+func WithReplayBounds(maxFrames, maxTaps int) Option {
+	return func(s *Server) { s.replay = replayBounds{MaxFrames: maxFrames, MaxTaps: maxTaps} }
+}
+
+// WithBoardLabel names the board in leaderboard envelopes, e.g. "hard-eu"
+// when several game variants each run their own server
+func WithBoardLabel(label string) Option {
+	return func(s *Server) { s.boardLabel = label }
+}
+
+// WithMaxResponseEntries caps list responses at max entries, guarding
+// against a misconfigured board size
+func WithMaxResponseEntries(max int) Option {
+	return func(s *Server) { s.maxResponseEntries = max }
+}
+
+// WithPollInterval hints leaderboard pollers to come back after roughly
+// base, jittered per response
+func WithPollInterval(base time.Duration) Option {
+	return func(s *Server) { s.pollInterval = base }
+}
+
+// WithBackups snapshots the board into dir every interval, keeping the
+// newest keep snapshots
+func WithBackups(dir string, interval time.Duration, keep int) Option {
+	return func(s *Server) {
+		s.backupDir = dir
+		s.backupInterval = interval
+		s.backupKeep = keep
+	}
+}
+
+// WithDailyArchive archives each day's final board into dir on the daily
+// reset, keeping the newest keep days
+func WithDailyArchive(dir string, keep int) Option {
+	return func(s *Server) {
+		s.dailyDir = dir
+		s.dailyKeep = keep
+	}
+}
+
+// WithEventLog records accepted submissions to el
+func WithEventLog(el *EventLog) Option {
+	return func(s *Server) { s.events = el }
+}
+
+// WithPublisher sends the outcome of every accepted submission to p,
+// asynchronously and in order
+func WithPublisher(p Publisher) Option {
+	return func(s *Server) { s.publisher = newAsyncPublisher(p) }
+}
+
+// WithAuditLog records admin actions to al
+func WithAuditLog(al *AuditLog) Option {
+	return func(s *Server) { s.audit = al }
+}
+
+// WithPrettyJSON indents every JSON response
+func WithPrettyJSON(pretty bool) Option {
+	return func(s *Server) { s.prettyJSON = pretty }
+}
+
+// NewServer returns a Server configured by opts. Without options it serves
+// an empty board with the default name policy and no admin API.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		now:                time.Now,
+		namePolicy:         regexp.MustCompile(defaultNamePattern),
+		maxScoreRate:       1,
+		recordAll:          true,
+		scoreFn:            scoreFormulas[defaultScoreFormula],
+		boardLabel:         defaultBoardLabel,
+		maxResponseEntries: defaultMaxResponseEntries,
+		replay:             replayBounds{MaxFrames: defaultReplayMaxFrames, MaxTaps: defaultReplayMaxTaps},
+		og:                 newOGImageCache(),
+		sockets:            newSocketHub(),
+		stats:              newStatsAccumulator(),
+		reports:            newReportQueue(),
+		review:             newReviewQueue(),
+		submitRate:         newSlidingCounter(breakerWindow),
+		rejections:         rejectionCounter{counts: make(map[string]uint64)},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.lb == nil {
+		s.lb = NewLeaderboard()
+	}
+	s.lb.now = s.now
+	s.store = s.lb
+	if s.cacheTTL > 0 {
+		s.store = newCachedStore(s.lb, s.cacheTTL, s.cacheMaxItems, s.now)
+	}
+	if s.claims == nil {
+		s.claims = &nameClaims{hashes: make(map[string]string)}
+	}
+	if s.exemptions == nil {
+		s.exemptions = &rateLimitExemptions{names: make(map[string]bool)}
+	}
+	s.sessions = newGameSessions(s.now)
+	s.metrics = newServerMetrics(s)
+	s.lb.OnChange(func(ev BoardEvent) { s.metrics.boardChanges.WithLabelValues(string(ev.Kind)).Inc() })
+	if s.queueSize > 0 {
+		s.queue = newSubmitQueue(s.store, s.queueSize)
+	}
+	return s
+}
+
+// Shutdown closes every streaming client cleanly, waiting up to
+// socketDrainTimeout or until ctx is done for them to disconnect, and
+// stops the submission queue once it has applied what it holds
+func (s *Server) Shutdown(ctx context.Context) error {
+	sctx, cancel := context.WithTimeout(ctx, socketDrainTimeout)
+	defer cancel()
+	err := s.sockets.Shutdown(sctx)
+
+	if s.queue != nil {
+		err = errors.Join(err, s.queue.Close(ctx))
+	}
+
+	if s.publisher != nil {
+		err = errors.Join(err, s.publisher.Close(ctx))
+	}
+	return err
+}
+
+// routes registers every endpoint, falling back to static for anything
+// that isn't part of the API
+func (s *Server) routes(static http.Handler) http.Handler {
+	r := httprouter.New()
+
+	// API endpoints
+	r.POST("/api/scores", s.handleSubmitScore)
+	r.GET("/api/leaderboard", s.handleGetLeaderboard)
+	r.GET("/api/leaderboard/changes", s.handleGetChanges)
+	r.GET("/api/leaderboard/histogram", s.handleGetHistogram)
+	r.GET("/api/leaderboard/threshold", s.handleGetThreshold)
+	r.GET("/api/leaderboard/live", s.handleGetLiveBoard)
+	r.GET("/api/leaderboard/daily/:date", s.handleGetDailyBoard)
+	r.GET("/api/rank/:name", s.handleGetRank)
+	r.POST("/api/ranks", s.handleGetRanks)
+	r.GET("/api/stats", s.handleStats)
+	r.GET("/api/milestones", s.handleGetMilestones)
+	r.GET("/api/records.atom", s.handleRecordsFeed)
+	r.POST("/api/report", s.primaryOnly(s.handleReportPlayer))
+	r.GET("/api/players", s.handleListPlayers)
+	r.GET("/api/players/:name/bests", s.handleGetPlayerBests)
+	r.GET("/api/og/:name", s.handleOGImage)
+	r.POST("/api/names/claim", s.primaryOnly(s.handleClaimName))
+	r.GET("/api/game/start", s.primaryOnly(s.handleStartGame))
+	r.POST("/api/game/:sessionID/results", s.primaryOnly(s.handleSessionResults))
+	r.GET("/ws/game", s.handleGameSocket)
+	r.Handler(http.MethodGet, "/metrics", s.metrics.handler)
+	r.GET("/healthz", s.handleHealth)
+
+	// Admin endpoints
+	r.PATCH("/api/admin/scores/:name", s.requireAdmin(s.primaryOnly(s.handleRenamePlayer)))
+	r.GET("/api/admin/export", s.requireAdmin(s.handleExport))
+	r.GET("/api/admin/snapshot", s.requireAdmin(s.handleSnapshot))
+	r.GET("/api/admin/config", s.requireAdmin(s.handleGetConfig))
+	r.GET("/api/admin/backup", s.requireAdmin(s.handleBackupArchive))
+	r.POST("/api/admin/restore-archive", s.requireAdmin(s.primaryOnly(s.handleRestoreArchive)))
+	r.POST("/api/admin/scores/purge", s.requireAdmin(s.primaryOnly(s.handlePurgeScores)))
+	r.DELETE("/api/admin/scores/:id", s.requireAdmin(s.primaryOnly(s.handleDeleteScore)))
+	r.GET("/api/admin/stats/rejections", s.requireAdmin(s.handleRejectionStats))
+	r.GET("/api/admin/reports", s.requireAdmin(s.handleListReports))
+	r.GET("/api/admin/exemptions", s.requireAdmin(s.handleListExemptions))
+	r.PUT("/api/admin/exemptions/:name", s.requireAdmin(s.primaryOnly(s.handleSetExemption)))
+	r.DELETE("/api/admin/exemptions/:name", s.requireAdmin(s.primaryOnly(s.handleSetExemption)))
+	r.GET("/api/admin/review", s.requireAdmin(s.handleListHeldScores))
+	r.POST("/api/admin/review/:id/approve", s.requireAdmin(s.primaryOnly(s.handleApproveHeldScore)))
+	r.DELETE("/api/admin/review/:id", s.requireAdmin(s.primaryOnly(s.handleRejectHeldScore)))
+	r.POST("/api/admin/freeze", s.requireAdmin(s.primaryOnly(s.handleFreeze)))
+	r.DELETE("/api/admin/freeze", s.requireAdmin(s.primaryOnly(s.handleUnfreeze)))
+
+	// Static files
+	r.NotFound = static
+
+	if s.prettyJSON {
+		return withPrettyJSON(r)
+	}
+	return r
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// sessionTTL is how long an unused game session stays valid
+	sessionTTL = time.Hour
+	// maxSessions bounds the number of outstanding game sessions
+	maxSessions = 100000
+)
+
+// gameSession is a game started with /api/game/start
+type gameSession struct {
+	Started time.Time
+	// Seed drives the game's pipe heights, so its input log can be replayed
+	Seed uint32
+}
+
+// gameSessions tracks games started with /api/game/start so submissions
+// can be checked against how long the game actually ran
+type gameSessions struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	started   map[string]gameSession
+	lastSweep time.Time
+}
+
+func newGameSessions(now func() time.Time) *gameSessions {
+	return &gameSessions{now: now, started: make(map[string]gameSession)}
+}
+
+// sweep drops expired sessions. gs.mu must be held.
+func (gs *gameSessions) sweep(t time.Time) {
+	for id, session := range gs.started {
+		if t.Sub(session.Started) > sessionTTL {
+			delete(gs.started, id)
+		}
+	}
+	gs.lastSweep = t
+}
+
+// Start begins a new session, returning false if too many are outstanding
+func (gs *gameSessions) Start() (string, gameSession, bool) {
+	t := gs.now()
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if t.Sub(gs.lastSweep) > time.Minute || len(gs.started) >= maxSessions {
+		gs.sweep(t)
+	}
+	if len(gs.started) >= maxSessions {
+		return "", gameSession{}, false
+	}
+
+	id := rand.Text()
+	var seed [4]byte
+	rand.Read(seed[:])
+	session := gameSession{Started: t, Seed: binary.LittleEndian.Uint32(seed[:])}
+	gs.started[id] = session
+	return id, session, true
+}
+
+// Finish consumes the session id and returns it. It returns false for
+// unknown, already used or expired sessions.
+func (gs *gameSessions) Finish(id string) (gameSession, bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	session, ok := gs.started[id]
+	if !ok {
+		return gameSession{}, false
+	}
+	delete(gs.started, id)
+	if gs.now().Sub(session.Started) > sessionTTL {
+		return gameSession{}, false
+	}
+	return session, true
+}
+
+// maxPlausibleScore is the highest score reachable in a game running for
+// elapsed, allowing one point of slack for timing jitter
+func (s *Server) maxPlausibleScore(elapsed time.Duration) float64 {
+	return math.Floor(elapsed.Seconds()*s.maxScoreRate) + 1
+}
+
+// handleStartGame handles GET /api/game/start
+func (s *Server) handleStartGame(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	id, session, ok := s.sessions.Start()
+	if !ok {
+		writeThrottled(w, http.StatusServiceUnavailable, "Too many active games, try again later", submitRetryAfter)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]any{"sessionId": id, "startedAt": session.Started, "seed": session.Seed})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func startGame(t *testing.T, h http.Handler) string {
+	t.Helper()
+	rec := do(h, http.MethodGet, "/api/game/start", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("starting a game: status %d", rec.Code)
+	}
+	var body struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.SessionID
+}
+
+func TestSubmitSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		// first is submitted with the session before the valid submission
+		first  string
+		status int
+	}{
+		{name: "plausible", elapsed: 10 * time.Second, status: http.StatusCreated},
+		{name: "implausible", elapsed: time.Second, status: http.StatusUnprocessableEntity},
+		{name: "after invalid meta", elapsed: 10 * time.Second, first: `"meta":[1]`, status: http.StatusCreated},
+		{name: "after invalid beatRank", elapsed: 10 * time.Second, first: `"beatRank":-1`, status: http.StatusCreated},
+		{name: "already used", elapsed: 10 * time.Second, first: `"meta":{}`, status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			s := NewServer(WithClock(func() time.Time { return now }), WithSessionPolicy(true, 1))
+			h := testHandler(s)
+			id := startGame(t, h)
+			now = now.Add(tt.elapsed)
+
+			if tt.first != "" {
+				do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":"ann","score":5,"sessionId":%q,%s}`, id, tt.first))
+			}
+			rec := do(h, http.MethodPost, "/api/scores", fmt.Sprintf(`{"name":"ann","score":5,"sessionId":%q}`, id))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Submission signature algorithms, as named in X-Sig-Alg
+const (
+	sigHMACSHA256 = "hmac-sha256"
+	sigEd25519    = "ed25519"
+)
+
+// defaultSignatureAlg is assumed when a signed request has no X-Sig-Alg
+const defaultSignatureAlg = sigHMACSHA256
+
+// supportedSignatureAlgs lists every algorithm the server knows how to check
+var supportedSignatureAlgs = []string{sigHMACSHA256, sigEd25519}
+
+var (
+	errUnknownSignatureAlg = errors.New("Unsupported signature algorithm")
+	errBadSignature        = errors.New("Invalid or missing signature")
+)
+
+// signatureVerifier checks submission bodies against an X-Signature header
+// using one of the allowed algorithms. Anything it can't verify is
+// rejected.
+type signatureVerifier struct {
+	verifiers map[string]func(body, sig []byte) bool
+}
+
+// newSignatureVerifier allows those of algs whose key is configured:
+// secret for hmac-sha256 and publicKey (base64) for ed25519. It returns
+// nil, nil when neither key is set, meaning submissions aren't signed.
+func newSignatureVerifier(algs []string, secret, publicKey string) (*signatureVerifier, error) {
+	if secret == "" && publicKey == "" {
+		return nil, nil
+	}
+
+	sv := &signatureVerifier{verifiers: make(map[string]func(body, sig []byte) bool)}
+	var errs []error
+	for _, alg := range algs {
+		alg = strings.ToLower(alg)
+		switch alg {
+		case sigHMACSHA256:
+			if secret == "" {
+				continue
+			}
+			key := []byte(secret)
+			sv.verifiers[alg] = func(body, sig []byte) bool {
+				mac := hmac.New(sha256.New, key)
+				mac.Write(body)
+				return hmac.Equal(mac.Sum(nil), sig)
+			}
+		case sigEd25519:
+			if publicKey == "" {
+				continue
+			}
+			raw, err := base64.StdEncoding.DecodeString(publicKey)
+			if err != nil || len(raw) != ed25519.PublicKeySize {
+				errs = append(errs, errors.New("signature algorithm ed25519 needs a base64 Ed25519 public key"))
+				continue
+			}
+			pub := ed25519.PublicKey(raw)
+			sv.verifiers[alg] = func(body, sig []byte) bool {
+				return ed25519.Verify(pub, body, sig)
+			}
+		default:
+			errs = append(errs, fmt.Errorf("unknown signature algorithm %q, supported: %s", alg, strings.Join(supportedSignatureAlgs, ", ")))
+		}
+	}
+	if len(sv.verifiers) == 0 && len(errs) == 0 {
+		errs = append(errs, errors.New("no allowed signature algorithm has its signing key set"))
+	}
+	return sv, errors.Join(errs...)
+}
+
+// Verify checks body against the base64 signature sig made with alg
+func (sv *signatureVerifier) Verify(alg, sig string, body []byte) error {
+	if alg == "" {
+		alg = defaultSignatureAlg
+	}
+	verify, ok := sv.verifiers[strings.ToLower(alg)]
+	if !ok {
+		return errUnknownSignatureAlg
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil || !verify(body, raw) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// Algorithms returns the allowed algorithms, sorted
+func (sv *signatureVerifier) Algorithms() []string {
+	algs := make([]string, 0, len(sv.verifiers))
+	for alg := range sv.verifiers {
+		algs = append(algs, alg)
+	}
+	slices.Sort(algs)
+	return algs
+}
+
+// signatureError is the rejection for a body that fails verification
+func (sv *signatureVerifier) signatureError(err error) *submitError {
+	serr := &submitError{Status: http.StatusUnauthorized, Message: err.Error()}
+	if errors.Is(err, errUnknownSignatureAlg) {
+		serr.Message += "; allowed: " + strings.Join(sv.Algorithms(), ", ")
+	}
+	return serr
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSubmitSignatures(t *testing.T) {
+	const secret = "shh"
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+
+	body := []byte(`{"name":"ann","score":10}`)
+	hmacSig := func(key string, b []byte) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(b)
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	edSig := func(key ed25519.PrivateKey, b []byte) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(key, b))
+	}
+
+	tests := []struct {
+		name   string
+		algs   []string
+		alg    string
+		sig    string
+		status int
+	}{
+		{name: "hmac-sha256", algs: supportedSignatureAlgs, alg: sigHMACSHA256, sig: hmacSig(secret, body), status: http.StatusCreated},
+		{name: "hmac-sha256 by default", algs: supportedSignatureAlgs, sig: hmacSig(secret, body), status: http.StatusCreated},
+		{name: "algorithm names ignore case", algs: supportedSignatureAlgs, alg: "HMAC-SHA256", sig: hmacSig(secret, body), status: http.StatusCreated},
+		{name: "hmac-sha256 wrong key", algs: supportedSignatureAlgs, alg: sigHMACSHA256, sig: hmacSig("guess", body), status: http.StatusUnauthorized},
+		{name: "ed25519", algs: supportedSignatureAlgs, alg: sigEd25519, sig: edSig(priv, body), status: http.StatusCreated},
+		{name: "ed25519 wrong key", algs: supportedSignatureAlgs, alg: sigEd25519, sig: edSig(otherPriv, body), status: http.StatusUnauthorized},
+		{name: "signature for another body", algs: supportedSignatureAlgs, alg: sigEd25519, sig: edSig(priv, []byte(`{"name":"ann","score":99}`)), status: http.StatusUnauthorized},
+		{name: "signed with the other algorithm", algs: supportedSignatureAlgs, alg: sigEd25519, sig: hmacSig(secret, body), status: http.StatusUnauthorized},
+		{name: "missing signature", algs: supportedSignatureAlgs, alg: sigHMACSHA256, status: http.StatusUnauthorized},
+		{name: "not base64", algs: supportedSignatureAlgs, alg: sigHMACSHA256, sig: "!!", status: http.StatusUnauthorized},
+		{name: "unknown algorithm", algs: supportedSignatureAlgs, alg: "md5", sig: hmacSig(secret, body), status: http.StatusUnauthorized},
+		{name: "algorithm not allowed", algs: []string{sigEd25519}, alg: sigHMACSHA256, sig: hmacSig(secret, body), status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sv, err := newSignatureVerifier(tt.algs, secret, base64.StdEncoding.EncodeToString(pub))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := testHandler(NewServer(WithSignatureVerifier(sv)))
+			req := httptest.NewRequest(http.MethodPost, "/api/scores", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.alg != "" {
+				req.Header.Set("X-Sig-Alg", tt.alg)
+			}
+			if tt.sig != "" {
+				req.Header.Set("X-Signature", tt.sig)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestNewSignatureVerifier(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	key := base64.StdEncoding.EncodeToString(pub)
+	tests := []struct {
+		name              string
+		algs              []string
+		secret, publicKey string
+		want              []string
+		err               bool
+	}{
+		{name: "unsigned", algs: supportedSignatureAlgs},
+		{name: "both keys", algs: supportedSignatureAlgs, secret: "shh", publicKey: key, want: []string{sigEd25519, sigHMACSHA256}},
+		{name: "only a secret", algs: supportedSignatureAlgs, secret: "shh", want: []string{sigHMACSHA256}},
+		{name: "only a public key", algs: supportedSignatureAlgs, publicKey: key, want: []string{sigEd25519}},
+		{name: "unknown algorithm", algs: []string{"md5"}, secret: "shh", err: true},
+		{name: "bad public key", algs: []string{sigEd25519}, publicKey: "AAAA", err: true},
+		{name: "no key for any allowed algorithm", algs: []string{sigEd25519}, secret: "shh", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sv, err := newSignatureVerifier(tt.algs, tt.secret, tt.publicKey)
+			if (err != nil) != tt.err {
+				t.Fatalf("newSignatureVerifier = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				return
+			}
+			if tt.want == nil {
+				if sv != nil {
+					t.Errorf("got a verifier allowing %v, want none", sv.Algorithms())
+				}
+				return
+			}
+			if got := sv.Algorithms(); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Algorithms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// stateArchiveFormat and stateArchiveVersion identify a state archive;
+	// restores refuse any other version
+	stateArchiveFormat  = "flappy-gopher-state"
+	stateArchiveVersion = 1
+	// maxStateArchiveBytes caps an uploaded archive, compressed and not
+	maxStateArchiveBytes = 64 << 20
+)
+
+// Files making up a state archive
+const (
+	archiveManifestFile   = "manifest.json"
+	archiveBoardFile      = "leaderboard.json"
+	archiveHistoryFile    = "history.json"
+	archiveMilestonesFile = "milestones.json"
+	archiveClaimsFile     = "claims.json"
+	archiveExemptionsFile = "exemptions.json"
+	archiveReportsFile    = "reports.json"
+	archiveReviewFile     = "review.json"
+	archiveConfigFile     = "config.json"
+)
+
+// stateManifest describes a state archive
+type stateManifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Board     string    `json:"board"`
+}
+
+// boardState is the leaderboard's own part of a state archive
+type boardState struct {
+	BoardSince time.Time `json:"boardSince"`
+	// RankedAt and ScoreDecay say what order Entries are in: by score
+	// decayed as of RankedAt, or by raw score when ScoreDecay is 0
+	RankedAt   time.Time   `json:"rankedAt"`
+	ScoreDecay Duration    `json:"scoreDecay"`
+	Entries    []Score     `json:"entries"`
+	History    []Score     `json:"-"`
+	Milestones []Milestone `json:"-"`
+}
+
+// serverState is everything a state archive carries
+type serverState struct {
+	Board      boardState
+	Claims     map[string]string
+	Exemptions []string
+	Reports    []archivedReport
+	Review     []HeldScore
+}
+
+// archivedReport is a reported player with the addresses that reported
+// them, so distinct reporters still count once after a restore
+type archivedReport struct {
+	ReportedPlayer
+	Reporters []string `json:"reporters"`
+}
+
+// state copies the board, history and milestone winners under one lock
+func (lb *Leaderboard) state() boardState {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	st := boardState{
+		BoardSince: lb.boardSince,
+		RankedAt:   lb.rankedAt,
+		ScoreDecay: Duration{lb.decay},
+		Entries:    slices.Clone(lb.entries),
+		History:    slices.Clone(lb.history),
+		Milestones: []Milestone{},
+	}
+	if lb.milestones != nil {
+		st.Milestones = slices.Clone(lb.milestones.milestones)
+	}
+	return st
+}
+
+// restoreState replaces the board, history and milestone winners with st,
+// rebuilding everything derived from them. Only milestones for thresholds
+// configured here are kept. Stats and the live board start over.
+func (lb *Leaderboard) restoreState(st boardState) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.boardSince = st.BoardSince
+	lb.entries = slices.Clone(st.Entries)
+	lb.resort()
+	lb.entries = trimBoard(lb.entries, lb.Size())
+	lb.history = slices.Clone(st.History)
+	if lb.maxHistory > 0 && len(lb.history) > lb.maxHistory {
+		lb.history = lb.history[len(lb.history)-lb.maxHistory:]
+	}
+
+	lb.lastSubmit = make(map[string]time.Time)
+	lb.records = &recordLog{size: lb.records.size}
+	lb.nameKeys = nil
+	var maxID uint64
+	for _, e := range lb.history {
+		if t, ok := lb.lastSubmit[e.Name]; !ok || e.Timestamp.After(t) {
+			lb.lastSubmit[e.Name] = e.Timestamp
+		}
+		if e.Score >= float64(lb.minDisplayScore) {
+			lb.records.observe(e)
+		}
+		maxID = max(maxID, e.ID)
+	}
+	for _, e := range lb.entries {
+		maxID = max(maxID, e.ID)
+	}
+	lb.ids.Skip(maxID)
+	if lb.live != nil {
+		lb.live.entries = nil
+	}
+
+	if mt := lb.milestones; mt != nil {
+		for i := range mt.milestones {
+			m := &mt.milestones[i]
+			*m = Milestone{Threshold: m.Threshold}
+			if j := slices.IndexFunc(st.Milestones, func(s Milestone) bool { return s.Threshold == m.Threshold }); j >= 0 && st.Milestones[j].ReachedAt != nil {
+				*m = st.Milestones[j]
+			}
+		}
+		mt.save()
+	}
+	lb.recordChange(BoardReplace)
+}
+
+// writeStateArchive writes st as a gzipped tar of JSON files
+func writeStateArchive(w io.Writer, manifest stateManifest, st serverState, config Config) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	files := []struct {
+		name string
+		v    any
+	}{
+		{archiveManifestFile, manifest},
+		{archiveBoardFile, st.Board},
+		{archiveHistoryFile, st.Board.History},
+		{archiveMilestonesFile, st.Board.Milestones},
+		{archiveClaimsFile, st.Claims},
+		{archiveExemptionsFile, st.Exemptions},
+		{archiveReportsFile, st.Reports},
+		{archiveReviewFile, st.Review},
+		{archiveConfigFile, config},
+	}
+	for _, f := range files {
+		data, err := json.MarshalIndent(f.v, "", "  ")
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// errBadArchive marks an archive that can't be read at all, as opposed to
+// one whose contents fail validation
+var errBadArchive = errors.New("unreadable archive")
+
+// readStateArchive reads and validates an archive written by
+// writeStateArchive. The config snapshot is informational and ignored.
+func readStateArchive(r io.Reader) (serverState, error) {
+	var st serverState
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return st, fmt.Errorf("%w: %v", errBadArchive, err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return st, fmt.Errorf("%w: %v", errBadArchive, err)
+		}
+		if _, dup := files[hdr.Name]; dup {
+			return st, fmt.Errorf("duplicate file %s", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return st, fmt.Errorf("%w: %v", errBadArchive, err)
+		}
+		files[hdr.Name] = data
+	}
+
+	var manifest stateManifest
+	decode := func(name string, v any) error {
+		data, ok := files[name]
+		if !ok {
+			return fmt.Errorf("missing %s", name)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		return nil
+	}
+	// The manifest is checked first so a newer format is reported as such
+	// rather than as whatever part of it fails to decode
+	if err := decode(archiveManifestFile, &manifest); err != nil {
+		return st, err
+	}
+	if manifest.Format != stateArchiveFormat {
+		return st, fmt.Errorf("not a state archive (format %q)", manifest.Format)
+	}
+	if manifest.Version != stateArchiveVersion {
+		return st, fmt.Errorf("unsupported archive version %d, expected %d", manifest.Version, stateArchiveVersion)
+	}
+	if err := decode(archiveBoardFile, &st.Board); err != nil {
+		return st, err
+	}
+	if err := decode(archiveHistoryFile, &st.Board.History); err != nil {
+		return st, err
+	}
+	if err := decode(archiveMilestonesFile, &st.Board.Milestones); err != nil {
+		return st, err
+	}
+	if err := decode(archiveClaimsFile, &st.Claims); err != nil {
+		return st, err
+	}
+	if err := decode(archiveExemptionsFile, &st.Exemptions); err != nil {
+		return st, err
+	}
+	if err := decode(archiveReportsFile, &st.Reports); err != nil {
+		return st, err
+	}
+	if err := decode(archiveReviewFile, &st.Review); err != nil {
+		return st, err
+	}
+	return st, st.validate()
+}
+
+// validate checks everything a restore would apply
+func (st serverState) validate() error {
+	checkScores := func(file string, scores []Score) error {
+		ids := make(map[uint64]bool, len(scores))
+		for i, e := range scores {
+			switch {
+			case e.ID == 0:
+				return fmt.Errorf("%s: entry %d has no id", file, i)
+			case ids[e.ID]:
+				return fmt.Errorf("%s: duplicate id %d", file, e.ID)
+			case e.Name == "" || !utf8.ValidString(e.Name) || utf8.RuneCountInString(e.Name) > maxNameLength:
+				return fmt.Errorf("%s: entry %d has an invalid name", file, i)
+			case math.IsNaN(e.Score) || math.IsInf(e.Score, 0):
+				return fmt.Errorf("%s: entry %d has an invalid score", file, i)
+			case e.Timestamp.IsZero():
+				return fmt.Errorf("%s: entry %d has no timestamp", file, i)
+			}
+			ids[e.ID] = true
+		}
+		return nil
+	}
+	if err := checkScores(archiveBoardFile, st.Board.Entries); err != nil {
+		return err
+	}
+	value := func(e Score) float64 { return e.Score }
+	if d := st.Board.ScoreDecay.Duration; d > 0 {
+		value = func(e Score) float64 { return decayedScore(e, d, st.Board.RankedAt) }
+	}
+	if !slices.IsSortedFunc(st.Board.Entries, func(a, b Score) int { return cmp.Compare(value(b), value(a)) }) {
+		return fmt.Errorf("%s: entries are not sorted by score", archiveBoardFile)
+	}
+	if err := checkScores(archiveHistoryFile, st.Board.History); err != nil {
+		return err
+	}
+	if !slices.IsSortedFunc(st.Board.History, func(a, b Score) int { return a.Timestamp.Compare(b.Timestamp) }) {
+		return fmt.Errorf("%s: submissions are not in time order", archiveHistoryFile)
+	}
+	for _, m := range st.Board.Milestones {
+		if math.IsNaN(m.Threshold) || math.IsInf(m.Threshold, 0) || (m.ReachedAt != nil && m.Name == "") {
+			return fmt.Errorf("%s: invalid milestone %v", archiveMilestonesFile, m.Threshold)
+		}
+	}
+	for name, hash := range st.Claims {
+		if b, err := hex.DecodeString(hash); name == "" || err != nil || len(b) != 32 {
+			return fmt.Errorf("%s: invalid claim for %q", archiveClaimsFile, name)
+		}
+	}
+	for i, name := range st.Exemptions {
+		if name == "" || !utf8.ValidString(name) || utf8.RuneCountInString(name) > maxNameLength {
+			return fmt.Errorf("%s: entry %d has an invalid name", archiveExemptionsFile, i)
+		}
+	}
+	reported := make(map[string]bool, len(st.Reports))
+	for i, p := range st.Reports {
+		switch {
+		case p.Name == "" || reported[p.Name]:
+			return fmt.Errorf("%s: entry %d has a missing or duplicate name", archiveReportsFile, i)
+		case p.Count < 1 || len(p.Reporters) == 0 || len(p.Reasons) > reportReasonsKept:
+			return fmt.Errorf("%s: entry %d has invalid counts", archiveReportsFile, i)
+		}
+		reported[p.Name] = true
+	}
+	held := make(map[uint64]bool, len(st.Review))
+	for i, h := range st.Review {
+		switch {
+		case h.ID == 0 || held[h.ID]:
+			return fmt.Errorf("%s: entry %d has a missing or duplicate id", archiveReviewFile, i)
+		case h.Name == "" || !utf8.ValidString(h.Name) || utf8.RuneCountInString(h.Name) > maxNameLength:
+			return fmt.Errorf("%s: entry %d has an invalid name", archiveReviewFile, i)
+		case math.IsNaN(h.Score) || math.IsInf(h.Score, 0) || h.Score < 0:
+			return fmt.Errorf("%s: entry %d has an invalid score", archiveReviewFile, i)
+		case h.HeldAt.IsZero():
+			return fmt.Errorf("%s: entry %d has no time", archiveReviewFile, i)
+		}
+		held[h.ID] = true
+	}
+	if len(st.Review) > maxHeldScores {
+		return fmt.Errorf("%s: more than %d held scores", archiveReviewFile, maxHeldScores)
+	}
+	return nil
+}
+
+// WithConfigSnapshot sets the configuration reported by the admin API and
+// included in state archives
+func WithConfigSnapshot(c Config) Option {
+	return func(s *Server) { s.config = c }
+}
+
+// handleBackupArchive handles GET /api/admin/backup, serving the board,
+// history, milestone winners, name claims, rate limit exemptions, player
+// reports, held scores and a redacted config snapshot as a single .tar.gz
+func (s *Server) handleBackupArchive(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	now := s.now().UTC()
+	st := serverState{
+		Board:      s.lb.state(),
+		Claims:     s.claims.snapshot(),
+		Exemptions: s.exemptions.List(),
+		Reports:    s.reports.snapshot(),
+		Review:     s.review.List(),
+	}
+	manifest := stateManifest{Format: stateArchiveFormat, Version: stateArchiveVersion, CreatedAt: now, Board: s.boardLabel}
+
+	var buf bytes.Buffer
+	if err := writeStateArchive(&buf, manifest, st, s.config.redacted()); err != nil {
+		loggerFrom(r.Context()).Error("failed to write state archive", "err", err)
+		http.Error(w, "Failed to prepare backup", http.StatusInternalServerError)
+		return
+	}
+	s.auditLog(r, "backup", "", map[string]any{"entries": len(st.Board.Entries), "history": len(st.Board.History), "held": len(st.Review)})
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="flappy-gopher-%s.tar.gz"`, now.Format(backupTimeLayout)))
+	w.Write(buf.Bytes())
+}
+
+// handleRestoreArchive handles POST /api/admin/restore-archive, replacing
+// the server's state with an archive from GET /api/admin/backup. Nothing
+// is applied unless the whole archive is valid.
+func (s *Server) handleRestoreArchive(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	st, err := readStateArchive(http.MaxBytesReader(w, r.Body, maxStateArchiveBytes))
+	if errors.Is(err, errBadArchive) {
+		http.Error(w, "Request body must be a .tar.gz state archive", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid archive: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Claims and exemptions are the only parts that can fail to apply, so
+	// they go first, and the claims are put back if the exemptions fail
+	logger := loggerFrom(r.Context())
+	prevClaims := s.claims.snapshot()
+	if err := s.claims.replace(st.Claims); err != nil {
+		logger.Error("failed to store restored name claims", "err", err)
+		http.Error(w, "Failed to restore archive", http.StatusInternalServerError)
+		return
+	}
+	if err := s.exemptions.replace(st.Exemptions); err != nil {
+		logger.Error("failed to store restored rate limit exemptions", "err", err)
+		if err := s.claims.replace(prevClaims); err != nil {
+			logger.Error("failed to put back name claims", "err", err)
+		}
+		http.Error(w, "Failed to restore archive", http.StatusInternalServerError)
+		return
+	}
+	s.lb.restoreState(st.Board)
+	s.reports.replace(st.Reports)
+	s.review.replace(st.Review)
+	// Held scores keep their IDs when approved, so new scores must not
+	// reuse them
+	var maxHeldID uint64
+	for _, h := range st.Review {
+		maxHeldID = max(maxHeldID, h.ID)
+	}
+	s.lb.ids.Skip(maxHeldID)
+
+	logger.Info("admin restored state archive", "entries", len(st.Board.Entries), "history", len(st.Board.History), "claims", len(st.Claims), "held", len(st.Review))
+	s.auditLog(r, "restore", "", map[string]any{"entries": len(st.Board.Entries), "history": len(st.Board.History), "claims": len(st.Claims), "held": len(st.Review)})
+
+	if s.backupInterval > 0 {
+		if _, err := s.backupNow(); err != nil {
+			logger.Error("failed to persist board after restore", "dir", s.backupDir, "err", err)
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"status":  "success",
+		"entries": len(st.Board.Entries),
+		"history": len(st.Board.History),
+		"claims":  len(st.Claims),
+		"held":    len(st.Review),
+	})
+}
+
+// snapshot returns a copy of the claimed names and their token hashes
+func (nc *nameClaims) snapshot() map[string]string {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return maps.Clone(nc.hashes)
+}
+
+// replace swaps every claim for hashes, leaving the current ones in place
+// if they can't be persisted
+func (nc *nameClaims) replace(hashes map[string]string) error {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if hashes == nil {
+		hashes = make(map[string]string)
+	}
+	if nc.path != "" {
+		if err := writeJSONFile(nc.path, hashes); err != nil {
+			return err
+		}
+	}
+	nc.hashes = maps.Clone(hashes)
+	return nil
+}
+
+// replace swaps every exemption for names, leaving the current ones in
+// place if they can't be persisted
+func (ex *rateLimitExemptions) replace(names []string) error {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	prev := ex.names
+	ex.names = make(map[string]bool, len(names))
+	for _, name := range names {
+		ex.names[name] = true
+	}
+	if ex.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(ex.path, ex.sorted()); err != nil {
+		ex.names = prev
+		return err
+	}
+	return nil
+}
+
+// snapshot returns a copy of every reported player with their reporters
+func (rq *reportQueue) snapshot() []archivedReport {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	list := make([]archivedReport, 0, len(rq.players))
+	for _, p := range rq.players {
+		a := archivedReport{ReportedPlayer: *p, Reporters: slices.Sorted(maps.Keys(p.reporters))}
+		a.Reasons = slices.Clone(p.Reasons)
+		a.reporters = nil
+		list = append(list, a)
+	}
+	slices.SortFunc(list, func(a, b archivedReport) int { return cmp.Compare(a.Name, b.Name) })
+	return list
+}
+
+// replace swaps every report for reports. The repeat-report window starts
+// over, since when each address last reported isn't archived.
+func (rq *reportQueue) replace(reports []archivedReport) {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	rq.players = make(map[string]*ReportedPlayer, len(reports))
+	rq.seen = make(map[[2]string]time.Time)
+	for _, a := range reports {
+		p := a.ReportedPlayer
+		p.Reasons = slices.Clone(a.Reasons)
+		p.reporters = make(map[string]bool, len(a.Reporters))
+		for _, reporter := range a.Reporters {
+			p.reporters[reporter] = true
+		}
+		rq.players[p.Name] = &p
+	}
+}
+
+// replace swaps every held score for held
+func (rq *reviewQueue) replace(held []HeldScore) {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	rq.held = make(map[uint64]*HeldScore, len(held))
+	for _, h := range held {
+		// Archives are indented, metadata included
+		var meta bytes.Buffer
+		if len(h.Meta) > 0 && json.Compact(&meta, h.Meta) == nil {
+			h.Meta = meta.Bytes()
+		}
+		rq.held[h.ID] = &h
+	}
+}
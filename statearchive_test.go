@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAdminToken = "secret"
+
+// doAdmin sends one admin API request to h
+func doAdmin(h http.Handler, method, target string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func archiveTestServer(now time.Time) *Server {
+	lb := NewLeaderboard()
+	lb.size = 2
+	lb.now = func() time.Time { return now }
+	lb.milestones = &milestoneTracker{milestones: []Milestone{{Threshold: 10}}}
+	claims, _ := loadNameClaims("")
+	exemptions, _ := loadExemptions("", nil)
+	return NewServer(WithLeaderboard(lb), WithClock(func() time.Time { return now }), WithAdminToken(testAdminToken), WithNameClaims(claims), WithExemptions(exemptions))
+}
+
+// archivedState is what a backup should carry over, in comparable form
+type archivedState struct {
+	Board      []Score
+	History    []Score
+	Milestones []Milestone
+	Claims     map[string]string
+	Exemptions []string
+	Reports    []ReportedPlayerView
+	Review     []HeldScore
+}
+
+func archivedStateOf(s *Server) archivedState {
+	return archivedState{
+		Board:      s.lb.GetTopScores(),
+		History:    s.lb.History(),
+		Milestones: s.lb.Milestones(),
+		Claims:     s.claims.snapshot(),
+		Exemptions: s.exemptions.List(),
+		Reports:    s.reports.List(),
+		Review:     s.review.List(),
+	}
+}
+
+func TestStateArchiveRoundTrip(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	src := archiveTestServer(now)
+	for i, e := range []struct {
+		name  string
+		score float64
+	}{{"ann", 5}, {"bob", 12}, {"cat", 30}} {
+		src.lb.restore(Score{ID: uint64(i + 1), Name: e.name, Score: e.score, Timestamp: now.Add(time.Duration(i) * time.Minute)})
+	}
+	src.lb.ids.Skip(3)
+	if _, err := src.claims.Claim("ann"); err != nil {
+		t.Fatal(err)
+	}
+	src.exemptions.Set("bob", true)
+	src.reports.Add("192.0.2.1", "cat", "too good", now)
+	src.reports.Add("192.0.2.2", "cat", "bot", now.Add(time.Minute))
+	src.review.Hold(HeldScore{ID: 7, Name: "ann", Score: 900, Mode: defaultMode, PreviousBest: 5, HeldAt: now, Meta: []byte(`{"run":1}`)})
+
+	rec := doAdmin(testHandler(src), http.MethodGet, "/api/admin/backup", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("backup: status %d: %s", rec.Code, rec.Body)
+	}
+	archive := rec.Body.Bytes()
+
+	dst := archiveTestServer(now)
+	dst.lb.restore(Score{ID: 1, Name: "zed", Score: 99, Timestamp: now})
+	dst.review.Hold(HeldScore{ID: 2, Name: "zed", Score: 1, HeldAt: now})
+	rec = doAdmin(testHandler(dst), http.MethodPost, "/api/admin/restore-archive", bytes.NewReader(archive))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore: status %d: %s", rec.Code, rec.Body)
+	}
+
+	want, got := archivedStateOf(src), archivedStateOf(dst)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("restored state:\n got %+v\nwant %+v", got, want)
+	}
+	if id := dst.lb.ids.Next(); id <= 7 {
+		t.Errorf("next ID after restore = %d, want past the held score's 7", id)
+	}
+
+	// Approving the restored held score records it as submitted
+	rec = doAdmin(testHandler(dst), http.MethodPost, "/api/admin/review/7/approve", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("approve: status %d: %s", rec.Code, rec.Body)
+	}
+	if top := dst.lb.GetTopScores(); top[0].ID != 7 || top[0].Name != "ann" || !top[0].Timestamp.Equal(now) {
+		t.Errorf("top entry after approval = %+v, want ann's held score 7", top[0])
+	}
+}
+
+func TestStateArchiveValidate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	valid := func() serverState {
+		return serverState{
+			Board: boardState{
+				Entries: []Score{{ID: 2, Name: "bob", Score: 12, Timestamp: now}, {ID: 1, Name: "ann", Score: 5, Timestamp: now}},
+				History: []Score{{ID: 1, Name: "ann", Score: 5, Timestamp: now}},
+			},
+			Exemptions: []string{"bob"},
+			Reports:    []archivedReport{{ReportedPlayer: ReportedPlayer{Name: "ann", Count: 1}, Reporters: []string{"192.0.2.1"}}},
+			Review:     []HeldScore{{ID: 3, Name: "ann", Score: 90, HeldAt: now}},
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(*serverState)
+		err    string
+	}{
+		{name: "valid", change: func(*serverState) {}},
+		{name: "unsorted board", change: func(st *serverState) { st.Board.Entries[0].Score = 1 }, err: "not sorted"},
+		{name: "entry without id", change: func(st *serverState) { st.Board.Entries[0].ID = 0 }, err: "no id"},
+		{name: "empty exemption", change: func(st *serverState) { st.Exemptions = append(st.Exemptions, "") }, err: archiveExemptionsFile},
+		{name: "duplicate report", change: func(st *serverState) { st.Reports = append(st.Reports, st.Reports[0]) }, err: archiveReportsFile},
+		{name: "report without reporters", change: func(st *serverState) { st.Reports[0].Reporters = nil }, err: archiveReportsFile},
+		{name: "duplicate held score", change: func(st *serverState) { st.Review = append(st.Review, st.Review[0]) }, err: archiveReviewFile},
+		{name: "held score without time", change: func(st *serverState) { st.Review[0].HeldAt = time.Time{} }, err: archiveReviewFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := valid()
+			tt.change(&st)
+			err := st.validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("validate() = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("validate() = %v, want an error mentioning %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkWebDir reports why dir would fail to serve the game page: a
+// missing or unreadable index file, or no files at all
+func checkWebDir(dir, index string) error {
+	path := filepath.Join(dir, index)
+	fi, err := os.Stat(path)
+	if err != nil {
+		entries, _ := os.ReadDir(dir)
+		if len(entries) == 0 {
+			return fmt.Errorf("web directory %s is empty", dir)
+		}
+		return fmt.Errorf("web directory %s has no %s: %w", dir, index, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	return nil
+}
+
+// staticHandler serves the files under dir, answering directory requests
+// with the given index file
+func staticHandler(dir, index string) (http.Handler, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("web directory: %w", err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("web directory %s is not a directory", dir)
+	}
+
+	fs := http.FileServer(http.Dir(dir))
+	if index == "index.html" {
+		// http.FileServer already handles the default index
+		return fs, nil
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			r = r.Clone(r.Context())
+			r.URL.Path += index
+		}
+		fs.ServeHTTP(w, r)
+	}), nil
+}
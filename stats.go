@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// defaultMode buckets submissions that don't name a difficulty mode
+	defaultMode = "default"
+	// otherMode buckets modes seen after maxModes distinct ones
+	otherMode = "other"
+	// maxModes bounds how many distinct modes are tracked separately
+	maxModes = 32
+)
+
+var modePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// parseMode validates a submission's difficulty mode; an empty mode is
+// the default one
+func parseMode(mode string) (string, error) {
+	if mode == "" {
+		return defaultMode, nil
+	}
+	if !modePattern.MatchString(mode) {
+		return "", errors.New("Mode must be 1-32 lowercase letters, digits, '-' or '_'")
+	}
+	return mode, nil
+}
+
+// scoreAggregate is a running count, total and best of accepted scores
+type scoreAggregate struct {
+	Count int     `json:"count"`
+	Total float64 `json:"-"`
+	Best  float64 `json:"best"`
+}
+
+func (a *scoreAggregate) add(score float64) {
+	if a.Count == 0 || score > a.Best {
+		a.Best = score
+	}
+	a.Count++
+	a.Total += score
+}
+
+// ScoreStats is an aggregate as reported by /api/stats
+type ScoreStats struct {
+	scoreAggregate
+	Average float64 `json:"average"`
+}
+
+func (a scoreAggregate) stats() ScoreStats {
+	st := ScoreStats{scoreAggregate: a}
+	if a.Count > 0 {
+		st.Average = a.Total / float64(a.Count)
+	}
+	return st
+}
+
+// statsAccumulator tracks accepted submissions overall and per mode
+type statsAccumulator struct {
+	mu     sync.Mutex
+	global scoreAggregate
+	modes  map[string]*scoreAggregate
+}
+
+func newStatsAccumulator() *statsAccumulator {
+	return &statsAccumulator{modes: make(map[string]*scoreAggregate)}
+}
+
+// Record adds an accepted score under mode
+func (sa *statsAccumulator) Record(mode string, score float64) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.global.add(score)
+	agg, ok := sa.modes[mode]
+	if !ok {
+		if len(sa.modes) >= maxModes {
+			mode = otherMode
+		}
+		if agg, ok = sa.modes[mode]; !ok {
+			agg = &scoreAggregate{}
+			sa.modes[mode] = agg
+		}
+	}
+	agg.add(score)
+}
+
+// aggregateState is a scoreAggregate with its total, as kept in snapshot
+// events
+type aggregateState struct {
+	Count int     `json:"count"`
+	Total float64 `json:"total"`
+	Best  float64 `json:"best"`
+}
+
+// statsState is every aggregate a statsAccumulator holds
+type statsState struct {
+	Global aggregateState            `json:"global"`
+	Modes  map[string]aggregateState `json:"modes"`
+}
+
+// snapshot copies the aggregates
+func (sa *statsAccumulator) snapshot() statsState {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	st := statsState{Global: aggregateState(sa.global), Modes: make(map[string]aggregateState, len(sa.modes))}
+	for mode, agg := range sa.modes {
+		st.Modes[mode] = aggregateState(*agg)
+	}
+	return st
+}
+
+// restore replaces the aggregates with st
+func (sa *statsAccumulator) restore(st statsState) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.global = scoreAggregate(st.Global)
+	sa.modes = make(map[string]*scoreAggregate, len(st.Modes))
+	for mode, agg := range st.Modes {
+		a := scoreAggregate(agg)
+		sa.modes[mode] = &a
+	}
+}
+
+// StatsResponse is the body of GET /api/stats
+type StatsResponse struct {
+	ScoreStats
+	Modes map[string]ScoreStats `json:"modes"`
+}
+
+// Stats returns the global and per-mode aggregates
+func (sa *statsAccumulator) Stats() StatsResponse {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	resp := StatsResponse{
+		ScoreStats: sa.global.stats(),
+		Modes:      make(map[string]ScoreStats, len(sa.modes)),
+	}
+	for mode, agg := range sa.modes {
+		resp.Modes[mode] = agg.stats()
+	}
+	return resp
+}
+
+// handleStats handles GET /api/stats
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// A frozen board's stats stop at the freeze, like the board
+	if stats, ok := s.freeze.Stats(); ok {
+		writeJSON(w, r, http.StatusOK, stats)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s.stats.Stats())
+}
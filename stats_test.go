@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestStatsPerMode(t *testing.T) {
+	type submission struct {
+		mode  string
+		score float64
+	}
+	tests := []struct {
+		name        string
+		submissions []submission
+		global      ScoreStats
+		modes       map[string]ScoreStats
+	}{
+		{
+			name:   "nothing submitted",
+			global: ScoreStats{},
+			modes:  map[string]ScoreStats{},
+		},
+		{
+			name:        "legacy submissions",
+			submissions: []submission{{"", 4}, {"", 8}},
+			global:      ScoreStats{scoreAggregate{Count: 2, Total: 12, Best: 8}, 6},
+			modes:       map[string]ScoreStats{defaultMode: {scoreAggregate{Count: 2, Total: 12, Best: 8}, 6}},
+		},
+		{
+			name:        "several modes",
+			submissions: []submission{{"easy", 10}, {"hard", 3}, {"easy", 20}, {"", 1}, {"hard", 5}},
+			global:      ScoreStats{scoreAggregate{Count: 5, Total: 39, Best: 20}, 7.8},
+			modes: map[string]ScoreStats{
+				"easy":      {scoreAggregate{Count: 2, Total: 30, Best: 20}, 15},
+				"hard":      {scoreAggregate{Count: 2, Total: 8, Best: 5}, 4},
+				defaultMode: {scoreAggregate{Count: 1, Total: 1, Best: 1}, 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			h := testHandler(s)
+			for i, sub := range tt.submissions {
+				body := fmt.Sprintf(`{"name":"p%d","score":%v,"mode":%q}`, i, sub.score, sub.mode)
+				if rec := do(h, http.MethodPost, "/api/scores", body); rec.Code != http.StatusCreated {
+					t.Fatalf("submit %s: status %d: %s", body, rec.Code, rec.Body)
+				}
+			}
+
+			got := s.stats.Stats()
+			want := StatsResponse{ScoreStats: tt.global, Modes: tt.modes}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Stats() = %+v, want %+v", got, want)
+			}
+
+			// The endpoint serves the same numbers, without totals
+			var body struct {
+				Count int                        `json:"count"`
+				Modes map[string]json.RawMessage `json:"modes"`
+			}
+			if err := json.Unmarshal(do(h, http.MethodGet, "/api/stats", "").Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Count != tt.global.Count || len(body.Modes) != len(tt.modes) {
+				t.Errorf("/api/stats count %d with %d modes, want %d with %d", body.Count, len(body.Modes), tt.global.Count, len(tt.modes))
+			}
+		})
+	}
+}
+
+func TestStatsModeOverflow(t *testing.T) {
+	sa := newStatsAccumulator()
+	for i := range maxModes + 3 {
+		sa.Record(fmt.Sprint("mode", i), 1)
+	}
+	sa.Record("mode0", 1)
+
+	st := sa.Stats()
+	if len(st.Modes) != maxModes+1 {
+		t.Errorf("%d modes tracked, want %d plus %q", len(st.Modes), maxModes, otherMode)
+	}
+	if got := st.Modes[otherMode].Count; got != 3 {
+		t.Errorf("%q count = %d, want 3", otherMode, got)
+	}
+	if got := st.Modes["mode0"].Count; got != 2 {
+		t.Errorf("known mode count = %d, want 2", got)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		mode, want string
+		ok         bool
+	}{
+		{"", defaultMode, true},
+		{"hard", "hard", true},
+		{"night_2-x", "night_2-x", true},
+		{"Hard", "", false},
+		{"a b", "", false},
+	}
+	for _, tt := range tests {
+		got, err := parseMode(tt.mode)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseMode(%q) = %q, %v; want %q, ok %v", tt.mode, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Store is where submissions are written and the board is read from. The
+// in-memory Leaderboard is the only backend today; wrapping it lets front
+// layers such as the read cache sit between the server and a slower one.
+type Store interface {
+	// Add records a score and reports whether it placed on the board
+	Add(ctx context.Context, name string, score float64) (bool, error)
+	// Top returns up to n of the best board entries, best first
+	Top(n int) []Score
+}
+
+// Add implements Store
+func (lb *Leaderboard) Add(ctx context.Context, name string, score float64) (bool, error) {
+	return lb.AddScore(ctx, name, score)
+}
+
+// Top implements Store
+func (lb *Leaderboard) Top(n int) []Score {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return slices.Clone(trimBoard(lb.ranked(), n))
+}
+
+// versioner is a Store that numbers its board versions, as the
+// Leaderboard does for every change
+type versioner interface {
+	Version() uint64
+}
+
+// cachedTop is a Top result, the backend version it was read at and when
+// it stops being served
+type cachedTop struct {
+	entries []Score
+	version uint64
+	expires time.Time
+}
+
+// cachedStore caches Top results of the Store it wraps for up to ttl,
+// keeping at most maxEntries distinct n. Every Add through it clears the
+// cache. A backend that is a versioner has every other change (renames,
+// purges, resets, restores) invalidate it too, as a result read at an
+// older version is never served; any other backend's show up once the
+// TTL runs out.
+type cachedStore struct {
+	Store
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu    sync.Mutex
+	cache map[int]cachedTop
+	// gen counts invalidations so a read racing an Add can't cache what
+	// it saw from before the write
+	gen uint64
+}
+
+func newCachedStore(backend Store, ttl time.Duration, maxEntries int, now func() time.Time) *cachedStore {
+	return &cachedStore{
+		Store:      backend,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        now,
+		cache:      make(map[int]cachedTop),
+	}
+}
+
+// Add writes through to the backend and invalidates cached reads
+func (cs *cachedStore) Add(ctx context.Context, name string, score float64) (bool, error) {
+	placed, err := cs.Store.Add(ctx, name, score)
+	if err == nil {
+		cs.mu.Lock()
+		clear(cs.cache)
+		cs.gen++
+		cs.mu.Unlock()
+	}
+	return placed, err
+}
+
+// version returns the backend's board version, or 0 if it doesn't have
+// one
+func (cs *cachedStore) version() uint64 {
+	if v, ok := cs.Store.(versioner); ok {
+		return v.Version()
+	}
+	return 0
+}
+
+// Top serves n from the cache while it is fresh and the backend is still
+// at the version it was read at
+func (cs *cachedStore) Top(n int) []Score {
+	t := cs.now()
+	// Taken before reading, so a change landing mid-read leaves the
+	// result tagged older than it is, not newer
+	version := cs.version()
+
+	cs.mu.Lock()
+	if c, ok := cs.cache[n]; ok && t.Before(c.expires) && c.version == version {
+		cs.mu.Unlock()
+		return slices.Clone(c.entries)
+	}
+	gen := cs.gen
+	cs.mu.Unlock()
+
+	entries := cs.Store.Top(n)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.gen != gen {
+		return entries
+	}
+	if _, ok := cs.cache[n]; !ok && len(cs.cache) >= cs.maxEntries {
+		// Make room by dropping whatever has expired or gone stale, and
+		// give up on caching this n if nothing has
+		for k, c := range cs.cache {
+			if !t.Before(c.expires) || c.version != version {
+				delete(cs.cache, k)
+			}
+		}
+		if len(cs.cache) >= cs.maxEntries {
+			return entries
+		}
+	}
+	cs.cache[n] = cachedTop{entries: slices.Clone(entries), version: version, expires: t.Add(cs.ttl)}
+	return entries
+}
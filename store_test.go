@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubmitContextAbortsSlowStore(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    func(context.Context) (context.Context, context.CancelFunc)
+		status int
+	}{
+		{
+			name: "deadline passes mid-write",
+			ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				return context.WithTimeout(ctx, 20*time.Millisecond)
+			},
+			status: http.StatusGatewayTimeout,
+		},
+		{
+			name: "client goes away mid-write",
+			ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(ctx)
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			status: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			store := newBlockingStore()
+			defer close(store.release)
+			s.store = store
+
+			ctx, cancel := tt.ctx(t.Context())
+			defer cancel()
+			req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/scores", strings.NewReader(`{"name":"ann","score":10}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			testHandler(s).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if n := store.added.Load(); n != 0 {
+				t.Errorf("store completed %d writes, want none", n)
+			}
+			if got := s.stats.Stats().Count; got != 0 {
+				t.Errorf("stats count = %d, want the aborted write left out", got)
+			}
+		})
+	}
+}
+
+func TestAddScoreCancelled(t *testing.T) {
+	lb := NewLeaderboard()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := lb.AddScore(ctx, "ann", 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("AddScore with a cancelled context = %v, want context.Canceled", err)
+	}
+	if top := lb.GetTopScores(); len(top) != 0 {
+		t.Errorf("board holds %+v, want nothing added", top)
+	}
+}
+
+// countingStore is a fake backend counting the reads that reach it
+type countingStore struct {
+	board []Score
+	reads int
+}
+
+func (cs *countingStore) Add(ctx context.Context, name string, score float64) (bool, error) {
+	cs.board = append(cs.board, Score{Name: name, Score: score})
+	return true, nil
+}
+
+func (cs *countingStore) Top(n int) []Score {
+	cs.reads++
+	return trimBoard(slices.Clone(cs.board), n)
+}
+
+// versionedStore is a countingStore that numbers its board versions
+type versionedStore struct {
+	countingStore
+	version uint64
+}
+
+func (vs *versionedStore) Version() uint64 { return vs.version }
+
+func TestCachedStore(t *testing.T) {
+	// Each step is "top N", "add", "change" to the backend directly, or
+	// "wait" for a TTL to pass
+	tests := []struct {
+		name       string
+		maxEntries int
+		// versioned backends report a new version on every change
+		versioned bool
+		steps     []string
+		// reads is how many reads reach the backend in all
+		reads int
+	}{
+		{name: "repeat reads hit the cache", maxEntries: 4, steps: []string{"top 10", "top 10", "top 10"}, reads: 1},
+		{name: "expired entries are refetched", maxEntries: 4, steps: []string{"top 10", "wait", "top 10", "top 10"}, reads: 2},
+		{name: "add invalidates", maxEntries: 4, steps: []string{"top 10", "add", "top 10", "top 10"}, reads: 2},
+		{name: "sizes are cached apart", maxEntries: 4, steps: []string{"top 10", "top 5", "top 10", "top 5"}, reads: 2},
+		{name: "full cache passes reads through", maxEntries: 1, steps: []string{"top 10", "top 5", "top 5", "top 10"}, reads: 3},
+		{name: "backend change invalidates", maxEntries: 4, versioned: true, steps: []string{"top 10", "change", "top 10", "top 10"}, reads: 2},
+		{name: "backend change waits out the TTL unversioned", maxEntries: 4, steps: []string{"top 10", "change", "top 10", "wait", "top 10"}, reads: 2},
+		{name: "stale entries make room", maxEntries: 1, versioned: true, steps: []string{"top 10", "change", "top 5", "top 5"}, reads: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			vs := &versionedStore{countingStore: countingStore{board: []Score{{Name: "ann", Score: 10}}}}
+			backend := &vs.countingStore
+			var store Store = backend
+			if tt.versioned {
+				store = vs
+			}
+			cs := newCachedStore(store, time.Second, tt.maxEntries, func() time.Time { return now })
+
+			for _, step := range tt.steps {
+				switch {
+				case step == "add":
+					if _, err := cs.Add(t.Context(), "bob", 20); err != nil {
+						t.Fatal(err)
+					}
+				case step == "change":
+					// As an admin rename would, behind the cache's back
+					backend.board[0].Name += "x"
+					vs.version++
+				case step == "wait":
+					now = now.Add(time.Second)
+				default:
+					var n int
+					fmt.Sscanf(step, "top %d", &n)
+					if got := cs.Top(n); len(got) != len(backend.board) {
+						t.Errorf("%s: got %d entries, want the backend's %d", step, len(got), len(backend.board))
+					}
+				}
+			}
+			if backend.reads != tt.reads {
+				t.Errorf("backend got %d reads, want %d", backend.reads, tt.reads)
+			}
+		})
+	}
+}
+
+func TestCachedBoardAfterAdminChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		// want is the board's names right after the change
+		want []string
+	}{
+		{name: "rename", method: http.MethodPatch, target: "/api/admin/scores/ann", body: `{"name":"anna"}`, want: []string{"bob", "anna"}},
+		{name: "purge", method: http.MethodPost, target: "/api/admin/scores/purge", body: `{"minScore":15,"maxScore":25}`, want: []string{"ann"}},
+		{name: "delete", method: http.MethodDelete, target: "/api/admin/scores/1", want: []string{"bob"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(WithBoardCache(time.Hour, 4), WithAdminToken(testAdminToken))
+			h := testHandler(s)
+			do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":10}`)
+			do(h, http.MethodPost, "/api/scores", `{"name":"bob","score":20}`)
+			do(h, http.MethodGet, "/api/leaderboard", "")
+
+			if rec := doAdmin(h, tt.method, tt.target, strings.NewReader(tt.body)); rec.Code != http.StatusOK {
+				t.Fatalf("%s: status %d: %s", tt.name, rec.Code, rec.Body)
+			}
+			var resp struct {
+				Entries []Score `json:"entries"`
+				Version uint64  `json:"version"`
+			}
+			if err := json.Unmarshal(do(h, http.MethodGet, "/api/leaderboard?envelope=true", "").Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range resp.Entries {
+				got = append(got, e.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("board = %v, want %v", got, tt.want)
+			}
+			if v := s.lb.Version(); resp.Version != v {
+				t.Errorf("envelope version = %d, want %d", resp.Version, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxMetaBytes caps the size of the optional submission metadata
+const maxMetaBytes = 1024
+
+// submitRequest is a score submission, shared by the HTTP and WebSocket
+// transports
+type submitRequest struct {
+	Name  string          `json:"name"`
+	Score float64         `json:"score"`
+	Meta  json.RawMessage `json:"meta"`
+	Token string          `json:"token"`
+	// SessionID is the id returned by /api/game/start for this game
+	SessionID string `json:"sessionId"`
+	// Mode is the difficulty the game was played on; empty is the default
+	Mode string `json:"mode"`
+	// BeatRank, when set, only records the score if it would place at or
+	// above that rank
+	BeatRank int `json:"beatRank"`
+	// Replay, when set, is the game's input log; the score must match a
+	// re-simulation of it
+	Replay *RunLog `json:"replay"`
+	// Params, when set, are the game's raw parameters; the score is then
+	// computed from them by the server's score formula
+	Params *ScoreParams `json:"params"`
+
+	// sessionChecked is set when the caller already consumed the session
+	// and checked the score against it
+	sessionChecked bool
+}
+
+// submitError is a rejected submission along with how to report it
+type submitError struct {
+	Status  int
+	Message string
+	// RetryAfter, when non-zero, is sent as a Retry-After header
+	RetryAfter time.Duration
+	// Body, when set, is sent as JSON instead of the plain message
+	Body any
+	// Location, when set, is sent as a Location header
+	Location string
+}
+
+// write reports the rejection over HTTP
+func (e *submitError) write(w http.ResponseWriter, r *http.Request) {
+	if e.Location != "" {
+		w.Header().Set("Location", e.Location)
+	}
+	switch {
+	case e.RetryAfter > 0:
+		writeThrottled(w, e.Status, e.Message, e.RetryAfter)
+	case e.Body != nil:
+		writeJSON(w, r, e.Status, e.Body)
+	default:
+		http.Error(w, e.Message, e.Status)
+	}
+}
+
+var errOverloaded = &submitError{
+	Status:     http.StatusServiceUnavailable,
+	Message:    "Too many submissions, try again later",
+	RetryAfter: submitRetryAfter,
+}
+
+// maxScoreDecimals bounds -score-decimals so scaled scores stay exact
+const maxScoreDecimals = 6
+
+// roundScore rounds score to decimals places, reporting false if that
+// changes it by more than floating point noise
+func roundScore(score float64, decimals int) (float64, bool) {
+	scale := math.Pow10(decimals)
+	rounded := math.Round(score*scale) / scale
+	return rounded, math.Abs(rounded-score) <= 1e-9*math.Max(1, score)
+}
+
+// formatScore renders a score without trailing zeros, e.g. "42" or "12.5"
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// admitSubmission applies the checks that don't need the request body, so
+// transports can shed load before decoding anything
+func (s *Server) admitSubmission(ctx context.Context) *submitError {
+	logger := loggerFrom(ctx)
+
+	if s.follow != nil {
+		s.countRejection(logger, rejectFollower)
+		return s.followerError("/api/scores")
+	}
+
+	if s.submissionsOverloaded() {
+		s.metrics.submissionsShed.Inc()
+		s.countRejection(logger, rejectOverloaded)
+		return errOverloaded
+	}
+
+	if !s.window.Open(s.now()) {
+		s.countRejection(logger, rejectOutsideWindow)
+		return s.window.closedError()
+	}
+	return nil
+}
+
+// submitResult is the outcome of an accepted submission
+type submitResult struct {
+	// Placed reports whether the score made the board
+	Placed bool
+	// Ignored is set when the score wasn't recorded because it didn't
+	// beat the player's best and only improvements are recorded
+	Ignored bool
+	// Best is the player's best score so far when Ignored is set
+	Best float64
+	// Unqualified is set when the score wasn't recorded because it
+	// wouldn't reach the requested beatRank
+	Unqualified bool
+	// WouldRank is where the score would have placed, 0 if off the board
+	WouldRank int
+	// Held is set when the score was queued for admin review instead of
+	// being recorded
+	Held bool
+}
+
+// acceptSubmission validates req and records it
+func (s *Server) acceptSubmission(ctx context.Context, req *submitRequest) (submitResult, *submitError) {
+	logger := loggerFrom(ctx)
+
+	name, err := s.sanitizeName(req.Name)
+	if err != nil {
+		s.countRejection(logger, rejectInvalidName, "name", req.Name, "err", err)
+		return submitResult{}, &submitError{Status: nameErrorStatus(err), Message: err.Error()}
+	}
+	req.Name = s.lb.CanonicalName(name)
+	if req.Name != name {
+		logger.Info("lookalike name merged into known player", "name", name, "player", req.Name)
+	}
+
+	if !s.claims.Authorized(req.Name, req.Token) {
+		s.countRejection(logger, rejectNameClaimed, "name", req.Name)
+		return submitResult{}, &submitError{Status: http.StatusForbidden, Message: "Name is claimed by another player"}
+	}
+
+	mode, err := parseMode(req.Mode)
+	if err != nil {
+		s.countRejection(logger, rejectInvalidMode, "name", req.Name, "mode", req.Mode)
+		return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	req.Mode = mode
+
+	if req.Params != nil {
+		if req.Params.Pipes < 0 || req.Params.Pipes > maxParamPipes {
+			s.countRejection(logger, rejectInvalidScore, "name", req.Name, "pipes", req.Params.Pipes)
+			return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: fmt.Sprintf("pipes must be between 0 and %d", maxParamPipes)}
+		}
+		req.Params.Mode = req.Mode
+		computed := float64(s.scoreFn(*req.Params))
+		// A client may still send the score it showed, but it must agree
+		if req.Score != 0 && req.Score != computed {
+			s.countRejection(logger, rejectInvalidScore, "name", req.Name, "score", req.Score, "computed", computed)
+			return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: fmt.Sprintf("Score does not match its params, which score %s", formatScore(computed))}
+		}
+		req.Score = computed
+	}
+
+	if req.Score < 0 {
+		s.countRejection(logger, rejectInvalidScore, "name", req.Name, "score", req.Score)
+		return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: "Invalid score"}
+	}
+	score, ok := roundScore(req.Score, s.scoreDecimals)
+	if !ok {
+		s.countRejection(logger, rejectInvalidScore, "name", req.Name, "score", req.Score)
+		if s.scoreDecimals == 0 {
+			return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: "Score must be a whole number"}
+		}
+		return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: fmt.Sprintf("Score must have at most %d decimal places", s.scoreDecimals)}
+	}
+	req.Score = score
+
+	// A replay is only trusted against a seed the server handed out
+	if req.Replay != nil && req.SessionID == "" {
+		s.countRejection(logger, rejectNoSession, "name", req.Name, "replay", true)
+		return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: "A replay needs the sessionId of the game it records"}
+	}
+
+	if len(req.Meta) > maxMetaBytes {
+		s.countRejection(logger, rejectInvalidMeta, "name", req.Name, "size", len(req.Meta))
+		return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: "Meta too large"}
+	}
+
+	if len(req.Meta) > 0 && string(req.Meta) != "null" {
+		var meta map[string]any
+		if err := json.Unmarshal(req.Meta, &meta); err != nil {
+			s.countRejection(logger, rejectInvalidMeta, "name", req.Name, "err", err)
+			return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: "Meta must be an object"}
+		}
+	} else {
+		req.Meta = nil
+	}
+
+	if size := s.lb.Size(); req.BeatRank < 0 || req.BeatRank > size {
+		s.countRejection(logger, rejectInvalidScore, "name", req.Name, "beatRank", req.BeatRank)
+		return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: fmt.Sprintf("beatRank must be between 1 and %d", size)}
+	}
+
+	// The session is only consumed by a submission that is otherwise
+	// valid, so a malformed one can be corrected and resent
+	if !req.sessionChecked && (req.SessionID != "" || s.requireSession) {
+		session, ok := s.sessions.Finish(req.SessionID)
+		if !ok {
+			s.countRejection(logger, rejectNoSession, "name", req.Name)
+			return submitResult{}, &submitError{Status: http.StatusForbidden, Message: "Invalid or expired game session"}
+		}
+		if elapsed := s.now().Sub(session.Started); req.Score > s.maxPlausibleScore(elapsed) {
+			s.countRejection(logger, rejectImplausible, "name", req.Name, "score", req.Score, "elapsed", elapsed)
+			return submitResult{}, &submitError{Status: http.StatusUnprocessableEntity, Message: "Score is not plausible for the game duration"}
+		}
+		if req.Replay != nil {
+			if serr := s.checkReplay(logger, req, session.Seed); serr != nil {
+				return submitResult{}, serr
+			}
+		}
+	}
+
+	// The check and the write aren't atomic, so two racing submissions
+	// from one player may both be recorded; that only costs an extra
+	// history entry
+	if !s.recordAll {
+		if best, ok := s.lb.PersonalBest(req.Name); ok && req.Score <= best {
+			logger.Info("score not recorded, no improvement", "name", req.Name, "score", req.Score, "best", best)
+			return submitResult{Ignored: true, Best: best}, nil
+		}
+	}
+
+	// Like the improvement check, this looks at the board just before the
+	// write, so a racing submission can still push the score down a rank
+	if req.BeatRank > 0 {
+		if rank := s.lb.ProspectiveRank(req.Name, req.Score); rank == 0 || rank > req.BeatRank {
+			logger.Info("score not recorded, did not qualify", "name", req.Name, "score", req.Score, "beatRank", req.BeatRank, "wouldRank", rank)
+			return submitResult{Unqualified: true, WouldRank: rank}, nil
+		}
+	}
+
+	if held, serr := s.checkJump(ctx, logger, req); serr != nil || held {
+		return submitResult{Held: held}, serr
+	}
+
+	placed, serr := s.recordSubmission(ctx, req)
+	if serr != nil {
+		return submitResult{}, serr
+	}
+	return submitResult{Placed: placed}, nil
+}
+
+// recordSubmission writes an accepted req to the board, stats and event
+// log, reporting whether it placed
+func (s *Server) recordSubmission(ctx context.Context, req *submitRequest) (bool, *submitError) {
+	logger := loggerFrom(ctx)
+
+	placed, err := s.submitScore(ctx, req.Name, req.Score)
+	switch {
+	case errors.Is(err, errQueueFull):
+		s.countRejection(logger, rejectOverloaded, "name", req.Name, "queue", cap(s.queue.ch))
+		return false, errOverloaded
+	case errors.Is(err, context.DeadlineExceeded):
+		s.countRejection(logger, rejectCancelled, "name", req.Name, "err", err)
+		return false, &submitError{Status: http.StatusGatewayTimeout, Message: "Submission timed out"}
+	case err != nil:
+		s.countRejection(logger, rejectCancelled, "name", req.Name, "err", err)
+		return false, &submitError{Status: http.StatusServiceUnavailable, Message: "Submission cancelled"}
+	}
+	s.stats.Record(req.Mode, req.Score)
+	logger.Info("score submitted", "name", req.Name, "score", req.Score, "mode", req.Mode)
+
+	ev := SubmissionEvent{
+		Timestamp: s.now(),
+		RequestID: requestIDFrom(ctx),
+		Name:      req.Name,
+		Score:     req.Score,
+		Mode:      req.Mode,
+		Meta:      req.Meta,
+	}
+	// An approved held score is logged as of when it was submitted
+	if orig, ok := submittedFrom(ctx); ok {
+		ev.Timestamp = orig.Timestamp
+	}
+	if err := s.events.Record(ev); err != nil {
+		logger.Error("failed to record submission event", "name", req.Name, "err", err)
+	}
+	s.publishSubmission(ev, placed)
+	return placed, nil
+}
+
+// readSubmissionBody reads up to limit bytes of a submission body, decoding
+// its Content-Encoding and checking its signature when signing is on. On
+// failure it writes the error and returns false.
+func (s *Server) readSubmissionBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, bool) {
+	body, serr := requestBody(w, r, limit)
+	if serr != nil {
+		s.countRejection(loggerFrom(r.Context()), rejectInvalidBody, "encoding", r.Header.Get("Content-Encoding"))
+		serr.write(w, r)
+		return nil, false
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		s.countRejection(loggerFrom(r.Context()), rejectInvalidBody, "err", err)
+		if bodyTooLarge(err) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Signatures cover the decoded body exactly as sent
+	if s.signatures != nil {
+		if err := s.signatures.Verify(r.Header.Get("X-Sig-Alg"), r.Header.Get("X-Signature"), data); err != nil {
+			s.countRejection(loggerFrom(r.Context()), rejectBadSignature, "alg", r.Header.Get("X-Sig-Alg"), "err", err)
+			s.signatures.signatureError(err).write(w, r)
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// handleSubmitScore handles POST /api/scores
+func (s *Server) handleSubmitScore(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Checked up front so a bad parameter doesn't record the score
+	ranking, ok := rankingParam(w, r)
+	if !ok {
+		return
+	}
+
+	if serr := s.admitSubmission(r.Context()); serr != nil {
+		serr.write(w, r)
+		return
+	}
+
+	data, ok := s.readSubmissionBody(w, r, maxSubmitBodyBytes)
+	if !ok {
+		return
+	}
+
+	var req submitRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		s.countRejection(loggerFrom(r.Context()), rejectInvalidBody, "err", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	res, serr := s.acceptSubmission(r.Context(), &req)
+	if serr != nil {
+		serr.write(w, r)
+		return
+	}
+
+	if res.Unqualified {
+		resp := map[string]any{
+			"status":        "ignored",
+			"qualified":     false,
+			"processedInMs": processedInMs(start),
+		}
+		if res.WouldRank > 0 {
+			resp["wouldRank"] = res.WouldRank
+		}
+		writeJSON(w, r, http.StatusOK, resp)
+		return
+	}
+	if res.Held {
+		writeJSON(w, r, http.StatusAccepted, map[string]any{
+			"status":        "held",
+			"processedInMs": processedInMs(start),
+		})
+		return
+	}
+	if res.Ignored {
+		writeJSON(w, r, http.StatusOK, map[string]any{
+			"status":        "ignored",
+			"improved":      false,
+			"best":          res.Best,
+			"processedInMs": processedInMs(start),
+		})
+		return
+	}
+
+	placed := res.Placed
+	resp := map[string]any{"status": "success"}
+	if !s.recordAll {
+		resp["improved"] = true
+	}
+	if req.BeatRank > 0 {
+		resp["qualified"] = true
+	}
+	if placed {
+		// Looked up on the public board, so a frozen board isn't revealed
+		entries, value := s.publicBoard()
+		// The next player up for a "beat them next" prompt; null at #1
+		if above, ok := nextAboveIn(entries, req.Name); ok {
+			resp["nextTarget"] = above
+		}
+		if rank, _, ok := rankIn(entries, req.Name, ranking, value); ok {
+			resp["rank"] = rank
+		}
+	}
+
+	// In strict mode the status tells "ranked" (201) apart from "recorded
+	// but not on the board" (200), and the body says which via madeTopTen.
+	// The default stays 201 for every accepted submission.
+	status := http.StatusCreated
+	if r.URL.Query().Get("strict") == "true" {
+		if !placed {
+			status = http.StatusOK
+		}
+		resp["madeTopTen"] = placed
+	}
+	if status == http.StatusCreated {
+		// Where the player's standing can be fetched again
+		w.Header().Set("Location", "/api/rank/"+url.PathEscape(req.Name))
+	}
+	resp["processedInMs"] = processedInMs(start)
+	writeJSON(w, r, status, resp)
+}
+
+// processedInMs reports the time since start in fractional milliseconds
+func processedInMs(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testHandler serves s the way main does, minus the access log and the
+// static files
+func testHandler(s *Server) http.Handler {
+	return withRequestID(withClientIP(nil, withRateLimits(s.rateLimits, s.rateLimitExempt, s.routes(http.NotFoundHandler()))))
+}
+
+// do sends one request with an optional JSON body to h
+func do(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSubmitLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   []string
+		target   string
+		body     string
+		status   int
+		location string
+	}{
+		{
+			name:     "recorded",
+			target:   "/api/scores",
+			body:     `{"name":"ann lee","score":10}`,
+			status:   http.StatusCreated,
+			location: "/api/rank/ann%20lee",
+		},
+		{
+			name:   "no improvement",
+			before: []string{`{"name":"ann","score":10}`},
+			target: "/api/scores",
+			body:   `{"name":"ann","score":5}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "did not qualify",
+			before: []string{`{"name":"bob","score":10}`},
+			target: "/api/scores",
+			body:   `{"name":"ann","score":5,"beatRank":1}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "held for review",
+			before: []string{`{"name":"ann","score":2}`, `{"name":"ann","score":3}`},
+			target: "/api/scores",
+			body:   `{"name":"ann","score":500}`,
+			status: http.StatusAccepted,
+		},
+		{
+			name:   "strict and off the board",
+			before: []string{`{"name":"bob","score":10}`},
+			target: "/api/scores?strict=true",
+			body:   `{"name":"ann","score":0}`,
+			status: http.StatusOK,
+		},
+		{
+			name:     "strict and on the board",
+			target:   "/api/scores?strict=true",
+			body:     `{"name":"ann","score":10}`,
+			status:   http.StatusCreated,
+			location: "/api/rank/ann",
+		},
+		{
+			name:   "rejected",
+			target: "/api/scores",
+			body:   `{"name":"ann","score":-1}`,
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size = 1
+			s := NewServer(WithLeaderboard(lb), WithJumpDetector(10, 2), WithRecordAllSubmissions(false))
+			h := testHandler(s)
+			for _, body := range tt.before {
+				if rec := do(h, http.MethodPost, "/api/scores", body); rec.Code != http.StatusCreated {
+					t.Fatalf("setup submission %s: status %d", body, rec.Code)
+				}
+			}
+
+			rec := do(h, http.MethodPost, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("Location"); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
+
+func TestSubmitNextTarget(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []string
+		body   string
+		// want is the nextTarget's name, "null" at #1, or "" when absent
+		want string
+	}{
+		{name: "first on an empty board", body: `{"name":"ann","score":10}`, want: "null"},
+		{name: "new #1", before: []string{`{"name":"bob","score":8}`}, body: `{"name":"ann","score":10}`, want: "null"},
+		{
+			name:   "mid-board",
+			before: []string{`{"name":"bob","score":30}`, `{"name":"cat","score":20}`, `{"name":"dan","score":5}`},
+			body:   `{"name":"ann","score":10}`,
+			want:   "cat",
+		},
+		{name: "tie ranks below", before: []string{`{"name":"bob","score":10}`}, body: `{"name":"ann","score":10}`, want: "bob"},
+		{name: "off the board", before: []string{`{"name":"bob","score":30}`, `{"name":"cat","score":20}`, `{"name":"dan","score":5}`}, body: `{"name":"ann","score":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size = 3
+			h := testHandler(NewServer(WithLeaderboard(lb)))
+			for _, body := range tt.before {
+				do(h, http.MethodPost, "/api/scores", body)
+			}
+
+			rec := do(h, http.MethodPost, "/api/scores", tt.body)
+			var resp map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			raw, present := resp["nextTarget"]
+			var got string
+			switch {
+			case !present:
+			case string(raw) == "null":
+				got = "null"
+			default:
+				var above Score
+				if err := json.Unmarshal(raw, &above); err != nil {
+					t.Fatal(err)
+				}
+				got = above.Name
+			}
+			if got != tt.want {
+				t.Errorf("nextTarget = %s, want %q", raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundScore(t *testing.T) {
+	tests := []struct {
+		score    float64
+		decimals int
+		want     float64
+		ok       bool
+	}{
+		{12, 0, 12, true},
+		{12.5, 0, 13, false},
+		{12.34, 2, 12.34, true},
+		{12.345, 2, 12.35, false},
+		{0.1 + 0.2, 1, 0.3, true},
+		{1e6 + 0.25, 2, 1e6 + 0.25, true},
+		{0, 3, 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := roundScore(tt.score, tt.decimals)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("roundScore(%v, %d) = %v, %v; want %v, %v", tt.score, tt.decimals, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFractionalScores(t *testing.T) {
+	tests := []struct {
+		name     string
+		decimals int
+		scores   []string
+		// want is the board's scores as serialized, best first
+		want   []string
+		status int
+	}{
+		{name: "whole numbers", decimals: 0, scores: []string{"12", "30", "7"}, want: []string{"30", "12", "7"}, status: http.StatusCreated},
+		{name: "fractions", decimals: 2, scores: []string{"12.5", "12.25", "12", "12.75"}, want: []string{"12.75", "12.5", "12.25", "12"}, status: http.StatusCreated},
+		{name: "too precise", decimals: 1, scores: []string{"12.25"}, want: []string{}, status: http.StatusUnprocessableEntity},
+		{name: "fraction on a whole board", decimals: 0, scores: []string{"12.5"}, want: []string{}, status: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHandler(NewServer(WithScoreDecimals(tt.decimals)))
+			for i, score := range tt.scores {
+				rec := do(h, http.MethodPost, "/api/scores", `{"name":"p`+string(rune('a'+i))+`","score":`+score+`}`)
+				if rec.Code != tt.status {
+					t.Fatalf("submit %s: status %d, want %d: %s", score, rec.Code, tt.status, rec.Body)
+				}
+			}
+
+			var board []struct {
+				Score json.RawMessage `json:"score"`
+			}
+			if err := json.Unmarshal(do(h, http.MethodGet, "/api/leaderboard", "").Body.Bytes(), &board); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, e := range board {
+				got = append(got, string(e.Score))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("board scores = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordAllSubmissions(t *testing.T) {
+	scores := []string{"10", "5", "10", "15"}
+	tests := []struct {
+		name      string
+		recordAll bool
+		// statuses and improved are per submission; improved is "" when absent
+		statuses []int
+		improved []string
+		history  int
+	}{
+		{
+			name:      "every play recorded",
+			recordAll: true,
+			statuses:  []int{http.StatusCreated, http.StatusCreated, http.StatusCreated, http.StatusCreated},
+			improved:  []string{"", "", "", ""},
+			history:   4,
+		},
+		{
+			name:      "only improvements recorded",
+			recordAll: false,
+			statuses:  []int{http.StatusCreated, http.StatusOK, http.StatusOK, http.StatusCreated},
+			improved:  []string{"true", "false", "false", "true"},
+			history:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(WithRecordAllSubmissions(tt.recordAll))
+			h := testHandler(s)
+			for i, score := range scores {
+				rec := do(h, http.MethodPost, "/api/scores", `{"name":"ann","score":`+score+`}`)
+				if rec.Code != tt.statuses[i] {
+					t.Errorf("submit %s: status %d, want %d", score, rec.Code, tt.statuses[i])
+				}
+				var resp map[string]json.RawMessage
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if got := string(resp["improved"]); got != tt.improved[i] {
+					t.Errorf("submit %s: improved = %q, want %q", score, got, tt.improved[i])
+				}
+			}
+
+			if got := len(s.lb.History()); got != tt.history {
+				t.Errorf("history has %d entries, want %d", got, tt.history)
+			}
+			if got := s.stats.Stats().Count; got != tt.history {
+				t.Errorf("stats count = %d, want %d", got, tt.history)
+			}
+			if best, _ := s.lb.PersonalBest("ann"); best != 15 {
+				t.Errorf("personal best = %v, want 15", best)
+			}
+		})
+	}
+}
+
+func TestSubmitValidationStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "valid", body: `{"name":"ann","score":10}`, status: http.StatusCreated},
+		{name: "not JSON", body: `name=ann&score=10`, status: http.StatusBadRequest},
+		{name: "truncated JSON", body: `{"name":"ann","score":`, status: http.StatusBadRequest},
+		{name: "wrong field type", body: `{"name":"ann","score":"ten"}`, status: http.StatusBadRequest},
+		{name: "empty body", body: ``, status: http.StatusBadRequest},
+		{name: "missing name", body: `{"score":10}`, status: http.StatusUnprocessableEntity},
+		{name: "name too long", body: `{"name":"` + strings.Repeat("a", maxNameLength+1) + `","score":10}`, status: http.StatusUnprocessableEntity},
+		{name: "negative score", body: `{"name":"ann","score":-5}`, status: http.StatusUnprocessableEntity},
+		{name: "fractional score", body: `{"name":"ann","score":1.5}`, status: http.StatusUnprocessableEntity},
+		{name: "malformed mode", body: `{"name":"ann","score":10,"mode":"Hard Mode!"}`, status: http.StatusUnprocessableEntity},
+		{name: "meta not an object", body: `{"name":"ann","score":10,"meta":[1,2]}`, status: http.StatusUnprocessableEntity},
+		{name: "beatRank past the board", body: `{"name":"ann","score":10,"beatRank":1000}`, status: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(testHandler(NewServer()), http.MethodPost, "/api/scores", tt.body)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestSubmitBeatRank(t *testing.T) {
+	board := []string{`{"name":"bob","score":30}`, `{"name":"cat","score":20}`, `{"name":"dan","score":10}`}
+	tests := []struct {
+		name     string
+		dedup    bool
+		body     string
+		status   int
+		recorded bool
+		// wouldRank is the reported placement of an unqualified score
+		wouldRank int
+	}{
+		{name: "beats the rank", body: `{"name":"ann","score":25,"beatRank":2}`, status: http.StatusCreated, recorded: true},
+		{name: "takes the top", body: `{"name":"ann","score":40,"beatRank":1}`, status: http.StatusCreated, recorded: true},
+		{name: "too low for the rank", body: `{"name":"ann","score":15,"beatRank":2}`, status: http.StatusOK, wouldRank: 3},
+		{name: "a tie doesn't beat it", body: `{"name":"ann","score":20,"beatRank":2}`, status: http.StatusOK, wouldRank: 3},
+		{name: "off the board", body: `{"name":"ann","score":5,"beatRank":3}`, status: http.StatusOK},
+		{name: "dedup keeps a better entry", dedup: true, body: `{"name":"cat","score":15,"beatRank":3}`, status: http.StatusOK},
+		{name: "no beatRank", body: `{"name":"ann","score":1}`, status: http.StatusCreated, recorded: true},
+		{name: "beatRank past the board", body: `{"name":"ann","score":50,"beatRank":4}`, status: http.StatusUnprocessableEntity},
+		{name: "negative beatRank", body: `{"name":"ann","score":50,"beatRank":-1}`, status: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size, lb.dedup = 3, tt.dedup
+			s := NewServer(WithLeaderboard(lb))
+			h := testHandler(s)
+			for _, body := range board {
+				do(h, http.MethodPost, "/api/scores", body)
+			}
+
+			rec := do(h, http.MethodPost, "/api/scores", tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := len(s.lb.History()) > len(board); got != tt.recorded {
+				t.Errorf("recorded = %v, want %v", got, tt.recorded)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Qualified *bool `json:"qualified"`
+				WouldRank int   `json:"wouldRank"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Qualified == nil || *resp.Qualified || resp.WouldRank != tt.wouldRank {
+				t.Errorf("response = %s, want qualified false and wouldRank %d", rec.Body, tt.wouldRank)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// RankThreshold is the bar to clear to reach a rank on the board
+type RankThreshold struct {
+	Rank int `json:"rank"`
+	// Score is the score currently held at Rank, or the lowest score
+	// that places when the board doesn't reach that far yet
+	Score float64 `json:"score"`
+	// Holder is the player at Rank; empty while the rank is open
+	Holder string `json:"holder,omitempty"`
+	Open   bool   `json:"open"`
+}
+
+// rankThreshold works out the bar for rank on the given board, ranked by
+// value
+func rankThreshold(scores []Score, rank int, minDisplayScore int, value func(Score) float64) RankThreshold {
+	if rank > len(scores) {
+		return RankThreshold{Rank: rank, Score: float64(max(minDisplayScore, 0)), Open: true}
+	}
+	held := scores[rank-1]
+	return RankThreshold{Rank: rank, Score: value(held), Holder: held.Name}
+}
+
+// handleGetThreshold handles GET /api/leaderboard/threshold?rank=N
+func (s *Server) handleGetThreshold(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rank, err := strconv.Atoi(r.URL.Query().Get("rank"))
+	size := s.lb.Size()
+	if err != nil || rank < 1 || rank > size {
+		http.Error(w, fmt.Sprintf("rank must be between 1 and %d", size), http.StatusBadRequest)
+		return
+	}
+
+	scores, value := s.publicBoard()
+	writeJSON(w, r, http.StatusOK, rankThreshold(scores, rank, s.lb.minDisplayScore, value))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestRankThreshold(t *testing.T) {
+	full := []string{`{"name":"ann","score":30}`, `{"name":"bob","score":20}`, `{"name":"cat","score":10}`}
+	sparse := []string{`{"name":"ann","score":30}`}
+	tests := []struct {
+		name            string
+		board           []string
+		minDisplayScore int
+		query           string
+		status          int
+		want            RankThreshold
+	}{
+		{name: "full board top", board: full, query: "rank=1", status: http.StatusOK, want: RankThreshold{Rank: 1, Score: 30, Holder: "ann"}},
+		{name: "full board last", board: full, query: "rank=3", status: http.StatusOK, want: RankThreshold{Rank: 3, Score: 10, Holder: "cat"}},
+		{name: "sparse board held", board: sparse, query: "rank=1", status: http.StatusOK, want: RankThreshold{Rank: 1, Score: 30, Holder: "ann"}},
+		{name: "sparse board open", board: sparse, query: "rank=3", status: http.StatusOK, want: RankThreshold{Rank: 3, Open: true}},
+		{name: "open above a floor", board: sparse, minDisplayScore: 5, query: "rank=2", status: http.StatusOK, want: RankThreshold{Rank: 2, Score: 5, Open: true}},
+		{name: "empty board", query: "rank=1", status: http.StatusOK, want: RankThreshold{Rank: 1, Open: true}},
+		{name: "rank zero", board: full, query: "rank=0", status: http.StatusBadRequest},
+		{name: "past the board size", board: full, query: "rank=4", status: http.StatusBadRequest},
+		{name: "not a number", board: full, query: "rank=top", status: http.StatusBadRequest},
+		{name: "missing", board: full, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLeaderboard()
+			lb.size, lb.minDisplayScore = 3, tt.minDisplayScore
+			h := testHandler(NewServer(WithLeaderboard(lb)))
+			for _, body := range tt.board {
+				do(h, http.MethodPost, "/api/scores", body)
+			}
+
+			rec := do(h, http.MethodGet, "/api/leaderboard/threshold?"+tt.query, "")
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var got RankThreshold
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("threshold = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// submitRetryAfter is how long throttled submitters are asked to wait
+const submitRetryAfter = time.Second
+
+// writeThrottled responds with status (429 or 503) and a Retry-After header.
+//
+// Retry-After is always sent in its delay-seconds form (a whole number of
+// seconds, rounded up) rather than as an HTTP-date, so clients never need a
+// synchronized clock to interpret it.
+func writeThrottled(w http.ResponseWriter, status int, msg string, after time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(after)))
+	http.Error(w, msg, status)
+}
+
+// retryAfterSeconds rounds after up to whole seconds, at least one
+func retryAfterSeconds(after time.Duration) int {
+	return max(int(math.Ceil(after.Seconds())), 1)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		after time.Duration
+		want  int
+	}{
+		{0, 1},
+		{-time.Second, 1},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1200 * time.Millisecond, 2},
+		{90 * time.Second, 90},
+	}
+	for _, tt := range tests {
+		if got := retryAfterSeconds(tt.after); got != tt.want {
+			t.Errorf("retryAfterSeconds(%v) = %d, want %d", tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimitedRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		want int
+	}{
+		{name: "sub-second wait", rate: 4, want: 1},
+		{name: "several seconds", rate: 0.25, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			limiter, err := newRateLimiter([]RateLimitRule{{Route: "GET /api/leaderboard", Rate: tt.rate, Burst: 1}}, func() time.Time { return now })
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := testHandler(NewServer(WithRateLimits(limiter)))
+			if rec := do(h, http.MethodGet, "/api/leaderboard", ""); rec.Code != http.StatusOK {
+				t.Fatalf("first request: status %d", rec.Code)
+			}
+
+			rec := do(h, http.MethodGet, "/api/leaderboard", "")
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+			}
+			secs, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+			if err != nil {
+				t.Fatalf("Retry-After %q isn't delay-seconds: %v", rec.Header().Get("Retry-After"), err)
+			}
+			if secs != tt.want {
+				t.Errorf("Retry-After = %d, want %d", secs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+)
+
+// maxEventLineBytes bounds one event log line read back at warm-up
+const maxEventLineBytes = 1 << 20
+
+// replayState is what warm-up derives from the event log besides the board
+// and history: stats, the records feed, milestone winners and each
+// player's last submission. Compaction drops events it was derived from,
+// so it heads the compacted log with this as a snapshot event. Covers is
+// how many of the events after it the snapshot already accounts for.
+type replayState struct {
+	Covers     int                  `json:"covers"`
+	Stats      statsState           `json:"stats"`
+	RecordBest *Score               `json:"recordBest,omitempty"`
+	Records    []RecordEvent        `json:"records"`
+	Milestones []Milestone          `json:"milestones,omitempty"`
+	LastSubmit map[string]time.Time `json:"lastSubmit"`
+}
+
+// restore records a past entry at its original time
+func (lb *Leaderboard) restore(entry Score) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.addEntry(entry)
+}
+
+// beginReplay makes changes rebuild state quietly until endReplay: they
+// don't bump the version, notify listeners or announce milestones
+func (lb *Leaderboard) beginReplay() {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.replaying = true
+}
+
+// endReplay records the replayed board as a single version, without
+// notifying listeners, and saves any milestone winners awarded meanwhile
+func (lb *Leaderboard) endReplay() {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.replaying = false
+	if len(lb.entries) > 0 {
+		lb.logVersion()
+	}
+	if lb.milestones != nil && lb.milestones.unsaved {
+		lb.milestones.save()
+		lb.milestones.unsaved = false
+	}
+}
+
+// replayState captures lb's part of a snapshot event
+func (lb *Leaderboard) replayState() replayState {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	st := replayState{
+		RecordBest: lb.records.best,
+		Records:    slices.Clone(lb.records.events),
+		LastSubmit: make(map[string]time.Time, len(lb.lastSubmit)),
+	}
+	for name, t := range lb.lastSubmit {
+		st.LastSubmit[name] = t
+	}
+	if lb.milestones != nil {
+		st.Milestones = slices.Clone(lb.milestones.milestones)
+	}
+	return st
+}
+
+// holdMilestones detaches lb's milestone tracker, so the events a snapshot
+// covers don't award milestones it already knows the winners of
+func (lb *Leaderboard) holdMilestones() *milestoneTracker {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	held := lb.milestones
+	lb.milestones = nil
+	return held
+}
+
+// applyReplayState restores lb's part of a snapshot event once the events
+// it covers are replayed, replacing what they derived, and reattaches the
+// milestone tracker held meanwhile. Milestones already won, such as those
+// loaded at startup, keep their winners.
+func (lb *Leaderboard) applyReplayState(st replayState, held *milestoneTracker) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.records.best = nil
+	if st.RecordBest != nil {
+		best := *st.RecordBest
+		lb.records.best = &best
+	}
+	lb.records.events = slices.Clone(st.Records)
+	if len(lb.records.events) > lb.records.size {
+		lb.records.events = lb.records.events[len(lb.records.events)-lb.records.size:]
+	}
+	lb.lastSubmit = make(map[string]time.Time, len(st.LastSubmit))
+	for name, t := range st.LastSubmit {
+		lb.lastSubmit[name] = t
+	}
+	// Rebuilt on next use, with the snapshot's names
+	lb.nameKeys = nil
+
+	lb.milestones = held
+	if held == nil {
+		return
+	}
+	for _, won := range st.Milestones {
+		i := slices.IndexFunc(held.milestones, func(m Milestone) bool { return m.Threshold == won.Threshold })
+		if won.ReachedAt != nil && i >= 0 && held.milestones[i].ReachedAt == nil {
+			held.milestones[i] = won
+			held.unsaved = true
+		}
+	}
+}
+
+// replayEvents rebuilds lb and stats from events in log order, numbering
+// the i-th event's submission id(i). Warm-up and compaction both replay
+// through here, so a compacted log rebuilds what the full one did.
+func replayEvents(lb *Leaderboard, stats *statsAccumulator, events []SubmissionEvent, id func(i int) uint64) {
+	lb.beginReplay()
+	defer lb.endReplay()
+
+	// The events a snapshot covers only rebuild the board and history;
+	// the snapshot supplies the rest once they are replayed
+	var (
+		snapshot *replayState
+		held     *milestoneTracker
+		covered  int
+	)
+	for i, ev := range events {
+		if ev.Type == eventSnapshot {
+			snapshot, covered = ev.State, ev.State.Covers
+			held = lb.holdMilestones()
+			stats.restore(snapshot.Stats)
+		} else {
+			switch ev.Type {
+			case eventRename:
+				lb.RenamePlayer(ev.Name, ev.To)
+			case eventPurge:
+				lb.PurgeScores(*ev.MinScore, *ev.MaxScore)
+			default:
+				lb.restore(Score{ID: id(i), Name: ev.Name, Score: ev.Score, Timestamp: ev.Timestamp.UTC()})
+				if snapshot == nil {
+					stats.Record(cmp.Or(ev.Mode, defaultMode), ev.Score)
+				}
+			}
+			covered--
+		}
+		if snapshot != nil && covered <= 0 {
+			lb.applyReplayState(*snapshot, held)
+			snapshot = nil
+		}
+	}
+	if snapshot != nil {
+		// The log ended before the events the snapshot covers did
+		lb.applyReplayState(*snapshot, held)
+	}
+}
+
+// warmUp preloads the board, history and stats from the event log at path
+// and primes the board cache, so the first requests after a restart don't
+// find a cold, empty server. It returns the number of events replayed.
+//
+// Admin renames and purges are replayed in order with the submissions;
+// deletions by ID and daily resets made since those submissions are not.
+func (s *Server) warmUp(path string) (int, error) {
+	// A crash mid-append leaves a torn last line, which is skipped
+	events, skipped, err := readEvents(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	replayEvents(s.lb, s.stats, events, func(int) uint64 { return s.lb.ids.Next() })
+	n := len(events)
+	if skipped > 0 {
+		slog.Warn("skipped unreadable event log lines during warm-up", "path", path, "skipped", skipped)
+	}
+
+	s.store.Top(s.lb.Size())
+	return n, nil
+}
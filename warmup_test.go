@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingTop is a fake backend over a Leaderboard that counts board reads
+type countingTop struct {
+	*Leaderboard
+	reads int
+}
+
+func (ct *countingTop) Top(n int) []Score {
+	ct.reads++
+	return ct.Leaderboard.Top(n)
+}
+
+func TestWarmUpPrimesCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []SubmissionEvent
+		// reads is how many board reads reach the backend by the end
+		reads int
+	}{
+		{name: "preloaded", events: []SubmissionEvent{submitted(0, "ann", 15), submitted(1, "bob", 45)}, reads: 1},
+		{name: "no event log", reads: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events.jsonl")
+			if tt.events != nil {
+				el, err := OpenEventLog(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, ev := range tt.events {
+					if err := el.Record(ev); err != nil {
+						t.Fatal(err)
+					}
+				}
+				el.Close()
+			}
+
+			lb := compactTestBoard(10, false)
+			s := NewServer(WithLeaderboard(lb))
+			backend := &countingTop{Leaderboard: lb}
+			s.store = newCachedStore(backend, time.Minute, 4, s.now)
+
+			n, err := s.warmUp(path)
+			if err != nil || n != len(tt.events) {
+				t.Fatalf("warmUp = %d, %v; want %d, nil", n, err, len(tt.events))
+			}
+			if got := s.stats.Stats().Count; got != len(tt.events) {
+				t.Errorf("stats count after warm-up = %d, want %d", got, len(tt.events))
+			}
+			if tt.events != nil && backend.reads != 1 {
+				t.Fatalf("warm-up made %d board reads, want 1 to prime the cache", backend.reads)
+			}
+
+			// The first request is served the replayed board from the cache
+			var board []Score
+			if err := json.Unmarshal(do(testHandler(s), http.MethodGet, "/api/leaderboard", "").Body.Bytes(), &board); err != nil {
+				t.Fatal(err)
+			}
+			if len(board) != len(tt.events) {
+				t.Errorf("first request saw %d entries, want %d", len(board), len(tt.events))
+			}
+			if backend.reads != tt.reads {
+				t.Errorf("backend got %d board reads, want %d", backend.reads, tt.reads)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxGameMessageBytes caps a single inbound WebSocket message
+const maxGameMessageBytes = 4096
+
+// wsUpgrader leaves origin checks to the handler, which applies the
+// server's originPolicy before upgrading
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(*http.Request) bool { return true },
+}
+
+// gameReply is sent back for every message received on /ws/game
+type gameReply struct {
+	Type       string `json:"type"` // "result" or "error"
+	Status     int    `json:"status"`
+	Error      string `json:"error,omitempty"`
+	MadeTopTen bool   `json:"madeTopTen"`
+	Rank       int    `json:"rank,omitempty"`
+	// Ignored is set when the score wasn't recorded for not improving on
+	// the player's best or not reaching the requested beatRank
+	Ignored bool `json:"ignored,omitempty"`
+	// Held is set when the score was queued for admin review
+	Held bool `json:"held,omitempty"`
+	// RetryAfter is how many seconds a rate limited client should wait
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+// handleGameMessage runs one inbound submission through the same checks
+// as POST /api/scores
+func (s *Server) handleGameMessage(ctx context.Context, data []byte) gameReply {
+	if serr := s.admitSubmission(ctx); serr != nil {
+		return gameReply{Type: "error", Status: serr.Status, Error: serr.Message}
+	}
+	// Messages carry no headers to sign with, so signed deployments take
+	// submissions over HTTP only
+	if s.signatures != nil {
+		s.countRejection(loggerFrom(ctx), rejectBadSignature, "transport", "websocket")
+		return gameReply{Type: "error", Status: http.StatusUnauthorized, Error: "Signed submissions must be sent to POST /api/scores"}
+	}
+
+	var req submitRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		s.countRejection(loggerFrom(ctx), rejectInvalidBody, "err", err)
+		return gameReply{Type: "error", Status: http.StatusBadRequest, Error: "Invalid message"}
+	}
+
+	res, serr := s.acceptSubmission(ctx, &req)
+	if serr != nil {
+		return gameReply{Type: "error", Status: serr.Status, Error: serr.Message}
+	}
+	if res.Held {
+		return gameReply{Type: "result", Status: http.StatusAccepted, Held: true}
+	}
+	if res.Ignored || res.Unqualified {
+		return gameReply{Type: "result", Status: http.StatusOK, Ignored: true}
+	}
+
+	reply := gameReply{Type: "result", Status: http.StatusCreated, MadeTopTen: res.Placed}
+	entries, value := s.publicBoard()
+	if rank, _, ok := rankIn(entries, req.Name, rankingPositional, value); ok {
+		reply.Rank = rank
+	}
+	return reply
+}
+
+// limitGameMessage applies the POST /api/scores rate limit to one
+// submission on the socket opened by r before handling it, since the
+// limits only see the upgrade request itself
+func (s *Server) limitGameMessage(r *http.Request, data []byte) gameReply {
+	if !s.rateLimitExempt(r) {
+		if ok, wait := s.rateLimits.allow(http.MethodPost, "/api/scores", clientIP(r)); !ok {
+			loggerFrom(r.Context()).Warn("rate limited", "ip", clientIP(r), "path", r.URL.Path)
+			return gameReply{Type: "error", Status: http.StatusTooManyRequests, Error: "Too many requests, slow down", RetryAfter: retryAfterSeconds(wait)}
+		}
+	}
+	return s.handleGameMessage(r.Context(), data)
+}
+
+// handleGameSocket handles GET /ws/game, accepting JSON score submissions
+// over a WebSocket and replying to each with its result. Malformed
+// messages get an error reply but keep the connection open.
+func (s *Server) handleGameSocket(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !s.originAllowed(r) {
+		loggerFrom(r.Context()).Info("rejected websocket origin", "origin", r.Header.Get("Origin"))
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an HTTP error
+		return
+	}
+	defer conn.Close()
+	if !s.sockets.Add(conn) {
+		return
+	}
+	defer s.sockets.Remove(conn)
+	conn.SetReadLimit(maxGameMessageBytes)
+
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		reply := gameReply{Type: "error", Status: http.StatusBadRequest, Error: "Expected a text message"}
+		if msgType == websocket.TextMessage {
+			reply = s.limitGameMessage(r, data)
+		}
+		if err := conn.WriteJSON(reply); err != nil {
+			return
+		}
+	}
+}